@@ -0,0 +1,69 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Weekday is a day of the week.
+// +kubebuilder:validation:Enum=Monday;Tuesday;Wednesday;Thursday;Friday;Saturday;Sunday
+type Weekday string
+
+// CalendarSpec defines the days that belong to a Calendar
+type CalendarSpec struct {
+	// TimeZone is the IANA time zone in which the days of the calendar begin and end.
+	// Defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Dates are single days of the calendar, formatted as YYYY-MM-DD.
+	// +optional
+	Dates []string `json:"dates,omitempty"`
+
+	// Weekdays are days of the week that are always part of the calendar.
+	// +optional
+	Weekdays []Weekday `json:"weekdays,omitempty"`
+
+	// ICalendar imports the all-day events of an iCalendar file stored in a ConfigMap.
+	// +optional
+	ICalendar *ICalendarSource `json:"iCalendar,omitempty"`
+}
+
+// ICalendarSource points to an iCalendar (RFC 5545) file stored in a ConfigMap.
+// Every day covered by a VEVENT is part of the calendar. Events recurring with
+// FREQ=YEARLY and no other rule part repeat every year; other recurrences only
+// contribute their first occurrence.
+type ICalendarSource struct {
+	// Namespace of the ConfigMap.
+	Namespace string `json:"namespace"`
+
+	// Name of the ConfigMap.
+	Name string `json:"name"`
+
+	// Key of the ConfigMap entry holding the iCalendar data.
+	Key string `json:"key"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+
+// Calendar is a cluster-wide set of days, such as business days or public holidays,
+// that schedules reference to restrict the days they run on.
+type Calendar struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec CalendarSpec `json:"spec,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// CalendarList contains a list of Calendar
+type CalendarList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Calendar `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Calendar{}, &CalendarList{})
+}
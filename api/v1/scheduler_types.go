@@ -5,13 +5,370 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// ReconcileAnnotation controls whether the controller mutates the children of a Scheduler.
+	ReconcileAnnotation = "lr.labs/reconcile"
+
+	// ReconcilePaused is the ReconcileAnnotation value that stops the controller from
+	// creating, updating or deleting CronJobs while it keeps reporting status.
+	ReconcilePaused = "paused"
+
+	// ScheduledTimeAnnotation records on a Job the logical time it was created for.
+	ScheduledTimeAnnotation = "lr.labs/scheduled-time"
+
+	// TriggerAnnotation set to "<schedule>@<nonce>" on a Scheduler starts a run of the
+	// schedule by hand. Each nonce starts at most one run.
+	TriggerAnnotation = "lr.labs/trigger"
+
+	// TriggerLabel records on a Job what started it.
+	TriggerLabel = "lr.labs/trigger"
+
+	// TriggerManual is the TriggerLabel value of Jobs started through TriggerAnnotation.
+	TriggerManual = "manual"
+
+	// TriggerBackfill is the TriggerLabel value of Jobs started by a backfill request.
+	TriggerBackfill = "backfill"
+
+	// TriggeredByAnnotation records on a manually triggered Job the field manager that set
+	// TriggerAnnotation on the Scheduler.
+	TriggeredByAnnotation = "lr.labs/triggered-by"
+
+	// TriggerDependency is the TriggerLabel value of Jobs started because the runs of
+	// the schedules they depend on succeeded.
+	TriggerDependency = "dependency"
+
+	// ReleasedAnnotation records on a Job the comma-separated dependent schedules that
+	// were started, or deliberately not started, after it succeeded.
+	ReleasedAnnotation = "lr.labs/released"
+
+	// StepLabel records on the Job of a step run in Jobs mode the name of the step.
+	StepLabel = "lr.labs/step"
+
+	// RunLabel records on the Job of a step run in Jobs mode the name of the Job of the
+	// run it belongs to.
+	RunLabel = "lr.labs/run"
+
+	// StepsAnnotation records on the Job of a run in Jobs mode the comma-separated steps
+	// of its pipeline, ending with MainStep.
+	StepsAnnotation = "lr.labs/steps"
+
+	// PipelinePhaseAnnotation records on the Job of a run in Jobs mode whether its
+	// pipeline Succeeded or Failed, once it has finished.
+	PipelinePhaseAnnotation = "lr.labs/pipeline-phase"
+
+	// MainStep is the name of the step running the schedule's own container, after the
+	// steps of its pipeline.
+	MainStep = "main"
+
+	// LockGroupLabel records on the Job of a run the lock group it holds while active.
+	LockGroupLabel = "lr.labs/lock-group"
+
+	// LockScopeLabel records on the Job of a run the scope of its lock group.
+	LockScopeLabel = "lr.labs/lock-scope"
+
+	// PriorityAnnotation records on a Job the priority of its schedule.
+	PriorityAnnotation = "lr.labs/priority"
+
+	// QueuedAnnotation marks a Job created suspended because a SchedulerQuota applies to
+	// its namespace. It is removed when the quota releases the Job.
+	QueuedAnnotation = "lr.labs/queued"
+
+	// RetryOfAnnotation records on the Job of a retried run the name of the Job of its
+	// first attempt, which identifies the run.
+	RetryOfAnnotation = "lr.labs/retry-of"
+
+	// AttemptAnnotation records on the Job of a retried run its attempt number.
+	AttemptAnnotation = "lr.labs/attempt"
+)
+
+// ScheduleMode selects which engine turns a schedule into Jobs.
+// +kubebuilder:validation:Enum=CronJob;Native
+type ScheduleMode string
+
+const (
+	// ScheduleModeCronJob renders the schedule as a Kubernetes CronJob.
+	ScheduleModeCronJob ScheduleMode = "CronJob"
+
+	// ScheduleModeNative lets the controller compute fire times itself and create Jobs directly.
+	ScheduleModeNative ScheduleMode = "Native"
+)
+
+// StepsMode selects how the steps of a schedule are run.
+// +kubebuilder:validation:Enum=InitContainers;Jobs
+type StepsMode string
+
+const (
+	// StepsModeInitContainers runs the steps as init containers of the pod of the run,
+	// sharing its workspace even without a PersistentVolumeClaim.
+	StepsModeInitContainers StepsMode = "InitContainers"
+
+	// StepsModeJobs runs every step in its own Job, started by the controller once the
+	// previous one has finished, with its own retries.
+	StepsModeJobs StepsMode = "Jobs"
+)
+
+// LockScope selects which schedules share a lock group of the same name.
+// +kubebuilder:validation:Enum=Namespace;Cluster
+type LockScope string
+
+const (
+	// LockScopeNamespace shares the lock group with schedules in the same namespace.
+	LockScopeNamespace LockScope = "Namespace"
+
+	// LockScopeCluster shares the lock group with schedules in every namespace.
+	LockScopeCluster LockScope = "Cluster"
+)
+
+// LockPolicy decides what happens to a run that is due while its lock group is held.
+// +kubebuilder:validation:Enum=Delay;Skip;Queue
+type LockPolicy string
+
+const (
+	// LockPolicyDelay runs the most recent slot held back once the lock is released.
+	LockPolicyDelay LockPolicy = "Delay"
+
+	// LockPolicySkip drops the slots that are due while the lock is held.
+	LockPolicySkip LockPolicy = "Skip"
+
+	// LockPolicyQueue runs every slot held back, oldest first, once the lock is
+	// released, up to MaxCatchUpRuns of them.
+	LockPolicyQueue LockPolicy = "Queue"
+)
+
+// CatchUpPolicy decides which slots missed while the controller was unavailable are run.
+// +kubebuilder:validation:Enum=None;Latest;All
+type CatchUpPolicy string
+
+const (
+	// CatchUpNone skips every missed slot.
+	CatchUpNone CatchUpPolicy = "None"
+
+	// CatchUpLatest runs the most recent missed slot only.
+	CatchUpLatest CatchUpPolicy = "Latest"
+
+	// CatchUpAll runs every missed slot, up to MaxCatchUpRuns of the most recent ones.
+	CatchUpAll CatchUpPolicy = "All"
+)
+
 // SchedulerSpec defines the desired state of Scheduler
 type SchedulerSpec struct {
+	// Suspend sets suspend on every CronJob owned by this Scheduler.
+	// Schedules can override it with their own Suspend field.
+	// +optional
+	Suspend *bool `json:"suspend,omitempty"`
+
+	// Stagger spreads schedules that share an expression, such as "0 * * * *", over a
+	// window. It overrides the controller-wide --stagger-window flag.
+	// +optional
+	Stagger *StaggerSpec `json:"stagger,omitempty"`
+
+	// BlackoutWindows are periods during which every schedule of this Scheduler is
+	// suspended, in addition to the windows of each schedule.
+	// +optional
+	BlackoutWindows []BlackoutWindow `json:"blackoutWindows,omitempty"`
+
+	// RunHistory configures the retention of the ScheduleRuns recording the runs of the
+	// schedules.
+	// +optional
+	RunHistory *RunHistory `json:"runHistory,omitempty"`
+
+	// Notifications sends notifications about the runs of the schedules to webhooks,
+	// Slack and email.
+	// +optional
+	Notifications *Notifications `json:"notifications,omitempty"`
+
 	// Schedules is the list of scheduled jobs to create
 	Schedules []Schedule `json:"schedules,omitempty"`
 }
 
+// RunHistory configures how long the ScheduleRuns of a Scheduler are kept. Only runs that
+// have finished or were skipped are removed.
+type RunHistory struct {
+	// Limit is the number of ScheduleRuns kept per schedule. Zero removes runs as soon as
+	// they finish.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=100
+	// +optional
+	Limit *int32 `json:"limit,omitempty"`
+
+	// TTL removes ScheduleRuns that finished longer ago, whatever the Limit.
+	// +optional
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+}
+
+// NotificationTrigger is an occurrence in the runs of a schedule that is notified.
+// +kubebuilder:validation:Enum=Failure;Recovery;Missed;LongRunning
+type NotificationTrigger string
+
+const (
+	// NotifyFailure is notified when a run fails and will not be retried.
+	NotifyFailure NotificationTrigger = "Failure"
+
+	// NotifyRecovery is notified when a run succeeds after the previous run of its
+	// schedule failed.
+	NotifyRecovery NotificationTrigger = "Recovery"
+
+	// NotifyMissed is notified when a slot of a schedule passes without a run starting.
+	NotifyMissed NotificationTrigger = "Missed"
+
+	// NotifyLongRunning is notified when a run is still running after LongRunningAfter.
+	NotifyLongRunning NotificationTrigger = "LongRunning"
+)
+
+// Notifications configures where and when the runs of a Scheduler are notified.
+// Notifications are delivered by the controller, retried with a backoff when the sink
+// fails, and sent once per run and trigger.
+type Notifications struct {
+	// LongRunningAfter is how long a run may run before LongRunning is notified.
+	// Defaults to one hour.
+	// +optional
+	LongRunningAfter *metav1.Duration `json:"longRunningAfter,omitempty"`
+
+	// MissedAfter is how long after a slot a schedule may go without a run before
+	// Missed is notified. Defaults to five minutes.
+	// +optional
+	MissedAfter *metav1.Duration `json:"missedAfter,omitempty"`
+
+	// Sinks are the destinations of the notifications.
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=10
+	// +listType=map
+	// +listMapKey=name
+	Sinks []NotificationSink `json:"sinks"`
+}
+
+// NotificationSink is a destination of notifications. Exactly one of Webhook, Slack and
+// SMTP is set.
+// +kubebuilder:validation:XValidation:rule="(has(self.webhook) ? 1 : 0) + (has(self.slack) ? 1 : 0) + (has(self.smtp) ? 1 : 0) == 1",message="exactly one of webhook, slack and smtp must be set"
+type NotificationSink struct {
+	// Name identifies the sink in the status of the Scheduler.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// On lists the triggers notified to the sink.
+	// +kubebuilder:validation:MinItems=1
+	On []NotificationTrigger `json:"on"`
+
+	// Schedules restricts the notifications to the runs of these schedules. All
+	// schedules are notified when it is empty.
+	// +optional
+	Schedules []string `json:"schedules,omitempty"`
+
+	// Webhook posts a JSON document to a URL.
+	// +optional
+	Webhook *WebhookSink `json:"webhook,omitempty"`
+
+	// Slack posts a message to a Slack-compatible incoming webhook.
+	// +optional
+	Slack *SlackSink `json:"slack,omitempty"`
+
+	// SMTP sends an email.
+	// +optional
+	SMTP *SMTPSink `json:"smtp,omitempty"`
+}
+
+// WebhookSink posts notifications to a URL, given directly or read from a Secret.
+// +kubebuilder:validation:XValidation:rule="has(self.url) != has(self.urlSecretRef)",message="exactly one of url and urlSecretRef must be set"
+type WebhookSink struct {
+	// URL is the URL notifications are posted to.
+	// +kubebuilder:validation:Pattern=`^https?://`
+	// +optional
+	URL string `json:"url,omitempty"`
+
+	// URLSecretRef selects the key of a Secret holding the URL, for URLs that carry
+	// credentials.
+	// +optional
+	URLSecretRef *corev1.SecretKeySelector `json:"urlSecretRef,omitempty"`
+
+	// AuthorizationSecretRef selects the key of a Secret holding the value of the
+	// Authorization header, such as "Bearer <token>".
+	// +optional
+	AuthorizationSecretRef *corev1.SecretKeySelector `json:"authorizationSecretRef,omitempty"`
+
+	// Body is a Go template rendering the JSON document posted, over .Trigger,
+	// .Namespace, .Scheduler, .Schedule, .Run, .ScheduledTime, .Time and .Message. The
+	// json function quotes a value, as in {"text": {{ .Message | json }}}. Defaults to a
+	// JSON object with all of them.
+	// +optional
+	Body string `json:"body,omitempty"`
+}
+
+// SlackSink posts notifications to a Slack-compatible incoming webhook.
+type SlackSink struct {
+	// URLSecretRef selects the key of a Secret holding the URL of the webhook.
+	URLSecretRef corev1.SecretKeySelector `json:"urlSecretRef"`
+}
+
+// SMTPSink sends notifications by email. The connection is upgraded with STARTTLS when
+// the server supports it, which is required to authenticate to a remote server.
+type SMTPSink struct {
+	// Host is the host name of the SMTP server.
+	// +kubebuilder:validation:MinLength=1
+	Host string `json:"host"`
+
+	// Port is the port of the SMTP server.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65535
+	// +kubebuilder:default=587
+	// +optional
+	Port int32 `json:"port,omitempty"`
+
+	// From is the sender address.
+	// +kubebuilder:validation:MinLength=1
+	From string `json:"from"`
+
+	// To are the recipient addresses.
+	// +kubebuilder:validation:MinItems=1
+	To []string `json:"to"`
+
+	// UsernameSecretRef and PasswordSecretRef select the keys of Secrets holding the
+	// credentials of the server. Mail is sent without authentication when they are unset.
+	// +optional
+	UsernameSecretRef *corev1.SecretKeySelector `json:"usernameSecretRef,omitempty"`
+	// +optional
+	PasswordSecretRef *corev1.SecretKeySelector `json:"passwordSecretRef,omitempty"`
+}
+
+// StaggerSpec configures the deterministic offsetting of schedules
+type StaggerSpec struct {
+	// Window is the largest offset added to the minute and hour fields of a schedule.
+	// The offset is derived from the schedule's name, so it stays the same across
+	// reconciles and controller restarts. A zero window disables staggering.
+	Window metav1.Duration `json:"window"`
+}
+
+// BlackoutWindow is a period during which schedules are suspended. A window either
+// recurs, starting at every activation of Schedule and lasting Duration, or is a single
+// range from Start to End.
+type BlackoutWindow struct {
+	// Name identifies the window in the Blackout condition.
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Schedule is a cron expression for the start of a recurring window.
+	// +optional
+	Schedule string `json:"schedule,omitempty"`
+
+	// Duration is how long a recurring window lasts.
+	// +optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+
+	// TimeZone is the IANA time zone Schedule is evaluated in. Defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Start is the beginning of a one-off window, as an RFC 3339 timestamp.
+	// +optional
+	Start *metav1.Time `json:"start,omitempty"`
+
+	// End is the end of a one-off window, as an RFC 3339 timestamp.
+	// +optional
+	End *metav1.Time `json:"end,omitempty"`
+}
+
 // Schedule defines a single cron job specification
+// +kubebuilder:validation:XValidation:rule="!has(self.steps) || (has(self.stepsMode) && self.stepsMode == 'Jobs') || self.steps.all(s, !has(s.retries) && !has(s.continueOnError))",message="retries and continueOnError require stepsMode Jobs"
+// +kubebuilder:validation:XValidation:rule="has(self.dependsOn) && size(self.dependsOn) > 0 ? !has(self.cronExpression) && !has(self.runAt) : has(self.cronExpression) != has(self.runAt)",message="exactly one of cronExpression, runAt and dependsOn must be set"
 type Schedule struct {
 	// Name is a unique name for the schedule (used to identify the cronjob)
 	Name string `json:"name"`
@@ -19,18 +376,687 @@ type Schedule struct {
 	// Image is the container image to run in the cronjob
 	Image string `json:"image"`
 
-	// CronExpression is the cron expression string that defines when to run the job
-	CronExpression string `json:"cronExpression"`
+	// CronExpression is the cron expression string that defines when to run the job.
+	// Besides the five standard fields and the @-macros it accepts an optional leading
+	// seconds field, "@every <duration>" intervals aligned to the Unix epoch, and H tokens
+	// ("H", "H(0-29)", "H/15") hashed from the schedule's name to spread load.
+	// Expressions that a CronJob cannot run, such as non-zero seconds, require mode Native.
+	// +optional
+	CronExpression string `json:"cronExpression,omitempty"`
+
+	// RunAt runs the schedule once at the given time instead of on a CronExpression. The
+	// controller creates the Job itself whatever the Mode. Suspend and blackout windows
+	// delay the run until they are lifted; Calendars and run limits do not apply.
+	// +optional
+	RunAt *metav1.Time `json:"runAt,omitempty"`
+
+	// RemoveStatusAfterRun drops the status of a RunAt schedule once its Job has finished.
+	// The finished Job then records that the run happened: deleting it lets the schedule
+	// run again.
+	// +optional
+	RemoveStatusAfterRun bool `json:"removeStatusAfterRun,omitempty"`
+
+	// Mode selects the scheduling engine. CronJob delegates to the Kubernetes CronJob
+	// controller, Native makes this controller fire the Jobs itself.
+	// +kubebuilder:default=CronJob
+	// +optional
+	Mode ScheduleMode `json:"mode,omitempty"`
 
-	// Params is the array of command line arguments to pass to the container image
+	// Params is the array of command line arguments to pass to the container image.
+	// Params and env values may be Go templates over .SchedulerName, .ScheduleName and
+	// .Namespace and, in Native mode, .ScheduledTime, .RunID, .Attempt, .Trigger and
+	// .Outputs, e.g. {{ .ScheduledTime | date "2006-01-02" }}. .Outputs holds the outputs
+	// of the latest successful run of each schedule of the Scheduler, or of its run for
+	// the same logical time, as in {{ index .Outputs "extract" "rows" }}. A schedule
+	// rendered as a CronJob whose params or env refer to run data, or to
+	// $(SCHEDULED_TIME), is rejected: its CronJob is left as it is and the Ready
+	// condition of the Scheduler reports the error.
 	Params []string `json:"params,omitempty"`
 
-	// Env is a list of environment variables to set in the container
+	// Env is a list of environment variables to set in the container, after
+	// SCHEDULER_NAME, SCHEDULE_NAME, SCHEDULED_TIME, RUN_ID, ATTEMPT and TRIGGER which
+	// describe the run. SCHEDULED_TIME is only set in the Jobs the controller creates,
+	// in Native mode or for runAt, dependsOn, lockGroup, triggered, retried and backfill
+	// runs: the Jobs of CronJobs do not have it, and may not refer to it. When tracing is enabled,
+	// the Jobs created by the controller itself, such as Native, triggered, retried and
+	// dependent runs, also receive TRACEPARENT first, so that a TRACEPARENT set here wins;
+	// the Jobs the CronJob controller creates in CronJob mode do not.
 	// +optional
 	Env []corev1.EnvVar `json:"env,omitempty"`
 
 	// +optional
 	EnvFrom []corev1.EnvFromSource `json:"envFrom,omitempty"`
+
+	// Suspend overrides the Scheduler-wide Suspend for this schedule only.
+	// +optional
+	Suspend *bool `json:"suspend,omitempty"`
+
+	// Jitter delays each run by a random offset between zero and this duration after the
+	// nominal schedule. The Native engine delays the creation of the Job, while in CronJob
+	// mode an init container sleeps before the job container starts.
+	// +optional
+	Jitter *metav1.Duration `json:"jitter,omitempty"`
+
+	// BlackoutWindows are periods during which this schedule is suspended. Its CronJob
+	// is suspended for the duration of a window and resumed afterwards, without running
+	// the slots of the window.
+	// +optional
+	BlackoutWindows []BlackoutWindow `json:"blackoutWindows,omitempty"`
+
+	// OnlyOn names Calendars whose days are the only days this schedule runs on.
+	// With several Calendars, a day of any of them is enough.
+	// +optional
+	OnlyOn []string `json:"onlyOn,omitempty"`
+
+	// SkipOn names Calendars whose days this schedule never runs on, such as holidays.
+	// +optional
+	SkipOn []string `json:"skipOn,omitempty"`
+
+	// StartAt is the time before which the schedule does not run.
+	// +optional
+	StartAt *metav1.Time `json:"startAt,omitempty"`
+
+	// EndAt is the time from which the schedule no longer runs. Once reached, the
+	// schedule is marked Completed and its CronJob is suspended.
+	// +optional
+	EndAt *metav1.Time `json:"endAt,omitempty"`
+
+	// MaxRuns is the number of runs after which the schedule is marked Completed and its
+	// CronJob is suspended. The Native engine never exceeds it; a CronJob may start one
+	// more run if it fires before the controller observes the previous one.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxRuns *int32 `json:"maxRuns,omitempty"`
+
+	// CatchUpPolicy decides which slots missed while the controller or the cluster was
+	// down are run. CronJob schedules support None and Latest; All requires mode Native.
+	// +kubebuilder:default=Latest
+	// +optional
+	CatchUpPolicy CatchUpPolicy `json:"catchUpPolicy,omitempty"`
+
+	// MaxCatchUpRuns bounds the number of missed slots run by the All policy, keeping the
+	// most recent ones. Defaults to 10.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxCatchUpRuns *int32 `json:"maxCatchUpRuns,omitempty"`
+
+	// Steps are containers run one after another before the schedule's own container,
+	// sharing the workspace mounted at /workspace.
+	// +kubebuilder:validation:MaxItems=20
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Steps []Step `json:"steps,omitempty"`
+
+	// StepsMode selects whether the steps run as init containers of the pod of the run or
+	// as a chain of Jobs.
+	// +kubebuilder:default=InitContainers
+	// +optional
+	StepsMode StepsMode `json:"stepsMode,omitempty"`
+
+	// WorkspaceClaimName is a PersistentVolumeClaim mounted at /workspace in the steps and
+	// the schedule's container. Without it, the workspace is an emptyDir, which is only
+	// shared between steps in InitContainers mode.
+	// +optional
+	WorkspaceClaimName string `json:"workspaceClaimName,omitempty"`
+
+	// Priority orders the runs of the schedule waiting for a SchedulerQuota against those
+	// of other schedules in the namespace. Runs of higher priority are released first.
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+
+	// LockGroup makes the runs of the schedule mutually exclusive with those of every
+	// schedule in the same group, in this or other Schedulers. Schedules in a lock group
+	// are run by the controller rather than by a CronJob, whatever the Mode.
+	// +optional
+	LockGroup *LockGroup `json:"lockGroup,omitempty"`
+
+	// DependsOn lists schedules of the same Scheduler this schedule runs after. Once the
+	// runs of all of them for the same logical slot have succeeded, the controller starts
+	// a run of this schedule for that slot. Dependencies must not form a cycle.
+	// +kubebuilder:validation:items:MinLength=1
+	// +listType=set
+	// +optional
+	DependsOn []string `json:"dependsOn,omitempty"`
+
+	// Backfill requests a run for every slot of the schedule between Start and End. The
+	// Jobs are created by the controller whatever the Mode, a few at a time, with the
+	// logical time of their slot in the SCHEDULED_TIME environment variable. Changing
+	// Start or End starts a new backfill.
+	// +optional
+	Backfill *BackfillRequest `json:"backfill,omitempty"`
+
+	// RetryPolicy makes the controller create a new Job for a failed run, after a delay
+	// that grows with every attempt, rather than retrying its pod right away as the
+	// Job's own backoff does. Its Jobs have a backoffLimit of 0 and restartPolicy Never,
+	// unless a step sets its own Retries.
+	// +optional
+	RetryPolicy *RetryPolicy `json:"retryPolicy,omitempty"`
+
+	// FailureThreshold opens the circuit of the schedule once this many of its runs have
+	// failed in a row: the schedule is suspended, and the CircuitOpen condition reports
+	// why, until the schedule is changed or CircuitCooldown has passed. Runs that are
+	// retried count once, with their last attempt.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	FailureThreshold *int32 `json:"failureThreshold,omitempty"`
+
+	// CircuitCooldown closes the circuit of the schedule this long after it opened, which
+	// resumes it. Without it, the circuit stays open until the schedule is changed.
+	// +optional
+	CircuitCooldown *metav1.Duration `json:"circuitCooldown,omitempty"`
+
+	// Preconditions must all be met for a run of the schedule to start, or the run is
+	// Skipped. The controller checks them before creating the Job of a Native, one-off
+	// or dependent run; CronJob runs check them in an init container, whose service
+	// account must be allowed to get the Deployments involved. The pods of CronJob runs
+	// with preconditions use restartPolicy Never, so a failing container is retried in a
+	// new pod, within backoffLimit, instead of being restarted in place.
+	// +kubebuilder:validation:MaxItems=10
+	// +optional
+	Preconditions []Precondition `json:"preconditions,omitempty"`
+
+	// Output configures how the output of finished runs is captured into their
+	// ScheduleRuns. Termination messages are always captured, up to the default size.
+	// +optional
+	Output *OutputCapture `json:"output,omitempty"`
+}
+
+// OutputCapture configures the excerpts of the output of a run recorded when it finishes.
+type OutputCapture struct {
+	// LogLines is the number of lines at the end of the job container's log captured
+	// when a run finishes. Zero captures no logs.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=1000
+	// +optional
+	LogLines int32 `json:"logLines,omitempty"`
+
+	// LogsOnSuccess also captures the logs of runs that succeeded. By default only the
+	// logs of failed runs are captured.
+	// +optional
+	LogsOnSuccess bool `json:"logsOnSuccess,omitempty"`
+
+	// MaxBytes is the size the termination message and the log excerpt are each
+	// truncated to, keeping their end.
+	// +kubebuilder:validation:Minimum=64
+	// +kubebuilder:validation:Maximum=4096
+	// +kubebuilder:default=1024
+	// +optional
+	MaxBytes int32 `json:"maxBytes,omitempty"`
+
+	// Redact is a list of regular expressions (RE2 syntax) whose matches are replaced by
+	// [REDACTED] in captured output. Output is not captured while a pattern is invalid.
+	// +kubebuilder:validation:MaxItems=20
+	// +optional
+	Redact []string `json:"redact,omitempty"`
+}
+
+// Precondition is a check a run of a schedule waits on. Exactly one of its fields is set.
+// +kubebuilder:validation:XValidation:rule="(has(self.configMap) ? 1 : 0) + (has(self.deployment) ? 1 : 0) + (has(self.http) ? 1 : 0) == 1",message="exactly one of configMap, deployment and http must be set"
+type Precondition struct {
+	// ConfigMap is met when a key of a ConfigMap has the given value.
+	// +optional
+	ConfigMap *ConfigMapPrecondition `json:"configMap,omitempty"`
+
+	// Deployment is met when a Deployment is Available.
+	// +optional
+	Deployment *DeploymentPrecondition `json:"deployment,omitempty"`
+
+	// HTTP is met when a GET request to a URL returns 200. The controller, which sends
+	// the request from its own network unless the schedule runs as a CronJob, does not
+	// follow redirects.
+	// +optional
+	HTTP *HTTPPrecondition `json:"http,omitempty"`
+}
+
+// ConfigMapPrecondition checks a flag in a ConfigMap in the namespace of the Scheduler.
+type ConfigMapPrecondition struct {
+	// Name is the name of the ConfigMap.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+
+	// Key is the key of the flag.
+	// +kubebuilder:validation:MinLength=1
+	Key string `json:"key"`
+
+	// Value is the value the flag must have.
+	// +kubebuilder:default="true"
+	// +optional
+	Value string `json:"value,omitempty"`
+}
+
+// DeploymentPrecondition checks a Deployment in the namespace of the Scheduler.
+type DeploymentPrecondition struct {
+	// Name is the name of the Deployment.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// HTTPPrecondition checks a health endpoint.
+type HTTPPrecondition struct {
+	// URL is the http or https URL requested.
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url"`
+}
+
+// RetryPolicy decides whether and when a failed run is attempted again.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts of a run, including the first one.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// +kubebuilder:default=3
+	// +optional
+	MaxAttempts int32 `json:"maxAttempts,omitempty"`
+
+	// InitialDelay is how long after the first attempt failed the second one starts.
+	// Defaults to 10s.
+	// +optional
+	InitialDelay *metav1.Duration `json:"initialDelay,omitempty"`
+
+	// Multiplier multiplies the delay before every further attempt.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=2
+	// +optional
+	Multiplier int32 `json:"multiplier,omitempty"`
+
+	// MaxDelay caps the delay between two attempts. Defaults to 1h.
+	// +optional
+	MaxDelay *metav1.Duration `json:"maxDelay,omitempty"`
+
+	// RetryOn lists the exit codes a failed attempt is retried for. Attempts failing with
+	// another exit code, or without one such as those past their deadline, are not
+	// retried. When empty, every failure is retried.
+	// +listType=set
+	// +optional
+	RetryOn []int32 `json:"retryOn,omitempty"`
+}
+
+// LockGroup is a group of schedules whose runs never overlap.
+type LockGroup struct {
+	// Name identifies the group within its scope.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name"`
+
+	// Scope is Namespace to share the group with schedules in the same namespace only,
+	// or Cluster to share it with schedules in every namespace.
+	// +kubebuilder:default=Namespace
+	// +optional
+	Scope LockScope `json:"scope,omitempty"`
+
+	// Policy decides what happens to a scheduled run that is due while another member
+	// of the group is running. Runs started by hand, by a backfill or by dependencies
+	// always wait for the lock.
+	// +kubebuilder:default=Delay
+	// +optional
+	Policy LockPolicy `json:"policy,omitempty"`
+}
+
+// Step is a container run as part of the pipeline of a schedule.
+type Step struct {
+	// Name identifies the step. "main" is reserved for the schedule's own container.
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	// +kubebuilder:validation:MaxLength=40
+	// +kubebuilder:validation:XValidation:rule="self != 'main'",message="main is reserved for the schedule's own container"
+	Name string `json:"name"`
+
+	// Image is the container image of the step. Defaults to the image of the schedule.
+	// +optional
+	Image string `json:"image,omitempty"`
+
+	// Params are the command line arguments of the step, rendered like those of the
+	// schedule.
+	// +optional
+	Params []string `json:"params,omitempty"`
+
+	// Env is a list of environment variables to set in the step's container, after
+	// those describing the run.
+	// +optional
+	Env []corev1.EnvVar `json:"env,omitempty"`
+
+	// Retries is the number of times the Job of a failed step is retried in Jobs mode.
+	// Defaults to the backoff limit of Kubernetes Jobs.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Retries *int32 `json:"retries,omitempty"`
+
+	// ContinueOnError runs the next steps in Jobs mode even if this one fails.
+	// +optional
+	ContinueOnError bool `json:"continueOnError,omitempty"`
+}
+
+// BackfillRequest is a range of past slots to run.
+type BackfillRequest struct {
+	// Start is the earliest slot to run, inclusive.
+	Start metav1.Time `json:"start"`
+
+	// End is the latest slot to run, inclusive.
+	End metav1.Time `json:"end"`
+}
+
+// RunPhase is the lifecycle phase of a single run of a schedule.
+// +kubebuilder:validation:Enum=Pending;Running;Succeeded;Failed;Skipped
+type RunPhase string
+
+const (
+	// RunPhasePending means the Job exists but its container has not started yet.
+	RunPhasePending RunPhase = "Pending"
+
+	// RunPhaseRunning means the job container has started and the Job has not finished.
+	RunPhaseRunning RunPhase = "Running"
+
+	// RunPhaseSucceeded means the Job completed successfully.
+	RunPhaseSucceeded RunPhase = "Succeeded"
+
+	// RunPhaseFailed means the Job failed.
+	RunPhaseFailed RunPhase = "Failed"
+
+	// RunPhaseSkipped means the run did not start because a precondition was not met.
+	RunPhaseSkipped RunPhase = "Skipped"
+)
+
+// RunStatus defines the observed state of a single run of a schedule
+type RunStatus struct {
+	// JobName is the name of the Job executing the run.
+	JobName string `json:"jobName"`
+
+	// Phase is the lifecycle phase of the run.
+	// +optional
+	Phase RunPhase `json:"phase,omitempty"`
+
+	// Trigger is "manual" for runs started through the trigger annotation, "backfill" for
+	// runs started by a backfill request, "dependency" for runs started after the
+	// schedules they depend on, and empty for runs started by the schedule.
+	// +optional
+	Trigger string `json:"trigger,omitempty"`
+
+	// ScheduledTime is the logical time the run was scheduled for.
+	// +optional
+	ScheduledTime *metav1.Time `json:"scheduledTime,omitempty"`
+
+	// StartTime is when the job container started.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// StartOffset is how long after ScheduledTime the job container started, including
+	// any jitter.
+	// +optional
+	StartOffset *metav1.Duration `json:"startOffset,omitempty"`
+
+	// CompletionTime is when the Job finished.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Attempt is the attempt of the run executed by JobName, starting at 1. Runs of a
+	// schedule with a RetryPolicy are reported once, with their latest attempt.
+	// +optional
+	Attempt int32 `json:"attempt,omitempty"`
+
+	// NextRetryTime is when the next attempt of the failed run starts.
+	// +optional
+	NextRetryTime *metav1.Time `json:"nextRetryTime,omitempty"`
+
+	// Reason explains why a Skipped run did not start.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Steps reports the steps of the run of a schedule with steps, ending with main.
+	// +optional
+	Steps []StepStatus `json:"steps,omitempty"`
+}
+
+// StepStatus defines the observed state of a step of a run.
+type StepStatus struct {
+	// Name is the name of the step, or main for the schedule's own container.
+	Name string `json:"name"`
+
+	// JobName is the name of the Job of the step in Jobs mode.
+	// +optional
+	JobName string `json:"jobName,omitempty"`
+
+	// Phase is the lifecycle phase of the step, Pending while it has not started.
+	Phase RunPhase `json:"phase"`
+
+	// Retries is the number of times the step's container was restarted or its pod
+	// replaced after a failure.
+	// +optional
+	Retries int32 `json:"retries,omitempty"`
+}
+
+// ScheduleStatus defines the observed state of a single schedule
+type ScheduleStatus struct {
+	// Name is the name of the schedule this status refers to.
+	Name string `json:"name"`
+
+	// EffectiveSchedule is the expression actually run, after H tokens are resolved,
+	// staggering is applied and the expression is translated for the CronJob.
+	// +optional
+	EffectiveSchedule string `json:"effectiveSchedule,omitempty"`
+
+	// LastFireTime is the logical time of the most recent run of the schedule.
+	// +optional
+	LastFireTime *metav1.Time `json:"lastFireTime,omitempty"`
+
+	// NextFireTime is the next time the Native engine will fire the schedule. For CronJob
+	// schedules restricted by Calendars, it is the next run on an eligible day.
+	// +optional
+	NextFireTime *metav1.Time `json:"nextFireTime,omitempty"`
+
+	// RunCount is the number of runs started for the schedule, counted against MaxRuns.
+	// +optional
+	RunCount int32 `json:"runCount,omitempty"`
+
+	// Completed is set once the schedule has reached its EndAt or MaxRuns and will not
+	// run again unless they are raised, or once the Job of a RunAt schedule has finished.
+	// +optional
+	Completed bool `json:"completed,omitempty"`
+
+	// CompletionReason is EndAtReached, MaxRunsReached or RunFinished when Completed is set.
+	// +optional
+	CompletionReason string `json:"completionReason,omitempty"`
+
+	// HeldSince is when the controller suspended the CronJob of the schedule for a
+	// blackout window, a day excluded by its Calendars, its StartAt, EndAt and MaxRuns or
+	// an open circuit, while it keeps it suspended. Native schedules, which skip the other
+	// slots by themselves, only record open circuits.
+	// +optional
+	HeldSince *metav1.Time `json:"heldSince,omitempty"`
+
+	// ResumedAt is when the controller last resumed the schedule after holding it. Until
+	// the schedule runs again, the slots missed before are not caught up.
+	// +optional
+	ResumedAt *metav1.Time `json:"resumedAt,omitempty"`
+
+	// Backfill reports the progress of the schedule's backfill request.
+	// +optional
+	Backfill *BackfillStatus `json:"backfill,omitempty"`
+
+	// Lock reports the lock group of the schedule.
+	// +optional
+	Lock *LockStatus `json:"lock,omitempty"`
+
+	// Circuit reports the circuit breaker of a schedule with a FailureThreshold.
+	// +optional
+	Circuit *CircuitStatus `json:"circuit,omitempty"`
+
+	// Notifications tracks the runs of the schedule already considered for notifications.
+	// +optional
+	Notifications *ScheduleNotificationStatus `json:"notifications,omitempty"`
+
+	// Runs lists the most recent runs of the schedule that still have a Job, newest first.
+	// +optional
+	Runs []RunStatus `json:"runs,omitempty"`
+}
+
+// ScheduleNotificationStatus tracks what was notified about the runs of a schedule, so
+// that every run is notified once.
+type ScheduleNotificationStatus struct {
+	// LastObservedJob and LastObservedTime identify the most recent finished run
+	// considered, and when it finished.
+	// +optional
+	LastObservedJob string `json:"lastObservedJob,omitempty"`
+	// +optional
+	LastObservedTime *metav1.Time `json:"lastObservedTime,omitempty"`
+
+	// LastPhase is the phase of the most recent finished run, Succeeded or Failed.
+	// +optional
+	LastPhase RunPhase `json:"lastPhase,omitempty"`
+
+	// LongRunning lists the running Jobs notified as LongRunning.
+	// +optional
+	LongRunning []string `json:"longRunning,omitempty"`
+
+	// LastMissedTime is the most recent slot notified as Missed.
+	// +optional
+	LastMissedTime *metav1.Time `json:"lastMissedTime,omitempty"`
+}
+
+// BackfillStatus reports the progress of a backfill request.
+type BackfillStatus struct {
+	// Start and End identify the request being processed.
+	Start metav1.Time `json:"start"`
+	End   metav1.Time `json:"end"`
+
+	// LastScheduledTime is the latest slot a Job was created for.
+	// +optional
+	LastScheduledTime *metav1.Time `json:"lastScheduledTime,omitempty"`
+
+	// Runs is the number of Jobs created for the request.
+	// +optional
+	Runs int32 `json:"runs,omitempty"`
+
+	// Completed is set once a Job was created for every slot of the request.
+	// +optional
+	Completed bool `json:"completed,omitempty"`
+}
+
+// CircuitStatus reports the circuit breaker of a schedule.
+type CircuitStatus struct {
+	// ConsecutiveFailures is the number of runs that failed in a row, up to the most
+	// recent finished run.
+	// +optional
+	ConsecutiveFailures int32 `json:"consecutiveFailures,omitempty"`
+
+	// LastFailure describes why the most recent failed run failed.
+	// +optional
+	LastFailure string `json:"lastFailure,omitempty"`
+
+	// LastObservedJob and LastObservedTime identify the most recent finished run counted,
+	// and when it finished.
+	// +optional
+	LastObservedJob string `json:"lastObservedJob,omitempty"`
+	// +optional
+	LastObservedTime *metav1.Time `json:"lastObservedTime,omitempty"`
+
+	// OpenedAt is when the circuit opened and suspended the schedule. It is unset while
+	// the circuit is closed.
+	// +optional
+	OpenedAt *metav1.Time `json:"openedAt,omitempty"`
+
+	// SpecHash is the hash of the schedule when the circuit opened. The circuit closes
+	// once the schedule no longer matches it.
+	// +optional
+	SpecHash string `json:"specHash,omitempty"`
+}
+
+// PendingNotification is a notification whose delivery to a sink failed.
+type PendingNotification struct {
+	// Sink is the name of the sink.
+	Sink string `json:"sink"`
+
+	// Trigger, Schedule and Run identify what is notified. Run is the name of the Job of
+	// the run, or of the Job a missed slot would have had.
+	Trigger  NotificationTrigger `json:"trigger"`
+	Schedule string              `json:"schedule"`
+	Run      string              `json:"run"`
+
+	// ScheduledTime is the logical time of the run.
+	// +optional
+	ScheduledTime *metav1.Time `json:"scheduledTime,omitempty"`
+
+	// Message describes what happened.
+	Message string `json:"message"`
+
+	// Time is when it happened.
+	Time metav1.Time `json:"time"`
+
+	// Attempts is the number of failed deliveries.
+	Attempts int32 `json:"attempts"`
+
+	// NextAttemptTime is when the delivery is attempted again.
+	NextAttemptTime metav1.Time `json:"nextAttemptTime"`
+
+	// LastError is why the last delivery failed.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+}
+
+// LockStatus reports the state of the lock group of a schedule.
+type LockStatus struct {
+	// Group is the name of the lock group.
+	Group string `json:"group"`
+
+	// Holder is the namespace and name of the Job holding the lock, unset while the lock
+	// is free.
+	// +optional
+	Holder string `json:"holder,omitempty"`
+
+	// WaitingSince is when a run of the schedule started waiting for the lock.
+	// +optional
+	WaitingSince *metav1.Time `json:"waitingSince,omitempty"`
+
+	// Skipped is the number of slots dropped by the Skip policy while the lock was held.
+	// +optional
+	Skipped int32 `json:"skipped,omitempty"`
+}
+
+// TriggerStatus describes a run started by hand.
+type TriggerStatus struct {
+	// Schedule is the name of the triggered schedule.
+	Schedule string `json:"schedule"`
+
+	// Nonce is the nonce of the trigger annotation.
+	Nonce string `json:"nonce"`
+
+	// JobName is the name of the Job started by the trigger.
+	JobName string `json:"jobName"`
+
+	// TriggeredBy is the field manager that set the trigger annotation, as recorded in the
+	// managedFields of the Scheduler.
+	// +optional
+	TriggeredBy string `json:"triggeredBy,omitempty"`
+
+	// Time is when the controller started the run.
+	Time metav1.Time `json:"time"`
+}
+
+// ChainStatus describes the runs of a chain of dependent schedules for one logical slot.
+type ChainStatus struct {
+	// Name is the name of the first schedule of the chain without dependencies.
+	Name string `json:"name"`
+
+	// ScheduledTime is the logical slot shared by the runs of the chain.
+	ScheduledTime metav1.Time `json:"scheduledTime"`
+
+	// Phase is Failed once a run of the chain failed, Succeeded once every run succeeded,
+	// Running once a run started, and Pending otherwise.
+	Phase RunPhase `json:"phase"`
+
+	// Steps are the runs of the schedules of the chain, each after its dependencies.
+	Steps []ChainStepStatus `json:"steps"`
+}
+
+// ChainStepStatus describes the run of one schedule of a chain.
+type ChainStepStatus struct {
+	// Schedule is the name of the schedule.
+	Schedule string `json:"schedule"`
+
+	// JobName is the name of the Job of the run, unset while it has not started.
+	// +optional
+	JobName string `json:"jobName,omitempty"`
+
+	// Phase is the lifecycle phase of the run, Pending while it has not started.
+	Phase RunPhase `json:"phase"`
 }
 
 // SchedulerStatus defines the observed state of Scheduler
@@ -43,6 +1069,32 @@ type SchedulerStatus struct {
 	// +optional
 	Active []corev1.ObjectReference `json:"active,omitempty"`
 
+	// Schedules holds the observed state of each schedule.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Schedules []ScheduleStatus `json:"schedules,omitempty"`
+
+	// LastTrigger is the last run started through the trigger annotation.
+	// +optional
+	LastTrigger *TriggerStatus `json:"lastTrigger,omitempty"`
+
+	// Chains lists the most recent slots of every chain of dependent schedules, newest
+	// first.
+	// +optional
+	Chains []ChainStatus `json:"chains,omitempty"`
+
+	// PendingNotifications lists the notifications waiting to be delivered again after
+	// their sink failed.
+	// +optional
+	PendingNotifications []PendingNotification `json:"pendingNotifications,omitempty"`
+
+	// ScheduleHashes are the hashes of the specs of the schedules, by name, when they
+	// were last published as CloudEvents. They are only kept while the controller
+	// publishes CloudEvents.
+	// +optional
+	ScheduleHashes map[string]string `json:"scheduleHashes,omitempty"`
+
 	// Conditions store the status of the Scheduler in a Kubernetes friendly way.
 	// This follows the standard Kubernetes API conventions.
 	// +kubebuilder:validation:Optional
@@ -0,0 +1,79 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SchedulerQuotaSpec defines the limits on the Jobs of the Schedulers of a namespace
+type SchedulerQuotaSpec struct {
+	// MaxRunning is the number of Jobs created by Schedulers that may run at once in the
+	// namespace. With several SchedulerQuotas in a namespace, the smallest limit applies.
+	// +kubebuilder:validation:Minimum=1
+	MaxRunning int32 `json:"maxRunning"`
+}
+
+// QueuedRun describes a Job waiting for the quota.
+type QueuedRun struct {
+	// JobName is the name of the queued Job.
+	JobName string `json:"jobName"`
+
+	// Scheduler and Schedule identify the schedule the run belongs to.
+	Scheduler string `json:"scheduler"`
+	Schedule  string `json:"schedule"`
+
+	// Priority is the priority of the schedule. Runs of higher priority are released first.
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+
+	// QueuedSince is when the Job was created.
+	QueuedSince metav1.Time `json:"queuedSince"`
+}
+
+// SchedulerQuotaStatus defines the observed state of SchedulerQuota
+type SchedulerQuotaStatus struct {
+	// Running is the number of Jobs created by Schedulers running in the namespace.
+	// +optional
+	Running int32 `json:"running,omitempty"`
+
+	// Queued is the number of Jobs waiting for the quota.
+	// +optional
+	Queued int32 `json:"queued,omitempty"`
+
+	// Pending lists the first Jobs waiting for the quota, in the order they will be
+	// released.
+	// +optional
+	Pending []QueuedRun `json:"pending,omitempty"`
+
+	// ObservedGeneration is the most recent generation observed for this SchedulerQuota.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Max",type=integer,JSONPath=`.spec.maxRunning`
+// +kubebuilder:printcolumn:name="Running",type=integer,JSONPath=`.status.running`
+// +kubebuilder:printcolumn:name="Queued",type=integer,JSONPath=`.status.queued`
+
+// SchedulerQuota limits how many Jobs created by Schedulers run at once in its namespace.
+// Jobs over the limit are created suspended and released by priority as others finish.
+type SchedulerQuota struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SchedulerQuotaSpec   `json:"spec,omitempty"`
+	Status SchedulerQuotaStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// SchedulerQuotaList contains a list of SchedulerQuota
+type SchedulerQuotaList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []SchedulerQuota `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&SchedulerQuota{}, &SchedulerQuotaList{})
+}
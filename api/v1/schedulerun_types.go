@@ -0,0 +1,106 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ScheduleRunSpec identifies the run of a schedule a ScheduleRun records
+type ScheduleRunSpec struct {
+	// SchedulerName and ScheduleName identify the schedule the run belongs to.
+	SchedulerName string `json:"schedulerName"`
+	ScheduleName  string `json:"scheduleName"`
+
+	// JobName is the name of the Job that executed the run, or would have for a run
+	// that was skipped.
+	JobName string `json:"jobName"`
+
+	// ScheduledTime is the logical time the run was scheduled for.
+	// +optional
+	ScheduledTime *metav1.Time `json:"scheduledTime,omitempty"`
+
+	// Trigger is what started the run, as reported in the run status of the Scheduler.
+	// +optional
+	Trigger string `json:"trigger,omitempty"`
+
+	// Attempt is the attempt of the run executed by JobName, starting at 1.
+	// +optional
+	Attempt int32 `json:"attempt,omitempty"`
+}
+
+// ScheduleRunStatus defines the observed outcome of a run
+type ScheduleRunStatus struct {
+	// Phase is the lifecycle phase of the run.
+	// +optional
+	Phase RunPhase `json:"phase,omitempty"`
+
+	// StartTime is when the job container started.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// CompletionTime is when the Job finished.
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// ExitCode is the exit code of the last job container that terminated.
+	// +optional
+	ExitCode *int32 `json:"exitCode,omitempty"`
+
+	// TerminationMessage is the termination message of the last job container that
+	// terminated, truncated and redacted as configured by the Output of the schedule.
+	// +optional
+	TerminationMessage string `json:"terminationMessage,omitempty"`
+
+	// Logs is the end of the log of the last job container that terminated, captured
+	// when the run finished if the Output of the schedule asks for it.
+	// +optional
+	Logs string `json:"logs,omitempty"`
+
+	// Outputs are the values of the JSON object the job container wrote to its
+	// termination message, if any. Strings are kept as they are and other values as
+	// JSON. Later runs read them in templates through .Outputs.
+	// +optional
+	Outputs map[string]string `json:"outputs,omitempty"`
+
+	// NodeName is the node the last pod of the run was scheduled to.
+	// +optional
+	NodeName string `json:"nodeName,omitempty"`
+
+	// Reason explains why a Skipped run did not start.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Scheduler",type=string,JSONPath=`.spec.schedulerName`
+// +kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.spec.scheduleName`
+// +kubebuilder:printcolumn:name="Scheduled",type=date,JSONPath=`.spec.scheduledTime`
+// +kubebuilder:printcolumn:name="Trigger",type=string,JSONPath=`.spec.trigger`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Exit",type=integer,JSONPath=`.status.exitCode`
+// +kubebuilder:printcolumn:name="Node",type=string,JSONPath=`.status.nodeName`,priority=1
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ScheduleRun records a run of a schedule. ScheduleRuns are created by the controller
+// for every execution, are owned by their Scheduler and outlive the Jobs that ran them
+// until the run history retention of the Scheduler removes them.
+type ScheduleRun struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ScheduleRunSpec   `json:"spec,omitempty"`
+	Status ScheduleRunStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ScheduleRunList contains a list of ScheduleRun
+type ScheduleRunList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ScheduleRun `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ScheduleRun{}, &ScheduleRunList{})
+}
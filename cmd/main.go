@@ -1,18 +1,28 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"net/http"
 	"os"
+	"time"
 
 	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cloudevents"
 	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/controller"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/tracing"
 
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server" // Import metrics server package
 )
@@ -30,15 +40,50 @@ func init() {
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
+	var staggerWindow time.Duration
+	var cloudEventsSink string
+	var cloudEventsQueueSize int
+	var otlpEndpoint string
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager.")
+	flag.DurationVar(&staggerWindow, "stagger-window", 0,
+		"Spread schedules with a fixed minute over this window. Schedulers can override it; 0 disables staggering.")
+	flag.StringVar(&cloudEventsSink, "cloudevents-sink", "",
+		"Publish CloudEvents about schedules and runs to this HTTP URL. Empty disables them.")
+	flag.IntVar(&cloudEventsQueueSize, "cloudevents-queue-size", cloudevents.DefaultQueueSize,
+		"The number of CloudEvents buffered while the sink is slow or down. Further events are dropped.")
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", "",
+		"Export traces of reconciles with OTLP over HTTP to this URL, such as http://otel-collector:4318. "+
+			"Defaults to the standard OTEL_EXPORTER_OTLP_ENDPOINT variables; tracing is disabled when none is set.")
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseDevMode(true)))
 
+	shutdownTracing := func(context.Context) error { return nil }
+	if tracing.Enabled(otlpEndpoint) {
+		shutdown, err := tracing.Setup(context.Background(), otlpEndpoint)
+		if err != nil {
+			setupLog.Error(err, "unable to set up tracing")
+			os.Exit(1)
+		}
+		shutdownTracing = shutdown
+	}
+
+	// Only Pods of scheduled Jobs are read, so the cache does not hold every Pod in the cluster
+	scheduledPods, err := labels.NewRequirement("scheduler", selection.Exists, nil)
+	if err != nil {
+		setupLog.Error(err, "unable to build the Pod cache selector")
+		os.Exit(1)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:         scheme,
+		Cache: cache.Options{
+			ByObject: map[client.Object]cache.ByObject{
+				&corev1.Pod{}: {Label: labels.NewSelector().Add(*scheduledPods)},
+			},
+		},
 		Metrics:        metricsserver.Options{BindAddress: metricsAddr}, // Updated metrics configuration
 		LeaderElection: enableLeaderElection,
 		LeaderElectionID: "scheduler-controller.lr.labs",
@@ -48,11 +93,45 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Pod logs are a subresource the controller-runtime client does not read
+	clientset, err := kubernetes.NewForConfig(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create clientset")
+		os.Exit(1)
+	}
+
+	var events controller.CloudEventEmitter
+	if cloudEventsSink != "" {
+		emitter, err := cloudevents.NewEmitter(cloudEventsSink, cloudEventsQueueSize, ctrl.Log.WithName("cloudevents"))
+		if err != nil {
+			setupLog.Error(err, "unable to create CloudEvents emitter")
+			os.Exit(1)
+		}
+		if err := mgr.Add(emitter); err != nil {
+			setupLog.Error(err, "unable to add CloudEvents emitter")
+			os.Exit(1)
+		}
+		events = emitter
+	}
+
 	if err = (&controller.SchedulerReconciler{
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		StaggerWindow: staggerWindow,
+		APIReader:     mgr.GetAPIReader(),
+		Recorder:      mgr.GetEventRecorderFor("scheduler-controller"),
+		PodLogs:       clientset.CoreV1(),
+		CloudEvents:   events,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Scheduler")
+		os.Exit(1)
+	}
+
+	if err = (&controller.SchedulerQuotaReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Scheduler")
+		setupLog.Error(err, "unable to create controller", "controller", "SchedulerQuota")
 		os.Exit(1)
 	}
 
@@ -79,4 +158,11 @@ func main() {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
+
+	// Export the spans still buffered
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		setupLog.Error(err, "unable to flush traces")
+	}
 }
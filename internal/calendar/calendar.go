@@ -0,0 +1,98 @@
+// Package calendar resolves Calendar resources into sets of days.
+package calendar
+
+import (
+	"fmt"
+	"time"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+)
+
+// maxEventDays bounds the number of days a single event can cover.
+const maxEventDays = 366
+
+// day is a calendar day. Yearly days have a zero year.
+type day struct {
+	year  int
+	month time.Month
+	day   int
+}
+
+func dayOf(t time.Time) day {
+	return day{t.Year(), t.Month(), t.Day()}
+}
+
+// Calendar is a set of days whose boundaries follow a time zone.
+type Calendar struct {
+	loc      *time.Location
+	dates    map[day]struct{}
+	yearly   map[day]struct{}
+	weekdays [7]bool
+}
+
+// New builds a Calendar from its spec and the iCalendar data it imports, if any.
+func New(spec schedulingapiv1.CalendarSpec, ics string) (*Calendar, error) {
+	loc, err := time.LoadLocation(spec.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q: %w", spec.TimeZone, err)
+	}
+	c := &Calendar{
+		loc:    loc,
+		dates:  map[day]struct{}{},
+		yearly: map[day]struct{}{},
+	}
+
+	for _, value := range spec.Dates {
+		t, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q: expected YYYY-MM-DD", value)
+		}
+		c.dates[dayOf(t)] = struct{}{}
+	}
+
+	for _, weekday := range spec.Weekdays {
+		index, ok := weekdays[weekday]
+		if !ok {
+			return nil, fmt.Errorf("unknown weekday %q", weekday)
+		}
+		c.weekdays[index] = true
+	}
+
+	if ics != "" {
+		if err := c.addICalendar(ics); err != nil {
+			return nil, fmt.Errorf("invalid iCalendar data: %w", err)
+		}
+	}
+	return c, nil
+}
+
+var weekdays = map[schedulingapiv1.Weekday]time.Weekday{
+	"Sunday":    time.Sunday,
+	"Monday":    time.Monday,
+	"Tuesday":   time.Tuesday,
+	"Wednesday": time.Wednesday,
+	"Thursday":  time.Thursday,
+	"Friday":    time.Friday,
+	"Saturday":  time.Saturday,
+}
+
+// Contains reports whether the day of t, in the calendar's time zone, is part of the calendar.
+func (c *Calendar) Contains(t time.Time) bool {
+	local := t.In(c.loc)
+	if c.weekdays[local.Weekday()] {
+		return true
+	}
+	d := dayOf(local)
+	if _, ok := c.dates[d]; ok {
+		return true
+	}
+	_, ok := c.yearly[day{month: d.month, day: d.day}]
+	return ok
+}
+
+// NextDay returns the start of the day after the one containing t, in the calendar's
+// time zone. Membership of the calendar cannot change before then.
+func (c *Calendar) NextDay(t time.Time) time.Time {
+	local := t.In(c.loc)
+	return time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, c.loc)
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package calendar
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+)
+
+func mustTime(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	Expect(err).NotTo(HaveOccurred())
+	return t
+}
+
+const holidays = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Christmas\r\n" +
+	"DTSTART;VALUE=DATE:20241225\r\n" +
+	"DTEND;VALUE=DATE:20241227\r\n" +
+	"RRULE:FREQ=YEARLY\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Company offsite, folded\r\n" +
+	" over two lines\r\n" +
+	"DTSTART;VALUE=DATE:20250314\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"DTSTART;VALUE=DATE:20251127\r\n" +
+	"RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=4TH\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+var _ = Describe("Calendar", func() {
+	It("should contain explicit dates and weekdays in its time zone", func() {
+		c, err := New(schedulingapiv1.CalendarSpec{
+			TimeZone: "Europe/Rome",
+			Dates:    []string{"2025-06-02"},
+			Weekdays: []schedulingapiv1.Weekday{"Saturday", "Sunday"},
+		}, "")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(c.Contains(mustTime("2025-06-02T10:00:00Z"))).To(BeTrue())
+		// 23:30 UTC on Friday is already Saturday in Rome
+		Expect(c.Contains(mustTime("2025-06-06T23:30:00Z"))).To(BeTrue())
+		Expect(c.Contains(mustTime("2025-06-06T12:00:00Z"))).To(BeFalse())
+		Expect(c.Contains(mustTime("2025-06-03T12:00:00Z"))).To(BeFalse())
+	})
+
+	It("should import all-day events from iCalendar data", func() {
+		c, err := New(schedulingapiv1.CalendarSpec{}, holidays)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(c.Contains(mustTime("2030-12-25T08:00:00Z"))).To(BeTrue(), "yearly events repeat")
+		Expect(c.Contains(mustTime("2030-12-26T08:00:00Z"))).To(BeTrue(), "DTEND is exclusive")
+		Expect(c.Contains(mustTime("2030-12-27T08:00:00Z"))).To(BeFalse())
+		Expect(c.Contains(mustTime("2025-03-14T08:00:00Z"))).To(BeTrue())
+		Expect(c.Contains(mustTime("2026-03-14T08:00:00Z"))).To(BeFalse())
+		Expect(c.Contains(mustTime("2025-11-27T08:00:00Z"))).To(BeTrue())
+		Expect(c.Contains(mustTime("2026-11-27T08:00:00Z"))).To(BeFalse(), "complex rules keep their first occurrence only")
+	})
+
+	It("should return the start of the next day in its time zone", func() {
+		c, err := New(schedulingapiv1.CalendarSpec{TimeZone: "America/New_York"}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.NextDay(mustTime("2025-06-02T10:00:00Z"))).To(BeTemporally("==", mustTime("2025-06-03T04:00:00Z")))
+	})
+
+	DescribeTable("rejecting invalid calendars",
+		func(spec schedulingapiv1.CalendarSpec, ics string) {
+			_, err := New(spec, ics)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("bad date", schedulingapiv1.CalendarSpec{Dates: []string{"25-12-2025"}}, ""),
+		Entry("bad weekday", schedulingapiv1.CalendarSpec{Weekdays: []schedulingapiv1.Weekday{"Caturday"}}, ""),
+		Entry("bad time zone", schedulingapiv1.CalendarSpec{TimeZone: "Mars/Olympus"}, ""),
+		Entry("unterminated event", schedulingapiv1.CalendarSpec{}, "BEGIN:VEVENT\nDTSTART:20250101\n"),
+		Entry("event without start", schedulingapiv1.CalendarSpec{}, "BEGIN:VEVENT\nEND:VEVENT\n"),
+	)
+})
@@ -0,0 +1,136 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// event is the part of a VEVENT that decides which days it covers.
+type event struct {
+	start, end string
+	allDay     bool
+	yearly     bool
+}
+
+// addICalendar adds the days covered by the VEVENTs of an iCalendar file.
+func (c *Calendar) addICalendar(data string) error {
+	var current *event
+	for _, line := range unfold(data) {
+		name, params, value := splitProperty(line)
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			current = &event{}
+		case name == "END" && value == "VEVENT":
+			if current == nil {
+				return fmt.Errorf("END:VEVENT without BEGIN:VEVENT")
+			}
+			if err := c.addEvent(*current); err != nil {
+				return err
+			}
+			current = nil
+		case current == nil:
+		case name == "DTSTART":
+			current.start = value
+			current.allDay = strings.Contains(params, "VALUE=DATE") && !strings.Contains(params, "VALUE=DATE-TIME")
+		case name == "DTEND":
+			current.end = value
+		case name == "RRULE":
+			current.yearly = isPlainYearly(value)
+		}
+	}
+	if current != nil {
+		return fmt.Errorf("unterminated VEVENT")
+	}
+	return nil
+}
+
+// addEvent adds the days from the start of an event up to, but excluding, the day it
+// ends on. An event ending on the day it starts covers that day.
+func (c *Calendar) addEvent(e event) error {
+	if e.start == "" {
+		return fmt.Errorf("VEVENT without DTSTART")
+	}
+	start, err := c.parseDate(e.start)
+	if err != nil {
+		return err
+	}
+	end := start.AddDate(0, 0, 1)
+	if e.end != "" {
+		if end, err = c.parseDate(e.end); err != nil {
+			return err
+		}
+		if !end.After(start) {
+			end = start.AddDate(0, 0, 1)
+		}
+	}
+
+	for t, n := start, 0; t.Before(end) && n < maxEventDays; t, n = t.AddDate(0, 0, 1), n+1 {
+		d := dayOf(t)
+		if e.yearly {
+			c.yearly[day{month: d.month, day: d.day}] = struct{}{}
+		} else {
+			c.dates[d] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// parseDate returns the day of a DATE or DATE-TIME value at midnight UTC. UTC times are
+// converted to the calendar's time zone first; floating and zoned times keep their date.
+func (c *Calendar) parseDate(value string) (time.Time, error) {
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date-time %q", value)
+		}
+		local := t.In(c.loc)
+		return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC), nil
+	}
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	t, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", value)
+	}
+	return t, nil
+}
+
+// isPlainYearly reports whether a recurrence rule repeats an event on the same day every
+// year, which is how fixed-date holidays are usually published.
+func isPlainYearly(rule string) bool {
+	yearly := false
+	for _, part := range strings.Split(rule, ";") {
+		switch part {
+		case "FREQ=YEARLY":
+			yearly = true
+		case "INTERVAL=1":
+		default:
+			return false
+		}
+	}
+	return yearly
+}
+
+// unfold joins folded content lines, which continue with a leading space or tab.
+func unfold(data string) []string {
+	var lines []string
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// splitProperty splits a content line into its upper-cased name, its parameters and its value.
+func splitProperty(line string) (name, params, value string) {
+	head, value, _ := strings.Cut(line, ":")
+	name, params, _ = strings.Cut(head, ";")
+	return strings.ToUpper(name), strings.ToUpper(params), value
+}
@@ -0,0 +1,196 @@
+// Package cloudevents publishes CloudEvents to an HTTP sink in structured mode, from a
+// bounded in-memory queue so that producers never wait for the sink.
+package cloudevents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/util/uuid"
+)
+
+// ContentType is the media type of events sent in structured mode.
+const ContentType = "application/cloudevents+json"
+
+const (
+	// DefaultQueueSize is the number of events buffered while the sink is slow or down.
+	DefaultQueueSize = 1000
+
+	// timeout bounds a delivery.
+	timeout = 10 * time.Second
+
+	// maxAttempts is the number of deliveries of an event attempted before it is dropped.
+	maxAttempts = 8
+
+	// retryDelay is the delay before the second delivery of an event, doubled for every
+	// further one up to maxRetryDelay.
+	retryDelay    = time.Second
+	maxRetryDelay = time.Minute
+)
+
+// Event is a CloudEvent.
+type Event struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// NewEvent returns an event with a new ID, encoding data as JSON.
+func NewEvent(eventType, source, subject string, t time.Time, data any) (Event, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, fmt.Errorf("failed to encode data of %s event: %w", eventType, err)
+	}
+	return Event{
+		SpecVersion:     "1.0",
+		ID:              string(uuid.NewUUID()),
+		Source:          source,
+		Type:            eventType,
+		Subject:         subject,
+		Time:            t.UTC(),
+		DataContentType: "application/json",
+		Data:            encoded,
+	}, nil
+}
+
+// Emitter publishes events to an HTTP sink. Events are queued by Emit and delivered in
+// order by Start, which retries failed deliveries with a backoff. Events are dropped
+// when the queue is full, or when the sink keeps failing or rejects them.
+type Emitter struct {
+	sink   string
+	queue  chan Event
+	client *http.Client
+	log    logr.Logger
+
+	// sleep waits between deliveries, and is replaced in tests.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewEmitter returns an emitter publishing to the sink URL, buffering up to queueSize
+// events.
+func NewEmitter(sink string, queueSize int, log logr.Logger) (*Emitter, error) {
+	u, err := url.Parse(sink)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid CloudEvents sink %q: an http or https URL is required", sink)
+	}
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+	return &Emitter{
+		sink:   sink,
+		queue:  make(chan Event, queueSize),
+		client: &http.Client{Timeout: timeout},
+		log:    log,
+		sleep:  sleep,
+	}, nil
+}
+
+// Emit queues an event without blocking. It reports false when the queue is full and
+// the event was dropped.
+func (e *Emitter) Emit(event Event) bool {
+	select {
+	case e.queue <- event:
+		return true
+	default:
+		e.log.Info("Dropped event, the queue is full", "type", event.Type, "subject", event.Subject)
+		return false
+	}
+}
+
+// Start delivers the queued events until ctx is done. It implements manager.Runnable.
+func (e *Emitter) Start(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event := <-e.queue:
+			e.deliver(ctx, event)
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable: events are queued by
+// the leader only, but standbys have none to deliver.
+func (e *Emitter) NeedLeaderElection() bool {
+	return false
+}
+
+// deliver sends an event, retrying with a backoff until it is accepted, rejected or out
+// of attempts.
+func (e *Emitter) deliver(ctx context.Context, event Event) {
+	delay := retryDelay
+	for attempt := 1; ; attempt++ {
+		err := e.send(ctx, event)
+		if err == nil {
+			return
+		}
+		var rejected rejectedError
+		if errors.As(err, &rejected) || attempt == maxAttempts {
+			e.log.Error(err, "Dropped event", "type", event.Type, "subject", event.Subject, "attempts", attempt)
+			return
+		}
+		if e.sleep(ctx, delay) != nil {
+			return
+		}
+		delay = min(2*delay, maxRetryDelay)
+	}
+}
+
+// rejectedError is a response of the sink that retrying will not change.
+type rejectedError struct {
+	error
+}
+
+// send posts an event in structured mode.
+func (e *Emitter) send(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return rejectedError{err}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.sink, bytes.NewReader(body))
+	if err != nil {
+		return rejectedError{err}
+	}
+	req.Header.Set("Content-Type", ContentType)
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("CloudEvents sink request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return nil
+	}
+	excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+	err = fmt.Errorf("CloudEvents sink returned %s: %s", resp.Status, strings.TrimSpace(string(excerpt)))
+	// Other client errors reject the event itself
+	if resp.StatusCode >= 400 && resp.StatusCode <= 499 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return rejectedError{err}
+	}
+	return err
+}
+
+// sleep waits for d, or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
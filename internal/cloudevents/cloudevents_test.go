@@ -0,0 +1,138 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cloudevents
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Emitter", func() {
+	var (
+		server   *httptest.Server
+		mu       sync.Mutex
+		statuses []int
+		received []*http.Request
+		bodies   [][]byte
+		emitter  *Emitter
+		delays   []time.Duration
+	)
+
+	BeforeEach(func() {
+		statuses, received, bodies, delays = nil, nil, nil, nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+			body, _ := io.ReadAll(r.Body)
+			received = append(received, r)
+			bodies = append(bodies, body)
+			status := http.StatusAccepted
+			if len(statuses) > 0 {
+				status, statuses = statuses[0], statuses[1:]
+			}
+			w.WriteHeader(status)
+		}))
+		DeferCleanup(server.Close)
+
+		var err error
+		emitter, err = NewEmitter(server.URL, 2, logr.Discard())
+		Expect(err).NotTo(HaveOccurred())
+		emitter.sleep = func(ctx context.Context, d time.Duration) error {
+			delays = append(delays, d)
+			return nil
+		}
+	})
+
+	event := func(eventType string) Event {
+		e, err := NewEvent(eventType, "/apis/lr.labs/v1/namespaces/default/schedulers/reports", "nightly",
+			time.Date(2025, 1, 1, 2, 0, 0, 0, time.UTC), map[string]string{"schedule": "nightly"})
+		Expect(err).NotTo(HaveOccurred())
+		return e
+	}
+
+	It("rejects sinks that are not HTTP URLs", func() {
+		_, err := NewEmitter("kafka://events", 0, logr.Discard())
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("posts events in structured mode", func() {
+		emitter.deliver(context.Background(), event("lr.labs.schedule.created"))
+		Expect(received).To(HaveLen(1))
+		Expect(received[0].Header.Get("Content-Type")).To(Equal(ContentType))
+
+		var decoded map[string]any
+		Expect(json.Unmarshal(bodies[0], &decoded)).To(Succeed())
+		Expect(decoded).To(HaveKeyWithValue("specversion", "1.0"))
+		Expect(decoded).To(HaveKeyWithValue("type", "lr.labs.schedule.created"))
+		Expect(decoded).To(HaveKeyWithValue("source", "/apis/lr.labs/v1/namespaces/default/schedulers/reports"))
+		Expect(decoded).To(HaveKeyWithValue("subject", "nightly"))
+		Expect(decoded).To(HaveKeyWithValue("time", "2025-01-01T02:00:00Z"))
+		Expect(decoded).To(HaveKeyWithValue("data", map[string]any{"schedule": "nightly"}))
+		Expect(decoded["id"]).NotTo(BeEmpty())
+	})
+
+	It("retries with a backoff until the sink accepts the event", func() {
+		statuses = []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusBadGateway}
+		emitter.deliver(context.Background(), event("lr.labs.run.started"))
+		Expect(received).To(HaveLen(4))
+		Expect(delays).To(Equal([]time.Duration{time.Second, 2 * time.Second, 4 * time.Second}))
+		Expect(bodies[3]).To(Equal(bodies[0]))
+	})
+
+	It("drops events the sink rejects or keeps failing", func() {
+		statuses = []int{http.StatusBadRequest}
+		emitter.deliver(context.Background(), event("lr.labs.run.failed"))
+		Expect(received).To(HaveLen(1))
+
+		statuses = make([]int, 20)
+		for i := range statuses {
+			statuses[i] = http.StatusInternalServerError
+		}
+		emitter.deliver(context.Background(), event("lr.labs.run.failed"))
+		Expect(received).To(HaveLen(1 + maxAttempts))
+		Expect(delays[len(delays)-1]).To(Equal(maxRetryDelay))
+	})
+
+	It("drops events without blocking when the queue is full", func() {
+		Expect(emitter.Emit(event("lr.labs.run.started"))).To(BeTrue())
+		Expect(emitter.Emit(event("lr.labs.run.started"))).To(BeTrue())
+		Expect(emitter.Emit(event("lr.labs.run.succeeded"))).To(BeFalse())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_ = emitter.Start(ctx)
+		}()
+		Eventually(func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(received)
+		}).Should(Equal(2))
+		cancel()
+		Eventually(done).Should(BeClosed())
+	})
+})
@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cronexpr"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cronjobbuilder"
+)
+
+// backfillParallelism is the number of backfill Jobs of a schedule allowed to run at
+// once. The next slots are started as they finish, since their Jobs are watched.
+const backfillParallelism = 3
+
+// reconcileBackfills advances the backfill request of every schedule.
+func (r *SchedulerReconciler) reconcileBackfills(ctx context.Context, scheduler *schedulingapiv1.Scheduler, calendars map[string]calendarFilter) []error {
+	log := log.FromContext(ctx)
+	var reconcileErrors []error
+	for _, schedule := range scheduler.Spec.Schedules {
+		if err := r.reconcileBackfill(ctx, scheduler, schedule, calendars[schedule.Name]); err != nil {
+			log.Error(err, "Failed to backfill schedule", "schedule", schedule.Name)
+			reconcileErrors = append(reconcileErrors, err)
+		}
+	}
+	return reconcileErrors
+}
+
+// reconcileBackfill creates Jobs for the next slots of a schedule's backfill request,
+// keeping at most backfillParallelism of them running. Slots after the current time are
+// left to the schedule itself.
+func (r *SchedulerReconciler) reconcileBackfill(ctx context.Context, scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule, filter calendarFilter) error {
+	request := schedule.Backfill
+	if request == nil {
+		if status := findScheduleStatus(&scheduler.Status, schedule.Name); status != nil {
+			status.Backfill = nil
+		}
+		return nil
+	}
+
+	status := scheduleStatus(&scheduler.Status, schedule.Name)
+	if status.Backfill == nil || !status.Backfill.Start.Equal(&request.Start) || !status.Backfill.End.Equal(&request.End) {
+		status.Backfill = &schedulingapiv1.BackfillStatus{Start: request.Start, End: request.End}
+	}
+	progress := status.Backfill
+	if progress.Completed {
+		return nil
+	}
+	if schedule.CronExpression == "" {
+		return fmt.Errorf("schedule %s: backfill requires a cron expression", schedule.Name)
+	}
+	if filter.err != nil {
+		return filter.err
+	}
+
+	expr, err := r.effectiveExpression(scheduler, schedule)
+	if err != nil {
+		return err
+	}
+	sched, err := cronexpr.Parse(expr, scheduleKey(scheduler, schedule))
+	if err != nil {
+		return fmt.Errorf("invalid cron expression for schedule %s: %w", schedule.Name, err)
+	}
+
+	running, err := r.runningBackfillJobs(ctx, scheduler, schedule)
+	if err != nil {
+		return err
+	}
+
+	// Start is inclusive, so the walk starts just before it
+	cursor := request.Start.Add(-time.Nanosecond)
+	if progress.LastScheduledTime != nil {
+		cursor = progress.LastScheduledTime.Time
+	}
+	end := request.End.Time
+	if now := r.now(); now.Before(end) {
+		end = now
+	}
+
+	for running < backfillParallelism {
+		slot := sched.Next(cursor)
+		if slot.IsZero() || slot.After(end) {
+			progress.Completed = true
+			return nil
+		}
+		cursor = slot
+		if !filter.allows(slot) {
+			continue
+		}
+
+		if waiting, err := r.waitForLock(ctx, scheduler, schedule); err != nil || waiting {
+			return err
+		}
+
+		outputs, err := r.runOutputs(ctx, scheduler, slot)
+		if err != nil {
+			return err
+		}
+		job, err := cronjobbuilder.BuildBackfillJob(scheduler, schedule, slot, outputs)
+		if err != nil {
+			return err
+		}
+		if err := ctrl.SetControllerReference(scheduler, job, r.Scheme); err != nil {
+			return fmt.Errorf("failed to set owner reference for Job %s: %w", job.Name, err)
+		}
+		if err := r.queueForQuota(ctx, job); err != nil {
+			return err
+		}
+		// A Job with the same name means the slot already ran, on schedule or in an
+		// earlier attempt at this backfill
+		log.FromContext(ctx).Info("Creating backfill Job", "name", job.Name, "scheduledTime", slot)
+		if err := r.createJob(ctx, job); err == nil {
+			progress.Runs++
+			running++
+		} else if !apierrors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create Job %s: %w", job.Name, err)
+		}
+		progress.LastScheduledTime = &metav1.Time{Time: slot}
+	}
+	return nil
+}
+
+// runningBackfillJobs counts the unfinished backfill Jobs of a schedule.
+func (r *SchedulerReconciler) runningBackfillJobs(ctx context.Context, scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule) (int, error) {
+	labels := cronjobbuilder.Labels(scheduler, schedule)
+	labels[schedulingapiv1.TriggerLabel] = schedulingapiv1.TriggerBackfill
+
+	var jobs batchv1.JobList
+	if err := r.List(ctx, &jobs, client.InNamespace(scheduler.Namespace), client.MatchingLabels(labels)); err != nil {
+		return 0, fmt.Errorf("failed to list backfill Jobs of schedule %s: %w", schedule.Name, err)
+	}
+
+	running := 0
+	for i := range jobs.Items {
+		if finished, _ := runFinished(&jobs.Items[i]); !finished {
+			running++
+		}
+	}
+	return running, nil
+}
@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cronexpr"
+)
+
+// blackoutState records which schedules of a Scheduler are inside a blackout window.
+type blackoutState struct {
+	// active maps the name of a blacked out schedule to the window it is in.
+	active map[string]activeWindow
+	// next is the earliest time a window starts or ends, or zero if none will.
+	next time.Time
+}
+
+// activeWindow is a blackout window a schedule is currently in.
+type activeWindow struct {
+	name string
+	end  time.Time
+}
+
+// isActive reports whether a schedule is inside a blackout window.
+func (b blackoutState) isActive(schedule string) bool {
+	_, ok := b.active[schedule]
+	return ok
+}
+
+// evaluateBlackouts resolves the blackout windows of every schedule at the given time.
+// Invalid windows are reported and otherwise ignored.
+func evaluateBlackouts(scheduler *schedulingapiv1.Scheduler, now time.Time) (blackoutState, []error) {
+	b := blackoutState{active: map[string]activeWindow{}}
+	var errs []error
+
+	evaluate := func(windows []schedulingapiv1.BlackoutWindow, key string) []activeWindow {
+		var active []activeWindow
+		for i, window := range windows {
+			name := window.Name
+			if name == "" {
+				name = fmt.Sprintf("%s[%d]", key, i)
+			}
+			end, change, err := windowState(window, name, now)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid blackout window %s: %w", name, err))
+				continue
+			}
+			if !end.IsZero() {
+				active = append(active, activeWindow{name: name, end: end})
+			}
+			if !change.IsZero() && (b.next.IsZero() || change.Before(b.next)) {
+				b.next = change
+			}
+		}
+		return active
+	}
+
+	shared := evaluate(scheduler.Spec.BlackoutWindows, scheduler.Namespace+"/"+scheduler.Name)
+	for _, schedule := range scheduler.Spec.Schedules {
+		active := append(evaluate(schedule.BlackoutWindows, scheduleKey(scheduler, schedule)), shared...)
+		// Overlapping windows keep the schedule suspended until the last one ends
+		for _, window := range active {
+			if current, ok := b.active[schedule.Name]; !ok || window.end.After(current.end) {
+				b.active[schedule.Name] = window
+			}
+		}
+	}
+	return b, errs
+}
+
+// blackedOutAt reports whether a schedule was inside a blackout window at t.
+func blackedOutAt(scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule, t time.Time) bool {
+	b, _ := evaluateBlackouts(scheduler, t)
+	return b.isActive(schedule.Name)
+}
+
+// windowState returns the end of a blackout window if now falls inside it, and the next
+// time the window starts or ends.
+func windowState(window schedulingapiv1.BlackoutWindow, key string, now time.Time) (end, change time.Time, err error) {
+	if window.Start != nil || window.End != nil {
+		if window.Schedule != "" {
+			return time.Time{}, time.Time{}, fmt.Errorf("schedule cannot be combined with start and end")
+		}
+		if window.Start == nil || window.End == nil || !window.End.After(window.Start.Time) {
+			return time.Time{}, time.Time{}, fmt.Errorf("a one-off window needs a start before its end")
+		}
+		switch {
+		case now.Before(window.Start.Time):
+			return time.Time{}, window.Start.Time, nil
+		case now.Before(window.End.Time):
+			return window.End.Time, window.End.Time, nil
+		}
+		return time.Time{}, time.Time{}, nil
+	}
+
+	if window.Schedule == "" || window.Duration == nil || window.Duration.Duration <= 0 {
+		return time.Time{}, time.Time{}, fmt.Errorf("a recurring window needs a schedule and a positive duration")
+	}
+	loc, err := time.LoadLocation(window.TimeZone)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("unknown time zone %q: %w", window.TimeZone, err)
+	}
+	sched, err := cronexpr.Parse(window.Schedule, key)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	// Cron fields are matched in the window's time zone, so daylight saving changes move
+	// the window with the wall clock.
+	local := now.In(loc)
+	if start := mostRecentSlot(sched, local.Add(-window.Duration.Duration), local); !start.IsZero() {
+		end = start.Add(window.Duration.Duration).UTC()
+		return end, end, nil
+	}
+	if next := sched.Next(local); !next.IsZero() {
+		change = next.UTC()
+	}
+	return time.Time{}, change, nil
+}
+
+// describe summarizes the active windows for the Blackout condition.
+func (b blackoutState) describe() string {
+	names := make([]string, 0, len(b.active))
+	for name := range b.active {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		window := b.active[name]
+		parts = append(parts, fmt.Sprintf("%s (window %s until %s)", name, window.name, window.end.Format(time.RFC3339)))
+	}
+	return "Schedules in a blackout window: " + strings.Join(parts, ", ")
+}
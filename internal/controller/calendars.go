@@ -0,0 +1,158 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/calendar"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cronexpr"
+)
+
+// maxSkippedDays bounds the search for the next run on an allowed day.
+const maxSkippedDays = 5 * 366
+
+// calendarFilter restricts a schedule to the days allowed by its Calendars.
+type calendarFilter struct {
+	onlyOn []*calendar.Calendar
+	skipOn []*calendar.Calendar
+	// err is set when a Calendar could not be loaded, in which case the schedule is
+	// held rather than run on days it may not be allowed to.
+	err error
+}
+
+// isEmpty reports whether the schedule references no Calendar.
+func (f calendarFilter) isEmpty() bool {
+	return len(f.onlyOn) == 0 && len(f.skipOn) == 0
+}
+
+// allows reports whether the schedule may run at t.
+func (f calendarFilter) allows(t time.Time) bool {
+	for _, c := range f.skipOn {
+		if c.Contains(t) {
+			return false
+		}
+	}
+	if len(f.onlyOn) == 0 {
+		return true
+	}
+	for _, c := range f.onlyOn {
+		if c.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// nextChange returns the earliest time after t at which allows may change, which is the
+// start of the next day in any of the Calendars' time zones.
+func (f calendarFilter) nextChange(t time.Time) time.Time {
+	var next time.Time
+	for _, c := range slices.Concat(f.onlyOn, f.skipOn) {
+		if day := c.NextDay(t); next.IsZero() || day.Before(next) {
+			next = day
+		}
+	}
+	return next
+}
+
+// nextAllowed returns the first activation of sched after t that falls on an allowed
+// day, skipping excluded days as a whole, or the zero time if there is none.
+func (f calendarFilter) nextAllowed(sched cronexpr.Schedule, t time.Time) time.Time {
+	for range maxSkippedDays {
+		next := sched.Next(t)
+		if next.IsZero() || f.allows(next) {
+			return next
+		}
+		t = f.nextChange(next).Add(-time.Second)
+	}
+	return time.Time{}
+}
+
+// resolveCalendars loads the Calendars referenced by every schedule of a Scheduler.
+func (r *SchedulerReconciler) resolveCalendars(ctx context.Context, scheduler *schedulingapiv1.Scheduler) map[string]calendarFilter {
+	loaded := map[string]*calendar.Calendar{}
+	load := func(names []string) ([]*calendar.Calendar, error) {
+		calendars := make([]*calendar.Calendar, 0, len(names))
+		for _, name := range names {
+			c, ok := loaded[name]
+			if !ok {
+				var err error
+				if c, err = r.loadCalendar(ctx, name); err != nil {
+					return nil, err
+				}
+				loaded[name] = c
+			}
+			calendars = append(calendars, c)
+		}
+		return calendars, nil
+	}
+
+	filters := map[string]calendarFilter{}
+	for _, schedule := range scheduler.Spec.Schedules {
+		var filter calendarFilter
+		if filter.onlyOn, filter.err = load(schedule.OnlyOn); filter.err == nil {
+			filter.skipOn, filter.err = load(schedule.SkipOn)
+		}
+		if filter.err != nil {
+			filter.err = fmt.Errorf("schedule %s: %w", schedule.Name, filter.err)
+		}
+		filters[schedule.Name] = filter
+	}
+	return filters
+}
+
+// loadCalendar reads a Calendar and the iCalendar data it imports.
+func (r *SchedulerReconciler) loadCalendar(ctx context.Context, name string) (*calendar.Calendar, error) {
+	var cal schedulingapiv1.Calendar
+	if err := r.Get(ctx, types.NamespacedName{Name: name}, &cal); err != nil {
+		return nil, fmt.Errorf("failed to get Calendar %s: %w", name, err)
+	}
+
+	var ics string
+	if source := cal.Spec.ICalendar; source != nil {
+		// ConfigMaps are read directly, rather than cached for the whole cluster
+		var configMap corev1.ConfigMap
+		if err := r.reader().Get(ctx, types.NamespacedName{Namespace: source.Namespace, Name: source.Name}, &configMap); err != nil {
+			return nil, fmt.Errorf("failed to get iCalendar ConfigMap of Calendar %s: %w", name, err)
+		}
+		var ok bool
+		if ics, ok = configMap.Data[source.Key]; !ok {
+			return nil, fmt.Errorf("ConfigMap %s/%s of Calendar %s has no key %s", source.Namespace, source.Name, name, source.Key)
+		}
+	}
+
+	c, err := calendar.New(cal.Spec, ics)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Calendar %s: %w", name, err)
+	}
+	return c, nil
+}
+
+// calendarToSchedulers maps a Calendar to the Schedulers with a schedule referencing it.
+func (r *SchedulerReconciler) calendarToSchedulers(ctx context.Context, obj client.Object) []reconcile.Request {
+	var schedulers schedulingapiv1.SchedulerList
+	if err := r.List(ctx, &schedulers); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list Schedulers for Calendar", "calendar", obj.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, scheduler := range schedulers.Items {
+		for _, schedule := range scheduler.Spec.Schedules {
+			if slices.Contains(schedule.OnlyOn, obj.GetName()) || slices.Contains(schedule.SkipOn, obj.GetName()) {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&scheduler)})
+				break
+			}
+		}
+	}
+	return requests
+}
@@ -0,0 +1,210 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cronjobbuilder"
+)
+
+// reconcileCircuits counts the runs of schedules with a FailureThreshold that finished
+// since the last reconcile, opens the circuit of those that failed too many times in a
+// row and closes the circuit of those that changed or cooled down. It returns when the
+// next cooldown ends.
+func (r *SchedulerReconciler) reconcileCircuits(ctx context.Context, scheduler *schedulingapiv1.Scheduler) (time.Duration, error) {
+	var jobs batchv1.JobList
+	if err := r.List(ctx, &jobs, client.InNamespace(scheduler.Namespace), client.MatchingLabels{"scheduler": scheduler.Name}); err != nil {
+		return 0, fmt.Errorf("failed to list Jobs for circuits: %w", err)
+	}
+	podsByJob, err := r.listPodsByJob(ctx, scheduler)
+	if err != nil {
+		return 0, err
+	}
+	attempts := indexRunAttempts(jobs.Items)
+	steps := indexStepJobs(jobs.Items)
+
+	now := r.now()
+	var requeueAfter time.Duration
+	for _, schedule := range scheduler.Spec.Schedules {
+		if schedule.FailureThreshold == nil {
+			if status := findScheduleStatus(&scheduler.Status, schedule.Name); status != nil {
+				status.Circuit = nil
+			}
+			continue
+		}
+		status := scheduleStatus(&scheduler.Status, schedule.Name)
+		if status.Circuit == nil {
+			status.Circuit = &schedulingapiv1.CircuitStatus{}
+		}
+		circuit := status.Circuit
+		countRuns(circuit, schedule, attempts[schedule.Name], podsByJob, steps)
+
+		hash := scheduleHash(schedule)
+		if circuit.OpenedAt == nil {
+			if circuit.ConsecutiveFailures >= *schedule.FailureThreshold {
+				circuit.OpenedAt = &metav1.Time{Time: now}
+				circuit.SpecHash = hash
+				log.FromContext(ctx).Info("Opening circuit", "schedule", schedule.Name, "failures", circuit.ConsecutiveFailures)
+				r.event(scheduler, corev1.EventTypeWarning, "CircuitOpen", "Suspended schedule %s after %d consecutive failures: %s",
+					schedule.Name, circuit.ConsecutiveFailures, circuit.LastFailure)
+			}
+			continue
+		}
+
+		var reason string
+		switch {
+		case circuit.SpecHash != hash:
+			reason = "the schedule changed"
+		case schedule.CircuitCooldown != nil:
+			closeAt := circuit.OpenedAt.Add(schedule.CircuitCooldown.Duration)
+			if now.Before(closeAt) {
+				requeueAfter = shortestRequeue(requeueAfter, closeAt.Sub(now))
+				continue
+			}
+			reason = "the cooldown has passed"
+		default:
+			continue
+		}
+		circuit.OpenedAt = nil
+		circuit.SpecHash = ""
+		circuit.ConsecutiveFailures = 0
+		log.FromContext(ctx).Info("Closing circuit", "schedule", schedule.Name, "reason", reason)
+		r.event(scheduler, corev1.EventTypeNormal, "CircuitClosed", "Resumed schedule %s since %s", schedule.Name, reason)
+	}
+	return requeueAfter, nil
+}
+
+// countRuns updates the consecutive failures of a circuit with the runs that finished
+// after the last one counted, in the order they finished.
+func countRuns(circuit *schedulingapiv1.CircuitStatus, schedule schedulingapiv1.Schedule, jobs map[string]*batchv1.Job, podsByJob map[string][]corev1.Pod, steps stepIndex) {
+	for _, f := range finishedSince(schedule, jobs, podsByJob, steps, circuit.LastObservedTime, circuit.LastObservedJob) {
+		if f.run.Phase == schedulingapiv1.RunPhaseFailed {
+			circuit.ConsecutiveFailures++
+			circuit.LastFailure = failureReason(f.job, podsByJob[f.job.Name])
+		} else {
+			circuit.ConsecutiveFailures = 0
+		}
+		circuit.LastObservedJob = f.job.Name
+		circuit.LastObservedTime = f.run.CompletionTime
+	}
+}
+
+// finishedRun is a run that succeeded or failed, with the Job of its latest attempt.
+type finishedRun struct {
+	job *batchv1.Job
+	run schedulingapiv1.RunStatus
+}
+
+// finishedSince returns the runs of a schedule that finished after the run of lastJob,
+// which finished at lastTime, in the order they finished. Failed runs that are still to
+// be retried are left out until their last attempt.
+func finishedSince(schedule schedulingapiv1.Schedule, jobs map[string]*batchv1.Job, podsByJob map[string][]corev1.Pod, steps stepIndex, lastTime *metav1.Time, lastJob string) []finishedRun {
+	var finished []finishedRun
+	for _, job := range jobs {
+		run := runStatus(job, podsByJob[job.Name])
+		applySteps(&run, job, podsByJob, steps)
+		if run.CompletionTime == nil || (run.Phase != schedulingapiv1.RunPhaseSucceeded && run.Phase != schedulingapiv1.RunPhaseFailed) {
+			continue
+		}
+		if _, retried := nextRetry(schedule, job, podsByJob, steps); retried {
+			continue
+		}
+		if lastTime != nil {
+			if run.CompletionTime.Before(lastTime) || (run.CompletionTime.Equal(lastTime) && job.Name <= lastJob) {
+				continue
+			}
+		}
+		finished = append(finished, finishedRun{job, run})
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		if !finished[i].run.CompletionTime.Equal(finished[j].run.CompletionTime) {
+			return finished[i].run.CompletionTime.Before(finished[j].run.CompletionTime)
+		}
+		return finished[i].job.Name < finished[j].job.Name
+	})
+	return finished
+}
+
+// failureReason describes why the run of a Job failed.
+func failureReason(job *batchv1.Job, pods []corev1.Pod) string {
+	reason := fmt.Sprintf("Job %s failed", job.Name)
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue {
+			reason = fmt.Sprintf("Job %s failed: %s: %s", job.Name, c.Reason, c.Message)
+		}
+	}
+	if _, ok := job.Annotations[schedulingapiv1.StepsAnnotation]; ok {
+		reason = fmt.Sprintf("pipeline of Job %s failed", job.Name)
+	}
+	if codes := exitCodes(pods); len(codes) > 0 {
+		reason += fmt.Sprintf(" (exit code %d)", codes[len(codes)-1])
+	}
+	return reason
+}
+
+// circuitOpen reports whether the circuit of a schedule is open.
+func circuitOpen(scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule) bool {
+	if schedule.FailureThreshold == nil {
+		return false
+	}
+	status := findScheduleStatus(&scheduler.Status, schedule.Name)
+	return status != nil && status.Circuit != nil && status.Circuit.OpenedAt != nil
+}
+
+// suspended reports whether the runs of a schedule are held back, because it is
+// suspended or its circuit is open.
+func suspended(scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule) bool {
+	return cronjobbuilder.IsSuspended(scheduler, schedule) || circuitOpen(scheduler, schedule)
+}
+
+// circuitCondition returns the CircuitOpen condition of a Scheduler.
+func circuitCondition(scheduler *schedulingapiv1.Scheduler) metav1.Condition {
+	var open []string
+	for _, schedule := range scheduler.Spec.Schedules {
+		if circuitOpen(scheduler, schedule) {
+			circuit := findScheduleStatus(&scheduler.Status, schedule.Name).Circuit
+			open = append(open, fmt.Sprintf("%s (%d consecutive failures, last: %s)", schedule.Name, circuit.ConsecutiveFailures, circuit.LastFailure))
+		}
+	}
+	if len(open) == 0 {
+		return metav1.Condition{
+			Type:    "CircuitOpen",
+			Status:  metav1.ConditionFalse,
+			Reason:  "CircuitsClosed",
+			Message: "No schedule is suspended for failing repeatedly.",
+		}
+	}
+	return metav1.Condition{
+		Type:    "CircuitOpen",
+		Status:  metav1.ConditionTrue,
+		Reason:  "FailureThresholdReached",
+		Message: "Suspended for failing repeatedly: " + strings.Join(open, "; "),
+	}
+}
+
+// scheduleHash returns a hash of the spec of a schedule.
+func scheduleHash(schedule schedulingapiv1.Schedule) string {
+	data, _ := json.Marshal(schedule)
+	h := fnv.New64a()
+	_, _ = h.Write(data)
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+// event records an event on a Scheduler, when the reconciler has a Recorder.
+func (r *SchedulerReconciler) event(scheduler *schedulingapiv1.Scheduler, eventType, reason, messageFmt string, args ...any) {
+	if r.Recorder != nil {
+		r.Recorder.Eventf(scheduler, eventType, reason, messageFmt, args...)
+	}
+}
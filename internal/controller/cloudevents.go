@@ -0,0 +1,230 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cloudevents"
+)
+
+// Types of the CloudEvents published about schedules and their runs.
+const (
+	ScheduleCreatedEvent = "lr.labs.schedule.created"
+	ScheduleUpdatedEvent = "lr.labs.schedule.updated"
+	ScheduleDeletedEvent = "lr.labs.schedule.deleted"
+	RunStartedEvent      = "lr.labs.run.started"
+	RunSucceededEvent    = "lr.labs.run.succeeded"
+	RunFailedEvent       = "lr.labs.run.failed"
+)
+
+// CloudEventEmitter queues CloudEvents to be published, without blocking.
+type CloudEventEmitter interface {
+	Emit(event cloudevents.Event) bool
+}
+
+// scheduleEventData is the data of the schedule events. It describes when a schedule
+// runs, but not what, since its params and env may hold values that must not leave the
+// cluster.
+type scheduleEventData struct {
+	Namespace    string    `json:"namespace"`
+	Scheduler    string    `json:"scheduler"`
+	SchedulerUID types.UID `json:"schedulerUID,omitempty"`
+	Schedule     string    `json:"schedule"`
+	// CronExpression, RunAt, DependsOn and Mode are those of the schedule, except in
+	// deleted events.
+	CronExpression string                       `json:"cronExpression,omitempty"`
+	RunAt          *metav1.Time                 `json:"runAt,omitempty"`
+	DependsOn      []string                     `json:"dependsOn,omitempty"`
+	Mode           schedulingapiv1.ScheduleMode `json:"mode,omitempty"`
+}
+
+// runEventData is the data of the run events.
+type runEventData struct {
+	Namespace      string                   `json:"namespace"`
+	Scheduler      string                   `json:"scheduler"`
+	SchedulerUID   types.UID                `json:"schedulerUID,omitempty"`
+	Schedule       string                   `json:"schedule"`
+	Run            string                   `json:"run"`
+	ScheduledTime  *metav1.Time             `json:"scheduledTime,omitempty"`
+	Trigger        string                   `json:"trigger,omitempty"`
+	Attempt        int32                    `json:"attempt,omitempty"`
+	Phase          schedulingapiv1.RunPhase `json:"phase"`
+	StartTime      *metav1.Time             `json:"startTime,omitempty"`
+	CompletionTime *metav1.Time             `json:"completionTime,omitempty"`
+	ExitCode       *int32                   `json:"exitCode,omitempty"`
+	Message        string                   `json:"message,omitempty"`
+	Outputs        map[string]string        `json:"outputs,omitempty"`
+}
+
+// eventSource is the CloudEvents source of the events about a Scheduler.
+func eventSource(namespace, name string) string {
+	return fmt.Sprintf("/apis/%s/namespaces/%s/schedulers/%s", schedulingapiv1.GroupVersion.String(), namespace, name)
+}
+
+// newEvent returns a CloudEvent, or false when it cannot be encoded.
+func newEvent(ctx context.Context, eventType, source, subject string, t time.Time, data any) (cloudevents.Event, bool) {
+	event, err := cloudevents.NewEvent(eventType, source, subject, t, data)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Not emitting event")
+		return cloudevents.Event{}, false
+	}
+	return event, true
+}
+
+// emit queues a CloudEvent, when the reconciler has an emitter.
+func (r *SchedulerReconciler) emit(ctx context.Context, eventType, source, subject string, t time.Time, data any) {
+	if r.CloudEvents == nil {
+		return
+	}
+	if event, ok := newEvent(ctx, eventType, source, subject, t, data); ok {
+		r.CloudEvents.Emit(event)
+	}
+}
+
+// publish queues CloudEvents, when the reconciler has an emitter.
+func (r *SchedulerReconciler) publish(events []cloudevents.Event) {
+	if r.CloudEvents == nil {
+		return
+	}
+	for _, event := range events {
+		r.CloudEvents.Emit(event)
+	}
+}
+
+// scheduleEvents records the hashes of the schedule specs of a Scheduler in its status
+// and returns the events about the schedules created, updated or deleted since they
+// were last recorded. The events are published once the status is saved, so that a
+// failed update does not publish them again on the next reconcile. The schedules of a
+// Scheduler reconciled before the emitter was enabled, which already have a status, are
+// recorded without events.
+func (r *SchedulerReconciler) scheduleEvents(ctx context.Context, scheduler *schedulingapiv1.Scheduler) []cloudevents.Event {
+	key := types.NamespacedName{Namespace: scheduler.Namespace, Name: scheduler.Name}
+	if r.CloudEvents == nil {
+		scheduler.Status.ScheduleHashes = nil
+		return nil
+	}
+
+	known := scheduler.Status.ScheduleHashes
+	report := known != nil || len(scheduler.Status.Schedules) == 0
+	hashes := make(map[string]string, len(scheduler.Spec.Schedules))
+	source := eventSource(scheduler.Namespace, scheduler.Name)
+	now := r.now()
+	data := func(name string) scheduleEventData {
+		return scheduleEventData{Namespace: scheduler.Namespace, Scheduler: scheduler.Name, SchedulerUID: scheduler.UID, Schedule: name}
+	}
+	var events []cloudevents.Event
+	add := func(eventType, subject string, data scheduleEventData) {
+		if event, ok := newEvent(ctx, eventType, source, subject, now, data); ok {
+			events = append(events, event)
+		}
+	}
+	for _, schedule := range scheduler.Spec.Schedules {
+		hash := scheduleHash(schedule)
+		hashes[schedule.Name] = hash
+		if !report {
+			continue
+		}
+		d := data(schedule.Name)
+		d.CronExpression = schedule.CronExpression
+		d.RunAt = schedule.RunAt
+		d.DependsOn = schedule.DependsOn
+		d.Mode = schedule.Mode
+		if previous, ok := known[schedule.Name]; !ok {
+			add(ScheduleCreatedEvent, schedule.Name, d)
+		} else if previous != hash {
+			add(ScheduleUpdatedEvent, schedule.Name, d)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(known)) {
+		if _, ok := hashes[name]; !ok {
+			add(ScheduleDeletedEvent, name, data(name))
+		}
+	}
+	scheduler.Status.ScheduleHashes = hashes
+
+	r.knownSchedules.Store(key, schedulerSchedules{uid: scheduler.UID, names: slices.Sorted(maps.Keys(hashes))})
+	return events
+}
+
+// schedulerSchedules are the schedules of a Scheduler when it was last reconciled.
+type schedulerSchedules struct {
+	uid   types.UID
+	names []string
+}
+
+// emitSchedulerDeleted publishes the deletion of the schedules of a Scheduler that no
+// longer exists. Only the Schedulers reconciled since the controller started are known.
+func (r *SchedulerReconciler) emitSchedulerDeleted(ctx context.Context, key types.NamespacedName) {
+	value, ok := r.knownSchedules.LoadAndDelete(key)
+	if !ok || r.CloudEvents == nil {
+		return
+	}
+	known := value.(schedulerSchedules)
+	source := eventSource(key.Namespace, key.Name)
+	for _, name := range known.names {
+		r.emit(ctx, ScheduleDeletedEvent, source, name, r.now(), scheduleEventData{
+			Namespace: key.Namespace, Scheduler: key.Name, SchedulerUID: known.uid, Schedule: name,
+		})
+	}
+}
+
+// emitRunEvents publishes the changes of a run between two versions of its ScheduleRun,
+// the previous one being nil for a new run. A run is started once its container has
+// started, or it finished without, so that every run that ends was reported started.
+func (r *SchedulerReconciler) emitRunEvents(ctx context.Context, scheduler *schedulingapiv1.Scheduler, previous, current *schedulingapiv1.ScheduleRun) {
+	if r.CloudEvents == nil {
+		return
+	}
+	var previousPhase schedulingapiv1.RunPhase
+	if previous != nil {
+		previousPhase = previous.Status.Phase
+	}
+	phase := current.Status.Phase
+	started := func(phase schedulingapiv1.RunPhase) bool {
+		return phase == schedulingapiv1.RunPhaseRunning || phase == schedulingapiv1.RunPhaseSucceeded || phase == schedulingapiv1.RunPhaseFailed
+	}
+
+	data := runEventData{
+		Namespace:      scheduler.Namespace,
+		Scheduler:      scheduler.Name,
+		SchedulerUID:   scheduler.UID,
+		Schedule:       current.Spec.ScheduleName,
+		Run:            current.Spec.JobName,
+		ScheduledTime:  current.Spec.ScheduledTime,
+		Trigger:        current.Spec.Trigger,
+		Attempt:        current.Spec.Attempt,
+		Phase:          phase,
+		StartTime:      current.Status.StartTime,
+		CompletionTime: current.Status.CompletionTime,
+		ExitCode:       current.Status.ExitCode,
+		Message:        current.Status.TerminationMessage,
+		Outputs:        current.Status.Outputs,
+	}
+	source := eventSource(scheduler.Namespace, scheduler.Name)
+	at := func(t *metav1.Time) time.Time {
+		if t != nil {
+			return t.Time
+		}
+		return r.now()
+	}
+
+	if started(phase) && !started(previousPhase) {
+		r.emit(ctx, RunStartedEvent, source, current.Spec.JobName, at(current.Status.StartTime), data)
+	}
+	if phase != previousPhase {
+		switch phase {
+		case schedulingapiv1.RunPhaseSucceeded:
+			r.emit(ctx, RunSucceededEvent, source, current.Spec.JobName, at(current.Status.CompletionTime), data)
+		case schedulingapiv1.RunPhaseFailed:
+			r.emit(ctx, RunFailedEvent, source, current.Spec.JobName, at(current.Status.CompletionTime), data)
+		}
+	}
+}
@@ -0,0 +1,404 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cronjobbuilder"
+)
+
+// slotJobs indexes the Jobs of a Scheduler by schedule and logical time.
+type slotJobs map[string]map[time.Time]*batchv1.Job
+
+// reconcileDependencies starts the runs of dependent schedules whose dependencies
+// succeeded for a slot. Each upstream Job records the dependents it released, so a slot
+// is never started twice even once the dependent's Job has been pruned.
+func (r *SchedulerReconciler) reconcileDependencies(ctx context.Context, scheduler *schedulingapiv1.Scheduler, blackouts blackoutState) []error {
+	log := log.FromContext(ctx)
+	var dependents []schedulingapiv1.Schedule
+	for _, schedule := range scheduler.Spec.Schedules {
+		if len(schedule.DependsOn) > 0 {
+			dependents = append(dependents, schedule)
+		}
+	}
+	if len(dependents) == 0 {
+		return nil
+	}
+	if err := validateDependencies(scheduler.Spec.Schedules); err != nil {
+		log.Error(err, "Invalid schedule dependencies")
+		return []error{err}
+	}
+
+	var jobs batchv1.JobList
+	if err := r.List(ctx, &jobs, client.InNamespace(scheduler.Namespace), client.MatchingLabels{"scheduler": scheduler.Name}); err != nil {
+		return []error{fmt.Errorf("failed to list Jobs for dependencies: %w", err)}
+	}
+	slots := indexSlotJobs(jobs.Items)
+
+	var reconcileErrors []error
+	for _, schedule := range dependents {
+		if err := r.reconcileDependent(ctx, scheduler, schedule, blackouts.isActive(schedule.Name), slots); err != nil {
+			log.Error(err, "Failed to reconcile dependent schedule", "schedule", schedule.Name)
+			reconcileErrors = append(reconcileErrors, err)
+			continue
+		}
+		if err := r.pruneJobHistory(ctx, scheduler, schedule); err != nil {
+			log.Error(err, "Failed to prune Job history", "schedule", schedule.Name)
+			reconcileErrors = append(reconcileErrors, err)
+		}
+	}
+	return reconcileErrors
+}
+
+// reconcileDependent starts a run of a dependent schedule for every slot all of its
+// dependencies succeeded for and that was not released to it yet. Slots released while
+// the schedule is suspended, blacked out, outside StartAt and EndAt or Completed are
+// skipped, as are those that succeeded before the schedule was first seen. Slots whose
+// preconditions are not met are released and recorded as Skipped runs.
+func (r *SchedulerReconciler) reconcileDependent(ctx context.Context, scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule, blackedOut bool, slots slotJobs) error {
+	firstSeen := findScheduleStatus(&scheduler.Status, schedule.Name) == nil
+	status := scheduleStatus(&scheduler.Status, schedule.Name)
+	status.EffectiveSchedule = ""
+	status.NextFireTime = nil
+
+	type release struct {
+		slot     time.Time
+		upstream []*batchv1.Job
+	}
+	var releases []release
+	for slot := range slots[schedule.DependsOn[0]] {
+		var upstream []*batchv1.Job
+		for _, name := range schedule.DependsOn {
+			job := slots[name][slot]
+			if job == nil {
+				break
+			}
+			if finished, condition := runFinished(job); !finished || condition != batchv1.JobComplete || releasedTo(job, schedule.Name) {
+				break
+			}
+			upstream = append(upstream, job)
+		}
+		if len(upstream) == len(schedule.DependsOn) {
+			releases = append(releases, release{slot, upstream})
+		}
+	}
+	sort.Slice(releases, func(i, j int) bool { return releases[i].slot.Before(releases[j].slot) })
+
+	for _, release := range releases {
+		now := r.now()
+		skip := firstSeen || blackedOut || suspended(scheduler, schedule) || notStarted(schedule, now)
+		if !skip && !updateCompletion(schedule, status, now) {
+			// The slot is left unreleased until the lock group of the schedule is free
+			if waiting, err := r.waitForLock(ctx, scheduler, schedule); err != nil || waiting {
+				return err
+			}
+			reason, err := r.checkPreconditions(ctx, scheduler, schedule)
+			if err != nil {
+				return err
+			}
+			if reason != "" {
+				skipRun(ctx, status, scheduler, schedule, release.slot, reason)
+			} else {
+				created, err := r.fireDependentJob(ctx, scheduler, schedule, release.slot)
+				if err != nil {
+					return err
+				}
+				if created {
+					status.RunCount++
+				}
+			}
+			status.LastFireTime = &metav1.Time{Time: release.slot}
+			updateCompletion(schedule, status, now)
+		}
+		for _, job := range release.upstream {
+			if err := r.releaseJob(ctx, job, schedule.Name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// fireDependentJob creates the Job of a dependent schedule for a slot and reports whether
+// it was created.
+func (r *SchedulerReconciler) fireDependentJob(ctx context.Context, scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule, slot time.Time) (bool, error) {
+	outputs, err := r.runOutputs(ctx, scheduler, slot)
+	if err != nil {
+		return false, err
+	}
+	job, err := cronjobbuilder.BuildDependentJob(scheduler, schedule, slot, outputs)
+	if err != nil {
+		return false, err
+	}
+	if err := ctrl.SetControllerReference(scheduler, job, r.Scheme); err != nil {
+		return false, fmt.Errorf("failed to set owner reference for Job %s: %w", job.Name, err)
+	}
+
+	if err := r.queueForQuota(ctx, job); err != nil {
+		return false, err
+	}
+
+	log.FromContext(ctx).Info("Creating dependent Job", "name", job.Name, "scheduledTime", slot, "dependsOn", schedule.DependsOn)
+	if err := r.createJob(ctx, job); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to create Job %s: %w", job.Name, err)
+	}
+	return true, nil
+}
+
+// releaseJob records on an upstream Job that its slot was released to a dependent schedule.
+func (r *SchedulerReconciler) releaseJob(ctx context.Context, job *batchv1.Job, dependent string) error {
+	patch := client.MergeFrom(job.DeepCopy())
+	if job.Annotations == nil {
+		job.Annotations = map[string]string{}
+	}
+	released := job.Annotations[schedulingapiv1.ReleasedAnnotation]
+	if released != "" {
+		released += ","
+	}
+	job.Annotations[schedulingapiv1.ReleasedAnnotation] = released + dependent
+	if err := r.Patch(ctx, job, patch); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("failed to record release of Job %s: %w", job.Name, err)
+	}
+	return nil
+}
+
+// releasedTo reports whether a Job's slot was already released to a dependent schedule.
+func releasedTo(job *batchv1.Job, dependent string) bool {
+	return slices.Contains(strings.Split(job.Annotations[schedulingapiv1.ReleasedAnnotation], ","), dependent)
+}
+
+// indexSlotJobs indexes the Jobs of runs by schedule and logical time. When several Jobs
+// share a slot, a successful one is preferred, then the latest attempt of a retried run.
+func indexSlotJobs(jobs []batchv1.Job) slotJobs {
+	slots := slotJobs{}
+	for i := range jobs {
+		job := &jobs[i]
+		scheduledTime := jobScheduledTime(job)
+		if scheduledTime == nil || isStepJob(job) {
+			continue
+		}
+		name := job.Labels["schedule"]
+		if slots[name] == nil {
+			slots[name] = map[time.Time]*batchv1.Job{}
+		}
+		slot := scheduledTime.UTC()
+		if existing := slots[name][slot]; existing != nil {
+			if _, condition := runFinished(existing); condition == batchv1.JobComplete {
+				continue
+			}
+			if _, condition := runFinished(job); condition != batchv1.JobComplete &&
+				cronjobbuilder.JobAttempt(existing) > cronjobbuilder.JobAttempt(job) {
+				continue
+			}
+		}
+		slots[name][slot] = job
+	}
+	return slots
+}
+
+// validateDependencies checks that schedules only depend on other schedules of the same
+// Scheduler and that their dependencies do not form a cycle.
+func validateDependencies(schedules []schedulingapiv1.Schedule) error {
+	byName := map[string]schedulingapiv1.Schedule{}
+	for _, schedule := range schedules {
+		byName[schedule.Name] = schedule
+	}
+	for _, schedule := range schedules {
+		for _, name := range schedule.DependsOn {
+			if _, ok := byName[name]; !ok {
+				return fmt.Errorf("schedule %s depends on unknown schedule %s", schedule.Name, name)
+			}
+		}
+	}
+
+	// Depth-first search, reporting the first cycle found as a path
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := map[string]int{}
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			cycle := append(slices.Clone(path[slices.Index(path, name):]), name)
+			return fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " -> "))
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, dependency := range byName[name].DependsOn {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+	for _, schedule := range schedules {
+		if err := visit(schedule.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dependencyChains groups the schedules linked by dependencies into chains, each ordered
+// so that a schedule follows its dependencies. Schedules without dependents or
+// dependencies belong to no chain.
+func dependencyChains(schedules []schedulingapiv1.Schedule) [][]string {
+	group := map[string]string{}
+	var find func(name string) string
+	find = func(name string) string {
+		parent, ok := group[name]
+		if !ok || parent == name {
+			group[name] = name
+			return name
+		}
+		root := find(parent)
+		group[name] = root
+		return root
+	}
+	linked := map[string]bool{}
+	for _, schedule := range schedules {
+		for _, name := range schedule.DependsOn {
+			group[find(schedule.Name)] = find(name)
+			linked[schedule.Name], linked[name] = true, true
+		}
+	}
+
+	// Repeatedly take the schedules whose dependencies are all placed, in spec order
+	var order []string
+	placed := map[string]bool{}
+	for progressed := true; progressed; {
+		progressed = false
+		for _, schedule := range schedules {
+			if placed[schedule.Name] || !linked[schedule.Name] {
+				continue
+			}
+			ready := true
+			for _, name := range schedule.DependsOn {
+				ready = ready && placed[name]
+			}
+			if ready {
+				placed[schedule.Name] = true
+				order = append(order, schedule.Name)
+				progressed = true
+			}
+		}
+	}
+
+	var chains [][]string
+	index := map[string]int{}
+	for _, name := range order {
+		root := find(name)
+		i, ok := index[root]
+		if !ok {
+			i = len(chains)
+			index[root] = i
+			chains = append(chains, nil)
+		}
+		chains[i] = append(chains[i], name)
+	}
+	return chains
+}
+
+// recordChains reports the most recent slots of every chain of dependent schedules, from
+// the slots its first schedules ran for.
+func recordChains(scheduler *schedulingapiv1.Scheduler, jobs []batchv1.Job, podsByJob map[string][]corev1.Pod, steps stepIndex) {
+	scheduler.Status.Chains = nil
+	if validateDependencies(scheduler.Spec.Schedules) != nil {
+		return
+	}
+	dependsOn := map[string][]string{}
+	for _, schedule := range scheduler.Spec.Schedules {
+		dependsOn[schedule.Name] = schedule.DependsOn
+	}
+	slots := indexSlotJobs(jobs)
+
+	var chains []schedulingapiv1.ChainStatus
+	for _, chain := range dependencyChains(scheduler.Spec.Schedules) {
+		var times []time.Time
+		for _, name := range chain {
+			if len(dependsOn[name]) > 0 {
+				continue
+			}
+			for slot := range slots[name] {
+				if !slices.ContainsFunc(times, slot.Equal) {
+					times = append(times, slot)
+				}
+			}
+		}
+		sort.Slice(times, func(i, j int) bool { return times[i].After(times[j]) })
+		if len(times) > runHistoryLimit {
+			times = times[:runHistoryLimit]
+		}
+
+		for _, slot := range times {
+			status := schedulingapiv1.ChainStatus{
+				Name:          chain[0],
+				ScheduledTime: metav1.Time{Time: slot},
+			}
+			for _, name := range chain {
+				step := schedulingapiv1.ChainStepStatus{Schedule: name, Phase: schedulingapiv1.RunPhasePending}
+				if job := slots[name][slot]; job != nil {
+					step.JobName = job.Name
+					run := runStatus(job, podsByJob[job.Name])
+					applySteps(&run, job, podsByJob, steps)
+					step.Phase = run.Phase
+				}
+				status.Steps = append(status.Steps, step)
+			}
+			status.Phase = chainPhase(status.Steps)
+			chains = append(chains, status)
+		}
+	}
+
+	sort.SliceStable(chains, func(i, j int) bool {
+		return chains[i].ScheduledTime.After(chains[j].ScheduledTime.Time)
+	})
+	scheduler.Status.Chains = chains
+}
+
+// chainPhase summarizes the phases of the runs of a chain.
+func chainPhase(steps []schedulingapiv1.ChainStepStatus) schedulingapiv1.RunPhase {
+	succeeded, started := 0, false
+	for _, step := range steps {
+		switch step.Phase {
+		case schedulingapiv1.RunPhaseFailed:
+			return schedulingapiv1.RunPhaseFailed
+		case schedulingapiv1.RunPhaseSucceeded:
+			succeeded++
+			started = true
+		case schedulingapiv1.RunPhaseRunning:
+			started = true
+		}
+	}
+	switch {
+	case succeeded == len(steps):
+		return schedulingapiv1.RunPhaseSucceeded
+	case started:
+		return schedulingapiv1.RunPhaseRunning
+	default:
+		return schedulingapiv1.RunPhasePending
+	}
+}
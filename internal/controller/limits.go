@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+)
+
+const (
+	// completionEndAt and completionMaxRuns are the reasons a schedule is Completed.
+	completionEndAt   = "EndAtReached"
+	completionMaxRuns = "MaxRunsReached"
+)
+
+// updateCompletion marks a schedule Completed once it has reached its EndAt or MaxRuns,
+// and reports whether it has. Raising the limits again resumes the schedule.
+func updateCompletion(schedule schedulingapiv1.Schedule, status *schedulingapiv1.ScheduleStatus, now time.Time) bool {
+	var reason string
+	switch {
+	case schedule.MaxRuns != nil && status.RunCount >= *schedule.MaxRuns:
+		reason = completionMaxRuns
+	case schedule.EndAt != nil && !now.Before(schedule.EndAt.Time):
+		reason = completionEndAt
+	}
+	status.Completed = reason != ""
+	status.CompletionReason = reason
+	return status.Completed
+}
+
+// notStarted reports whether the StartAt of a schedule is still ahead.
+func notStarted(schedule schedulingapiv1.Schedule, now time.Time) bool {
+	return schedule.StartAt != nil && now.Before(schedule.StartAt.Time)
+}
+
+// nextLimitChange returns the earliest StartAt or EndAt still ahead among the schedules
+// of a Scheduler, or the zero time if there is none.
+func nextLimitChange(scheduler *schedulingapiv1.Scheduler, now time.Time) time.Time {
+	var next time.Time
+	for _, schedule := range scheduler.Spec.Schedules {
+		for _, t := range []*metav1.Time{schedule.StartAt, schedule.EndAt} {
+			if t != nil && t.After(now) && (next.IsZero() || t.Time.Before(next)) {
+				next = t.Time
+			}
+		}
+	}
+	return next
+}
+
+// activeFrom returns the time after which the runs of a schedule are searched: t itself,
+// or just before StartAt while it is still ahead.
+func activeFrom(schedule schedulingapiv1.Schedule, t time.Time) time.Time {
+	if notStarted(schedule, t) {
+		return schedule.StartAt.Add(-time.Nanosecond)
+	}
+	return t
+}
+
+// beforeEnd returns t if it is before the EndAt of a schedule, or the zero time otherwise.
+func beforeEnd(schedule schedulingapiv1.Schedule, t time.Time) time.Time {
+	if schedule.EndAt != nil && !t.Before(schedule.EndAt.Time) {
+		return time.Time{}
+	}
+	return t
+}
@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+)
+
+const (
+	// lockPollInterval is how often runs waiting for their lock group check it again. The
+	// Jobs of other Schedulers are not watched, so their completion goes unnoticed.
+	lockPollInterval = 15 * time.Second
+
+	// completionLockHeld is the reason a RunAt schedule is Completed when its run was
+	// skipped because its lock group was held.
+	completionLockHeld = "LockHeld"
+)
+
+// lockHolder returns the oldest active run holding the lock group of a schedule, or nil
+// while the lock is free. Jobs are read from the API server rather than the cache, so a
+// run created earlier in the same reconcile, or by another Scheduler, is never missed.
+func (r *SchedulerReconciler) lockHolder(ctx context.Context, scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule) (*batchv1.Job, error) {
+	group := schedule.LockGroup
+	scope := lockScope(group)
+	opts := []client.ListOption{client.MatchingLabels{
+		schedulingapiv1.LockGroupLabel: group.Name,
+		schedulingapiv1.LockScopeLabel: string(scope),
+	}}
+	if scope == schedulingapiv1.LockScopeNamespace {
+		opts = append(opts, client.InNamespace(scheduler.Namespace))
+	}
+
+	var jobs batchv1.JobList
+	if err := r.reader().List(ctx, &jobs, opts...); err != nil {
+		return nil, fmt.Errorf("failed to list Jobs of lock group %s: %w", group.Name, err)
+	}
+	var holder *batchv1.Job
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if finished, _ := runFinished(job); finished || job.DeletionTimestamp != nil {
+			continue
+		}
+		if holder == nil || job.CreationTimestamp.Before(&holder.CreationTimestamp) {
+			holder = job
+		}
+	}
+	return holder, nil
+}
+
+// waitForLock reports whether a run of a schedule must wait because its lock group is
+// held, and records since when it waits.
+func (r *SchedulerReconciler) waitForLock(ctx context.Context, scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule) (bool, error) {
+	if schedule.LockGroup == nil {
+		return false, nil
+	}
+	holder, err := r.lockHolder(ctx, scheduler, schedule)
+	if err != nil {
+		return false, err
+	}
+	lock := lockStatus(scheduler, schedule)
+	if holder == nil {
+		lock.WaitingSince = nil
+		return false, nil
+	}
+	lock.Holder = holder.Namespace + "/" + holder.Name
+	if lock.WaitingSince == nil {
+		lock.WaitingSince = &metav1.Time{Time: r.now()}
+	}
+	return true, nil
+}
+
+// reconcileLocks reports who holds the lock group of every schedule that has one, and
+// returns whether a run is waiting for a lock.
+func (r *SchedulerReconciler) reconcileLocks(ctx context.Context, scheduler *schedulingapiv1.Scheduler) (bool, []error) {
+	var reconcileErrors []error
+	waiting := false
+	for _, schedule := range scheduler.Spec.Schedules {
+		if schedule.LockGroup == nil {
+			if status := findScheduleStatus(&scheduler.Status, schedule.Name); status != nil {
+				status.Lock = nil
+			}
+			continue
+		}
+		holder, err := r.lockHolder(ctx, scheduler, schedule)
+		if err != nil {
+			reconcileErrors = append(reconcileErrors, err)
+			continue
+		}
+		lock := lockStatus(scheduler, schedule)
+		lock.Holder = ""
+		if holder != nil {
+			lock.Holder = holder.Namespace + "/" + holder.Name
+		}
+		waiting = waiting || lock.WaitingSince != nil
+	}
+	return waiting, reconcileErrors
+}
+
+// lockStatus returns the lock status of a schedule, resetting it when the group changed.
+func lockStatus(scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule) *schedulingapiv1.LockStatus {
+	status := scheduleStatus(&scheduler.Status, schedule.Name)
+	if status.Lock == nil || status.Lock.Group != schedule.LockGroup.Name {
+		status.Lock = &schedulingapiv1.LockStatus{Group: schedule.LockGroup.Name}
+	}
+	return status.Lock
+}
+
+// lockScope returns the scope of a lock group, which defaults to Namespace.
+func lockScope(group *schedulingapiv1.LockGroup) schedulingapiv1.LockScope {
+	if group.Scope == "" {
+		return schedulingapiv1.LockScopeNamespace
+	}
+	return group.Scope
+}
+
+// lockPolicy returns the policy of the lock group of a schedule, which defaults to Delay.
+func lockPolicy(schedule schedulingapiv1.Schedule) schedulingapiv1.LockPolicy {
+	if schedule.LockGroup == nil || schedule.LockGroup.Policy == "" {
+		return schedulingapiv1.LockPolicyDelay
+	}
+	return schedule.LockGroup.Policy
+}
+
+// reader returns the uncached reader when one is configured, and the client otherwise.
+func (r *SchedulerReconciler) reader() client.Reader {
+	if r.APIReader != nil {
+		return r.APIReader
+	}
+	return r.Client
+}
@@ -0,0 +1,416 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cronexpr"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cronjobbuilder"
+)
+
+const (
+	// successfulJobsHistoryLimit and failedJobsHistoryLimit mirror the defaults of the
+	// Kubernetes CronJob controller for Jobs created by the Native engine.
+	successfulJobsHistoryLimit = 3
+	failedJobsHistoryLimit     = 1
+
+	// defaultMaxCatchUpRuns is the number of missed slots run by the All catch-up policy
+	// when MaxCatchUpRuns is unset.
+	defaultMaxCatchUpRuns = 10
+)
+
+// recentWindows are probed from the smallest to the largest when looking for the most
+// recent slot, so that a dense schedule does not walk every slot missed during an outage.
+var recentWindows = []time.Duration{
+	time.Minute,
+	time.Hour,
+	24 * time.Hour,
+	31 * 24 * time.Hour,
+	366 * 24 * time.Hour,
+}
+
+// reconcileNativeSchedules fires the due Jobs of every Native and RunAt schedule and
+// returns how long to wait until the next one is due.
+func (r *SchedulerReconciler) reconcileNativeSchedules(ctx context.Context, scheduler *schedulingapiv1.Scheduler, blackouts blackoutState, calendars map[string]calendarFilter) (time.Duration, []error) {
+	log := log.FromContext(ctx)
+	now := r.now()
+	var requeueAfter time.Duration
+	var reconcileErrors []error
+
+	for _, schedule := range scheduler.Spec.Schedules {
+		// Dependent schedules are started by reconcileDependencies
+		if !firedByController(schedule) || len(schedule.DependsOn) > 0 {
+			continue
+		}
+
+		var next time.Time
+		var err error
+		scheduleCtx, span := startScheduleSpan(ctx, schedule)
+		if schedule.RunAt != nil {
+			next, err = r.reconcileOneOff(scheduleCtx, scheduler, schedule, blackouts.isActive(schedule.Name), now)
+		} else {
+			next, err = r.reconcileNativeSchedule(scheduleCtx, scheduler, schedule, blackouts.isActive(schedule.Name), calendars[schedule.Name], now)
+		}
+		recordError(span, err)
+		span.End()
+		if err != nil {
+			log.Error(err, "Failed to reconcile native schedule", "schedule", schedule.Name)
+			reconcileErrors = append(reconcileErrors, err)
+			continue
+		}
+		if !next.IsZero() {
+			requeueAfter = shortestRequeue(requeueAfter, next.Sub(now))
+		}
+
+		if err := r.pruneJobHistory(ctx, scheduler, schedule); err != nil {
+			log.Error(err, "Failed to prune Job history", "schedule", schedule.Name)
+			reconcileErrors = append(reconcileErrors, err)
+		}
+	}
+
+	return requeueAfter, reconcileErrors
+}
+
+// reconcileNativeSchedule fires the most recent due slot of a schedule, if any, and
+// returns the next time the schedule fires.
+func (r *SchedulerReconciler) reconcileNativeSchedule(ctx context.Context, scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule, blackedOut bool, filter calendarFilter, now time.Time) (time.Time, error) {
+	expr, err := r.effectiveExpression(scheduler, schedule)
+	if err != nil {
+		return time.Time{}, err
+	}
+	sched, err := cronexpr.Parse(expr, scheduleKey(scheduler, schedule))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid cron expression for schedule %s: %w", schedule.Name, err)
+	}
+
+	status := scheduleStatus(&scheduler.Status, schedule.Name)
+	status.EffectiveSchedule = expr
+	if filter.err != nil {
+		return time.Time{}, filter.err
+	}
+	// A blackout window suspends the schedule like Suspend does, until the window ends,
+	// while a Completed schedule stops for good
+	recordHold(status, circuitOpen(scheduler, schedule), now)
+	if updateCompletion(schedule, status, now) || blackedOut || suspended(scheduler, schedule) {
+		status.NextFireTime = nil
+		return time.Time{}, nil
+	}
+
+	// Slots are counted from the last recorded fire time, falling back to the creation of
+	// the Scheduler, so restarts and leader changes neither skip nor repeat a slot. Slots
+	// missed while the circuit was open are not caught up once it closes.
+	earliest := scheduler.CreationTimestamp.Time
+	if status.LastFireTime != nil {
+		earliest = status.LastFireTime.Time
+	}
+	if resumed := status.ResumedAt; resumed != nil {
+		if resumed.After(earliest) {
+			earliest = resumed.Time
+		} else {
+			status.ResumedAt = nil
+		}
+	}
+	earliest = activeFrom(schedule, earliest)
+
+	// Due slots are fired once their jittered start is reached, and waited for until then.
+	// Slots on days excluded by the schedule's Calendars or inside its blackout windows are
+	// skipped, and so are slots
+	// missed for longer than the grace period under the None catch-up policy, unless they
+	// were held back by the schedule's lock group. Slots whose preconditions are not met
+	// are recorded as Skipped runs.
+	var pendingStart time.Time
+	for _, slot := range dueSlots(sched, schedule, earliest, now) {
+		if !filter.allows(slot) || blackedOutAt(scheduler, schedule, slot) {
+			continue
+		}
+		start := slot.Add(jitterOffset(scheduler, schedule, slot))
+		if start.After(now) {
+			pendingStart = start
+			break
+		}
+		heldBack := (status.Lock != nil && status.Lock.WaitingSince != nil) || lockPolicy(schedule) == schedulingapiv1.LockPolicyQueue
+		if schedule.CatchUpPolicy == schedulingapiv1.CatchUpNone && !heldBack && now.Sub(start) > cronjobbuilder.MissedRunGrace {
+			continue
+		}
+
+		waiting, err := r.waitForLock(ctx, scheduler, schedule)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if waiting && lockPolicy(schedule) == schedulingapiv1.LockPolicySkip {
+			status.Lock.Skipped++
+			status.Lock.WaitingSince = nil
+			status.LastFireTime = &metav1.Time{Time: slot}
+			continue
+		}
+		if waiting {
+			break
+		}
+
+		reason, err := r.checkPreconditions(ctx, scheduler, schedule)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if reason != "" {
+			skipRun(ctx, status, scheduler, schedule, slot, reason)
+			status.LastFireTime = &metav1.Time{Time: slot}
+			continue
+		}
+
+		created, err := r.fireJob(ctx, scheduler, schedule, slot)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if created {
+			status.RunCount++
+		}
+		status.LastFireTime = &metav1.Time{Time: slot}
+		if updateCompletion(schedule, status, now) {
+			status.NextFireTime = nil
+			return time.Time{}, nil
+		}
+		// The run now holds the lock, so the next slots wait for it to finish
+		if schedule.LockGroup != nil {
+			break
+		}
+	}
+
+	next := beforeEnd(schedule, filter.nextAllowed(sched, activeFrom(schedule, now)))
+	if !next.IsZero() {
+		next = next.Add(jitterOffset(scheduler, schedule, next))
+	}
+	if !pendingStart.IsZero() {
+		next = pendingStart
+	}
+
+	status.NextFireTime = nil
+	if !next.IsZero() {
+		status.NextFireTime = &metav1.Time{Time: next}
+	}
+	return next, nil
+}
+
+// jitterOffset returns the delay applied to a slot of a schedule with jitter. It looks
+// random but is derived from the schedule and the slot, so a restarted controller waits
+// for the same start time instead of drawing a new one.
+func jitterOffset(scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule, slot time.Time) time.Duration {
+	if schedule.Jitter == nil || schedule.Jitter.Duration < time.Second {
+		return 0
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(scheduleKey(scheduler, schedule)))
+	_, _ = h.Write([]byte(slot.UTC().Format(time.RFC3339)))
+	return time.Duration(h.Sum64()%uint64(schedule.Jitter.Duration/time.Second)) * time.Second
+}
+
+// fireJob creates the Job of a schedule for the given slot and reports whether it was
+// created. Job names are derived from the slot, so a Job that already exists was fired
+// before and is not an error.
+func (r *SchedulerReconciler) fireJob(ctx context.Context, scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule, slot time.Time) (bool, error) {
+	log := log.FromContext(ctx)
+	outputs, err := r.runOutputs(ctx, scheduler, slot)
+	if err != nil {
+		return false, err
+	}
+	job, err := cronjobbuilder.BuildJob(scheduler, schedule, slot, outputs)
+	if err != nil {
+		return false, err
+	}
+
+	if err := ctrl.SetControllerReference(scheduler, job, r.Scheme); err != nil {
+		return false, fmt.Errorf("failed to set owner reference for Job %s: %w", job.Name, err)
+	}
+
+	if err := r.queueForQuota(ctx, job); err != nil {
+		return false, err
+	}
+
+	log.Info("Creating Job", "name", job.Name, "scheduledTime", slot)
+	if err := r.createJob(ctx, job); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to create Job %s: %w", job.Name, err)
+	}
+	return true, nil
+}
+
+// pruneJobHistory deletes the oldest finished Jobs of a Native schedule beyond the
+// history limits.
+func (r *SchedulerReconciler) pruneJobHistory(ctx context.Context, scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule) error {
+	log := log.FromContext(ctx)
+	var jobs batchv1.JobList
+	if err := r.List(ctx, &jobs, client.InNamespace(scheduler.Namespace), client.MatchingLabels(cronjobbuilder.Labels(scheduler, schedule))); err != nil {
+		return fmt.Errorf("failed to list Jobs of schedule %s: %w", schedule.Name, err)
+	}
+	var podsByJob map[string][]corev1.Pod
+	if schedule.RetryPolicy != nil {
+		var err error
+		if podsByJob, err = r.listPodsByJob(ctx, scheduler); err != nil {
+			return err
+		}
+	}
+
+	for _, job := range expiredJobs(scheduler, schedule, jobs.Items, podsByJob) {
+		log.Info("Deleting old Job", "name", job.Name)
+		if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("failed to delete old Job %s: %w", job.Name, err)
+		}
+	}
+	return nil
+}
+
+// expiredJobs returns the oldest finished Jobs of a schedule owned by the Scheduler beyond
+// the history limits. A failed run waiting for its retry is kept, since the retry is
+// started from its Job. The CronJob of a schedule with a RetryPolicy leaves its failed
+// Jobs to this pruning too.
+func expiredJobs(scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule, jobs []batchv1.Job, podsByJob map[string][]corev1.Pod) []*batchv1.Job {
+	latest := indexRunAttempts(jobs)
+	steps := indexStepJobs(jobs)
+
+	var succeeded, failed []*batchv1.Job
+	for i := range jobs {
+		job := &jobs[i]
+		owned := metav1.IsControlledBy(job, scheduler)
+		if !owned && !(schedule.RetryPolicy != nil && ownedByCronJob(job, cronjobbuilder.CronJobName(scheduler, schedule))) {
+			continue
+		}
+		switch finished, condition := runFinished(job); {
+		case !finished:
+		case condition == batchv1.JobComplete:
+			if owned {
+				succeeded = append(succeeded, job)
+			}
+		default:
+			if latest.isLatestAttempt(job) {
+				if _, retried := nextRetry(schedule, job, podsByJob, steps); retried {
+					continue
+				}
+			}
+			failed = append(failed, job)
+		}
+	}
+
+	var expired []*batchv1.Job
+	for _, history := range []struct {
+		jobs  []*batchv1.Job
+		limit int
+	}{
+		{succeeded, successfulJobsHistoryLimit},
+		{failed, failedJobsHistoryLimit},
+	} {
+		if len(history.jobs) <= history.limit {
+			continue
+		}
+		sort.Slice(history.jobs, func(i, j int) bool {
+			return history.jobs[i].CreationTimestamp.Before(&history.jobs[j].CreationTimestamp)
+		})
+		expired = append(expired, history.jobs[:len(history.jobs)-history.limit]...)
+	}
+	return expired
+}
+
+// ownedByCronJob reports whether a Job was started by the named CronJob.
+func ownedByCronJob(job *batchv1.Job, name string) bool {
+	owner := metav1.GetControllerOf(job)
+	return owner != nil && owner.Kind == "CronJob" && owner.Name == name
+}
+
+// dueSlots returns the slots of a schedule in (earliest, now] to consider firing, oldest
+// first: up to MaxCatchUpRuns of the most recent ones under the All catch-up policy or the
+// Queue lock policy, and the most recent one otherwise.
+func dueSlots(sched cronexpr.Schedule, schedule schedulingapiv1.Schedule, earliest, now time.Time) []time.Time {
+	queued := schedule.LockGroup != nil && lockPolicy(schedule) == schedulingapiv1.LockPolicyQueue
+	if schedule.CatchUpPolicy != schedulingapiv1.CatchUpAll && !queued {
+		if slot := mostRecentSlot(sched, earliest, now); !slot.IsZero() {
+			return []time.Time{slot}
+		}
+		return nil
+	}
+
+	limit := defaultMaxCatchUpRuns
+	if schedule.MaxCatchUpRuns != nil {
+		limit = int(*schedule.MaxCatchUpRuns)
+	}
+
+	// Only walk the smallest recent window holding enough slots
+	for _, window := range recentWindows {
+		from := now.Add(-window)
+		if !from.After(earliest) {
+			break
+		}
+		if len(lastSlots(sched, from, now, limit)) == limit {
+			earliest = from
+			break
+		}
+	}
+	return lastSlots(sched, earliest, now, limit)
+}
+
+// lastSlots returns up to limit of the latest activations of sched in (from, now], oldest
+// first.
+func lastSlots(sched cronexpr.Schedule, from, now time.Time, limit int) []time.Time {
+	slots := make([]time.Time, 0, limit)
+	for t := sched.Next(from); !t.IsZero() && !t.After(now); t = sched.Next(t) {
+		if len(slots) == limit {
+			copy(slots, slots[1:])
+			slots = slots[:limit-1]
+		}
+		slots = append(slots, t)
+	}
+	return slots
+}
+
+// mostRecentSlot returns the latest activation of sched in (earliest, now], or the zero
+// time if none is due.
+func mostRecentSlot(sched cronexpr.Schedule, earliest, now time.Time) time.Time {
+	for _, window := range recentWindows {
+		from := now.Add(-window)
+		if !from.After(earliest) {
+			break
+		}
+		if next := sched.Next(from); !next.IsZero() && !next.After(now) {
+			earliest = from
+			break
+		}
+	}
+
+	var slot time.Time
+	for t := sched.Next(earliest); !t.IsZero() && !t.After(now); t = sched.Next(t) {
+		slot = t
+	}
+	return slot
+}
+
+// jobFinished reports whether a Job has completed or failed, and which of the two.
+func jobFinished(job *batchv1.Job) (bool, batchv1.JobConditionType) {
+	for _, c := range job.Status.Conditions {
+		if (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) && c.Status == corev1.ConditionTrue {
+			return true, c.Type
+		}
+	}
+	return false, ""
+}
+
+// shortestRequeue returns the shortest positive of two requeue delays, treating zero as unset.
+func shortestRequeue(current, candidate time.Duration) time.Duration {
+	if candidate <= 0 {
+		candidate = time.Second
+	}
+	if current == 0 || candidate < current {
+		return candidate
+	}
+	return current
+}
@@ -0,0 +1,484 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cronexpr"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cronjobbuilder"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/notify"
+)
+
+const (
+	// defaultLongRunningAfter is how long a run may run before LongRunning is notified,
+	// when the Scheduler does not set LongRunningAfter.
+	defaultLongRunningAfter = time.Hour
+
+	// defaultMissedAfter is how late a run may be before Missed is notified, when the
+	// Scheduler does not set MissedAfter.
+	defaultMissedAfter = 5 * time.Minute
+
+	// maxNotificationAttempts is the number of deliveries of a notification attempted
+	// before it is dropped.
+	maxNotificationAttempts = 5
+
+	// notificationRetryDelay is the delay before the second delivery of a notification,
+	// doubled for every further one up to maxNotificationRetryDelay.
+	notificationRetryDelay    = 30 * time.Second
+	maxNotificationRetryDelay = 10 * time.Minute
+
+	// maxPendingNotifications bounds the notifications of a Scheduler waiting to be
+	// delivered again.
+	maxPendingNotifications = 50
+)
+
+// NotificationDispatcher sends notifications in the background, without blocking.
+type NotificationDispatcher interface {
+	Dispatch(sender notify.Sender, n notify.Notification, done func(err error)) bool
+}
+
+// reconcileNotifications notifies the sinks of a Scheduler about the runs that failed,
+// recovered or ran too long and the slots that were missed since the last reconcile,
+// and delivers again the notifications whose sink failed. It returns when the next
+// notification may be due.
+func (r *SchedulerReconciler) reconcileNotifications(ctx context.Context, scheduler *schedulingapiv1.Scheduler, jobs []batchv1.Job, blackouts blackoutState, calendars map[string]calendarFilter) (time.Duration, error) {
+	config := scheduler.Spec.Notifications
+	if config == nil {
+		for i := range scheduler.Status.Schedules {
+			scheduler.Status.Schedules[i].Notifications = nil
+		}
+		scheduler.Status.PendingNotifications = nil
+		r.forgetDeliveries(types.NamespacedName{Namespace: scheduler.Namespace, Name: scheduler.Name}, nil)
+		return 0, nil
+	}
+
+	podsByJob, err := r.listPodsByJob(ctx, scheduler)
+	if err != nil {
+		return 0, err
+	}
+	attempts := indexRunAttempts(jobs)
+	steps := indexStepJobs(jobs)
+	longRunningAfter := defaultLongRunningAfter
+	if config.LongRunningAfter != nil {
+		longRunningAfter = config.LongRunningAfter.Duration
+	}
+
+	now := r.now()
+	var requeueAfter time.Duration
+	var occurred []schedulingapiv1.PendingNotification
+	for _, schedule := range scheduler.Spec.Schedules {
+		status := scheduleStatus(&scheduler.Status, schedule.Name)
+		observed, after := observeRuns(status, schedule, attempts[schedule.Name], podsByJob, steps, longRunningAfter, now)
+		occurred = append(occurred, observed...)
+		if after > 0 {
+			requeueAfter = shortestRequeue(requeueAfter, after)
+		}
+
+		missed, after, err := r.observeMissedSlot(scheduler, schedule, status, blackouts, calendars[schedule.Name], now)
+		if err != nil {
+			log.FromContext(ctx).Error(err, "Not checking for missed runs", "schedule", schedule.Name)
+		}
+		if missed != nil {
+			occurred = append(occurred, *missed)
+		}
+		if after > 0 {
+			requeueAfter = shortestRequeue(requeueAfter, after)
+		}
+	}
+
+	// Every occurrence is delivered now, to each sink it is meant for, after the
+	// notifications already waiting
+	pending := scheduler.Status.PendingNotifications
+	for _, o := range occurred {
+		for _, sink := range config.Sinks {
+			if !slices.Contains(sink.On, o.Trigger) || (len(sink.Schedules) > 0 && !slices.Contains(sink.Schedules, o.Schedule)) {
+				continue
+			}
+			n := o
+			n.Sink = sink.Name
+			n.NextAttemptTime = metav1.Time{Time: now}
+			if !slices.ContainsFunc(pending, func(p schedulingapiv1.PendingNotification) bool {
+				return p.Sink == n.Sink && p.Trigger == n.Trigger && p.Run == n.Run
+			}) {
+				pending = append(pending, n)
+			}
+		}
+	}
+
+	remaining, after := r.deliverNotifications(ctx, scheduler, pending, now)
+	if after > 0 {
+		requeueAfter = shortestRequeue(requeueAfter, after)
+	}
+	if len(remaining) > maxPendingNotifications {
+		for _, n := range remaining[:len(remaining)-maxPendingNotifications] {
+			r.event(scheduler, corev1.EventTypeWarning, "NotificationFailed", "Dropped %s notification of %s to sink %s: too many pending notifications",
+				n.Trigger, n.Run, n.Sink)
+		}
+		remaining = remaining[len(remaining)-maxPendingNotifications:]
+	}
+	scheduler.Status.PendingNotifications = remaining
+	r.forgetDeliveries(types.NamespacedName{Namespace: scheduler.Namespace, Name: scheduler.Name}, remaining)
+	return requeueAfter, nil
+}
+
+// observeRuns returns the notifications about the runs of a schedule since the last
+// reconcile: runs that failed, runs that succeeded after a failure and runs running for
+// longer than longRunningAfter. The runs that finished before the schedule was first
+// observed are not notified. It returns when a running Job becomes long-running.
+func observeRuns(status *schedulingapiv1.ScheduleStatus, schedule schedulingapiv1.Schedule, jobs map[string]*batchv1.Job, podsByJob map[string][]corev1.Pod, steps stepIndex, longRunningAfter time.Duration, now time.Time) ([]schedulingapiv1.PendingNotification, time.Duration) {
+	state := status.Notifications
+	first := state == nil
+	if first {
+		state = &schedulingapiv1.ScheduleNotificationStatus{}
+		status.Notifications = state
+	}
+
+	var notifications []schedulingapiv1.PendingNotification
+	occurred := func(trigger schedulingapiv1.NotificationTrigger, job *batchv1.Job, run schedulingapiv1.RunStatus, message string) {
+		notifications = append(notifications, schedulingapiv1.PendingNotification{
+			Trigger:       trigger,
+			Schedule:      schedule.Name,
+			Run:           job.Name,
+			ScheduledTime: run.ScheduledTime,
+			Message:       message,
+			Time:          metav1.Time{Time: now},
+		})
+	}
+
+	for _, f := range finishedSince(schedule, jobs, podsByJob, steps, state.LastObservedTime, state.LastObservedJob) {
+		if !first {
+			switch {
+			case f.run.Phase == schedulingapiv1.RunPhaseFailed:
+				occurred(schedulingapiv1.NotifyFailure, f.job, f.run, failureReason(f.job, podsByJob[f.job.Name]))
+			case state.LastPhase == schedulingapiv1.RunPhaseFailed:
+				occurred(schedulingapiv1.NotifyRecovery, f.job, f.run, fmt.Sprintf("Job %s succeeded after the previous run failed", f.job.Name))
+			}
+		}
+		state.LastPhase = f.run.Phase
+		state.LastObservedJob = f.job.Name
+		state.LastObservedTime = f.run.CompletionTime
+	}
+
+	var requeueAfter time.Duration
+	var longRunning []string
+	for _, job := range jobs {
+		if finished, _ := jobFinished(job); finished {
+			continue
+		}
+		started := job.CreationTimestamp.Time
+		if s := jobContainerStartTime(podsByJob[job.Name]); s != nil {
+			started = s.Time
+		}
+		if due := started.Add(longRunningAfter); due.After(now) {
+			requeueAfter = shortestRequeue(requeueAfter, due.Sub(now))
+			continue
+		}
+		longRunning = append(longRunning, job.Name)
+		if !slices.Contains(state.LongRunning, job.Name) {
+			run := runStatus(job, podsByJob[job.Name])
+			occurred(schedulingapiv1.NotifyLongRunning, job, run, fmt.Sprintf("Job %s has been running for %s", job.Name, now.Sub(started).Round(time.Second)))
+		}
+	}
+	slices.Sort(longRunning)
+	state.LongRunning = longRunning
+	return notifications, requeueAfter
+}
+
+// observeMissedSlot returns the notification about the latest slot of a cron schedule
+// that passed more than MissedAfter ago without the schedule firing, unless it was
+// already notified or the schedule was held back on purpose: suspended, blacked out,
+// waiting for its lock group or paused by annotation. It returns when the next slot
+// could be missed.
+func (r *SchedulerReconciler) observeMissedSlot(scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule, status *schedulingapiv1.ScheduleStatus, blackouts blackoutState, filter calendarFilter, now time.Time) (*schedulingapiv1.PendingNotification, time.Duration, error) {
+	if schedule.CronExpression == "" || len(schedule.DependsOn) > 0 || status.Completed || filter.err != nil ||
+		scheduler.Annotations[schedulingapiv1.ReconcileAnnotation] == schedulingapiv1.ReconcilePaused ||
+		suspended(scheduler, schedule) || blackouts.isActive(schedule.Name) || notStarted(schedule, now) ||
+		(status.Lock != nil && status.Lock.WaitingSince != nil) {
+		return nil, 0, nil
+	}
+	expr, err := r.effectiveExpression(scheduler, schedule)
+	if err != nil {
+		return nil, 0, err
+	}
+	sched, err := cronexpr.Parse(expr, scheduleKey(scheduler, schedule))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Runs start up to their jitter late on purpose
+	late := defaultMissedAfter
+	if config := scheduler.Spec.Notifications; config.MissedAfter != nil {
+		late = config.MissedAfter.Duration
+	}
+	if schedule.Jitter != nil {
+		late += schedule.Jitter.Duration
+	}
+
+	earliest := scheduler.CreationTimestamp.Time
+	for _, t := range []*metav1.Time{status.LastFireTime, status.Notifications.LastMissedTime} {
+		if t != nil && t.After(earliest) {
+			earliest = t.Time
+		}
+	}
+	earliest = activeFrom(schedule, earliest)
+
+	var requeueAfter time.Duration
+	deadline := now.Add(-late)
+	if next := sched.Next(maxTime(earliest, deadline)); !next.IsZero() {
+		requeueAfter = next.Add(late).Sub(now)
+	}
+	slot := mostRecentSlot(sched, earliest, deadline)
+	if slot.IsZero() || !filter.allows(slot) {
+		return nil, requeueAfter, nil
+	}
+
+	status.Notifications.LastMissedTime = &metav1.Time{Time: slot}
+	return &schedulingapiv1.PendingNotification{
+		Trigger:       schedulingapiv1.NotifyMissed,
+		Schedule:      schedule.Name,
+		Run:           cronjobbuilder.JobName(scheduler, schedule, slot),
+		ScheduledTime: &metav1.Time{Time: slot},
+		Message:       fmt.Sprintf("The run scheduled for %s did not start within %s", slot.Format(time.RFC3339), late),
+		Time:          metav1.Time{Time: now},
+	}, requeueAfter, nil
+}
+
+// deliverNotifications hands the due notifications of a Scheduler to the dispatcher and
+// collects the outcome of those it sent since the last reconcile, so that reconciles never
+// wait for a sink. It returns the notifications still pending, with the failed ones
+// scheduled again with a backoff, and when the next outcome or attempt is due.
+func (r *SchedulerReconciler) deliverNotifications(ctx context.Context, scheduler *schedulingapiv1.Scheduler, pending []schedulingapiv1.PendingNotification, now time.Time) ([]schedulingapiv1.PendingNotification, time.Duration) {
+	sinks := scheduler.Spec.Notifications.Sinks
+	schedulerKey := types.NamespacedName{Namespace: scheduler.Namespace, Name: scheduler.Name}
+	var requeueAfter time.Duration
+	var remaining []schedulingapiv1.PendingNotification
+	for _, n := range pending {
+		if n.NextAttemptTime.After(now) {
+			remaining = append(remaining, n)
+			requeueAfter = shortestRequeue(requeueAfter, n.NextAttemptTime.Sub(now))
+			continue
+		}
+		i := slices.IndexFunc(sinks, func(s schedulingapiv1.NotificationSink) bool { return s.Name == n.Sink })
+		if i < 0 {
+			continue
+		}
+
+		key := notificationKey{scheduler: schedulerKey, sink: n.Sink, trigger: n.Trigger, run: n.Run}
+		var err error
+		if value, ok := r.deliveries.Load(key); ok {
+			done, outcome := value.(*notificationDelivery).outcome()
+			if !done {
+				// The outcome is collected when the dispatcher wakes the Scheduler up,
+				// or at the latest once the delivery timed out
+				remaining = append(remaining, n)
+				requeueAfter = shortestRequeue(requeueAfter, notify.Timeout)
+				continue
+			}
+			r.deliveries.Delete(key)
+			err = outcome
+		} else {
+			var sent bool
+			if sent, err = r.dispatchNotification(ctx, scheduler, sinks[i], n, key); err == nil {
+				remaining = append(remaining, n)
+				if sent {
+					requeueAfter = shortestRequeue(requeueAfter, notify.Timeout)
+				} else {
+					// The dispatcher is busy, the notification waits without counting an attempt
+					requeueAfter = shortestRequeue(requeueAfter, notificationRetryDelay)
+				}
+				continue
+			}
+		}
+
+		if err == nil {
+			log.FromContext(ctx).Info("Delivered notification", "sink", n.Sink, "trigger", n.Trigger, "schedule", n.Schedule, "run", n.Run)
+			continue
+		}
+		n.Attempts++
+		n.LastError = err.Error()
+		if n.Attempts >= maxNotificationAttempts {
+			log.FromContext(ctx).Error(err, "Dropping notification", "sink", n.Sink, "trigger", n.Trigger, "run", n.Run)
+			r.event(scheduler, corev1.EventTypeWarning, "NotificationFailed", "Dropped %s notification of %s to sink %s after %d attempts: %s",
+				n.Trigger, n.Run, n.Sink, n.Attempts, n.LastError)
+			continue
+		}
+		delay := notificationDelay(n.Attempts)
+		n.NextAttemptTime = metav1.Time{Time: now.Add(delay)}
+		remaining = append(remaining, n)
+		requeueAfter = shortestRequeue(requeueAfter, delay)
+	}
+	return remaining, requeueAfter
+}
+
+// notificationKey identifies a notification of a Scheduler being delivered.
+type notificationKey struct {
+	scheduler types.NamespacedName
+	sink      string
+	trigger   schedulingapiv1.NotificationTrigger
+	run       string
+}
+
+// notificationDelivery is the outcome of a notification handed to the dispatcher.
+type notificationDelivery struct {
+	mu   sync.Mutex
+	done bool
+	err  error
+}
+
+// outcome reports whether the delivery is over, and its error.
+func (d *notificationDelivery) outcome() (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.done, d.err
+}
+
+// dispatchNotification hands a notification to the dispatcher, which wakes the Scheduler
+// up once it is sent. It reports false when the dispatcher is busy or missing, and fails
+// when the sink cannot be resolved.
+func (r *SchedulerReconciler) dispatchNotification(ctx context.Context, scheduler *schedulingapiv1.Scheduler, sink schedulingapiv1.NotificationSink, n schedulingapiv1.PendingNotification, key notificationKey) (bool, error) {
+	if r.Notifier == nil {
+		return false, nil
+	}
+	sender, err := r.notificationSender(ctx, scheduler.Namespace, sink)
+	if err != nil {
+		return false, err
+	}
+	d := &notificationDelivery{}
+	r.deliveries.Store(key, d)
+	sent := r.Notifier.Dispatch(sender, notification(scheduler, n), func(err error) {
+		d.mu.Lock()
+		d.done, d.err = true, err
+		d.mu.Unlock()
+		r.wakeUp(key.scheduler)
+	})
+	if !sent {
+		r.deliveries.Delete(key)
+	}
+	return sent, nil
+}
+
+// forgetDeliveries drops the deliveries of the notifications of a Scheduler that are no
+// longer pending, such as those of a deleted Scheduler or sink or those dropped.
+func (r *SchedulerReconciler) forgetDeliveries(scheduler types.NamespacedName, pending []schedulingapiv1.PendingNotification) {
+	r.deliveries.Range(func(k, _ any) bool {
+		key := k.(notificationKey)
+		if key.scheduler == scheduler && !slices.ContainsFunc(pending, func(n schedulingapiv1.PendingNotification) bool {
+			return n.Sink == key.sink && n.Trigger == key.trigger && n.Run == key.run
+		}) {
+			r.deliveries.Delete(key)
+		}
+		return true
+	})
+}
+
+// maxTime returns the later of two times.
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// notificationDelay returns how long to wait before delivering a notification again
+// after it failed the given number of times.
+func notificationDelay(attempts int32) time.Duration {
+	delay := notificationRetryDelay
+	for i := int32(1); i < attempts && delay < maxNotificationRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxNotificationRetryDelay)
+}
+
+// notification returns the notification sent to the sinks for a pending notification.
+func notification(scheduler *schedulingapiv1.Scheduler, n schedulingapiv1.PendingNotification) notify.Notification {
+	notification := notify.Notification{
+		Trigger:   string(n.Trigger),
+		Namespace: scheduler.Namespace,
+		Scheduler: scheduler.Name,
+		Schedule:  n.Schedule,
+		Run:       n.Run,
+		Time:      n.Time.UTC(),
+		Message:   n.Message,
+	}
+	if n.ScheduledTime != nil {
+		scheduledTime := n.ScheduledTime.UTC()
+		notification.ScheduledTime = &scheduledTime
+	}
+	return notification
+}
+
+// notificationSender returns the sender of a sink, with its credentials read from the
+// Secrets of the namespace of the Scheduler.
+func (r *SchedulerReconciler) notificationSender(ctx context.Context, namespace string, sink schedulingapiv1.NotificationSink) (notify.Sender, error) {
+	switch {
+	case sink.Webhook != nil:
+		webhook := notify.Webhook{URL: sink.Webhook.URL, Body: sink.Webhook.Body}
+		if ref := sink.Webhook.URLSecretRef; ref != nil {
+			value, err := r.secretValue(ctx, namespace, *ref)
+			if err != nil {
+				return nil, err
+			}
+			webhook.URL = value
+		}
+		if ref := sink.Webhook.AuthorizationSecretRef; ref != nil {
+			value, err := r.secretValue(ctx, namespace, *ref)
+			if err != nil {
+				return nil, err
+			}
+			webhook.Authorization = value
+		}
+		return webhook, nil
+
+	case sink.Slack != nil:
+		url, err := r.secretValue(ctx, namespace, sink.Slack.URLSecretRef)
+		if err != nil {
+			return nil, err
+		}
+		return notify.Slack{URL: url}, nil
+
+	case sink.SMTP != nil:
+		s := notify.SMTP{Host: sink.SMTP.Host, Port: int(sink.SMTP.Port), From: sink.SMTP.From, To: sink.SMTP.To}
+		if s.Port == 0 {
+			s.Port = 587
+		}
+		for _, c := range []struct {
+			ref   *corev1.SecretKeySelector
+			value *string
+		}{{sink.SMTP.UsernameSecretRef, &s.Username}, {sink.SMTP.PasswordSecretRef, &s.Password}} {
+			if c.ref == nil {
+				continue
+			}
+			value, err := r.secretValue(ctx, namespace, *c.ref)
+			if err != nil {
+				return nil, err
+			}
+			*c.value = value
+		}
+		return s, nil
+	}
+	return nil, fmt.Errorf("sink %s has no destination", sink.Name)
+}
+
+// secretValue reads a key of a Secret. Secrets are read directly rather than cached for
+// the whole cluster.
+func (r *SchedulerReconciler) secretValue(ctx context.Context, namespace string, ref corev1.SecretKeySelector) (string, error) {
+	var secret corev1.Secret
+	if err := r.reader().Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, &secret); err != nil {
+		return "", fmt.Errorf("failed to get Secret %s: %w", ref.Name, err)
+	}
+	value, ok := secret.Data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no key %s", ref.Name, ref.Key)
+	}
+	return string(value), nil
+}
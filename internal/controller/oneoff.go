@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cronjobbuilder"
+)
+
+// completionRunFinished is the reason a RunAt schedule is Completed.
+const completionRunFinished = "RunFinished"
+
+// firedByController reports whether the controller creates the Jobs of a schedule itself
+// rather than through a CronJob. Schedules in a lock group are, so that the lock is taken
+// before their runs start.
+func firedByController(schedule schedulingapiv1.Schedule) bool {
+	return schedule.Mode == schedulingapiv1.ScheduleModeNative || schedule.RunAt != nil || len(schedule.DependsOn) > 0 ||
+		schedule.LockGroup != nil
+}
+
+// reconcileOneOff creates the single Job of a RunAt schedule once it is due and returns
+// when it is, or the zero time once the Job has been created.
+func (r *SchedulerReconciler) reconcileOneOff(ctx context.Context, scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule, blackedOut bool, now time.Time) (time.Time, error) {
+	status := scheduleStatus(&scheduler.Status, schedule.Name)
+	status.EffectiveSchedule = ""
+	runAt := schedule.RunAt.Time.UTC()
+
+	// The Job is named after RunAt, so it tells whether the run already happened even
+	// when the status was removed after it finished
+	var job batchv1.Job
+	err := r.Get(ctx, types.NamespacedName{Namespace: scheduler.Namespace, Name: cronjobbuilder.JobName(scheduler, schedule, runAt)}, &job)
+	switch {
+	case err == nil:
+		status.LastFireTime = &metav1.Time{Time: runAt}
+		status.NextFireTime = nil
+		if finished, _ := runFinished(&job); finished {
+			status.Completed = true
+			status.CompletionReason = completionRunFinished
+		}
+		return time.Time{}, nil
+	case !apierrors.IsNotFound(err):
+		return time.Time{}, fmt.Errorf("failed to get Job of schedule %s: %w", schedule.Name, err)
+	case status.LastFireTime != nil:
+		// The Job ran and was deleted since, or the run was skipped for its lock group or
+		// preconditions
+		status.NextFireTime = nil
+		status.Completed = true
+		if status.CompletionReason != completionLockHeld && status.CompletionReason != completionPreconditionFailed {
+			status.CompletionReason = completionRunFinished
+		}
+		return time.Time{}, nil
+	}
+
+	status.Completed = false
+	status.CompletionReason = ""
+	if blackedOut || suspended(scheduler, schedule) {
+		status.NextFireTime = nil
+		return time.Time{}, nil
+	}
+
+	start := runAt.Add(jitterOffset(scheduler, schedule, runAt))
+	if start.After(now) {
+		status.NextFireTime = &metav1.Time{Time: start}
+		return start, nil
+	}
+
+	waiting, err := r.waitForLock(ctx, scheduler, schedule)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if waiting {
+		if lockPolicy(schedule) == schedulingapiv1.LockPolicySkip {
+			status.Lock.Skipped++
+			status.Lock.WaitingSince = nil
+			status.LastFireTime = &metav1.Time{Time: runAt}
+			status.NextFireTime = nil
+			status.Completed = true
+			status.CompletionReason = completionLockHeld
+		}
+		return time.Time{}, nil
+	}
+
+	reason, err := r.checkPreconditions(ctx, scheduler, schedule)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if reason != "" {
+		skipRun(ctx, status, scheduler, schedule, runAt, reason)
+		status.LastFireTime = &metav1.Time{Time: runAt}
+		status.NextFireTime = nil
+		status.Completed = true
+		status.CompletionReason = completionPreconditionFailed
+		return time.Time{}, nil
+	}
+
+	created, err := r.fireJob(ctx, scheduler, schedule, runAt)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if created {
+		status.RunCount++
+	}
+	status.LastFireTime = &metav1.Time{Time: runAt}
+	status.NextFireTime = nil
+	return time.Time{}, nil
+}
@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cronjobbuilder"
+)
+
+const (
+	// defaultOutputMaxBytes is the size captured output is truncated to when the schedule
+	// does not set Output.MaxBytes.
+	defaultOutputMaxBytes = 1024
+
+	// redactedOutput replaces the matches of the redaction patterns of a schedule.
+	redactedOutput = "[REDACTED]"
+
+	// truncatedOutput prefixes output whose beginning was cut.
+	truncatedOutput = "[truncated] "
+
+	// maxLogBytes bounds the logs read to capture their end.
+	maxLogBytes = 1 << 20
+)
+
+// outputFilter truncates and redacts the output captured from the runs of a schedule.
+type outputFilter struct {
+	maxBytes int
+	redact   []*regexp.Regexp
+	err      error
+}
+
+// newOutputFilter returns the filter configured by the Output of a schedule.
+func newOutputFilter(schedule schedulingapiv1.Schedule) outputFilter {
+	f := outputFilter{maxBytes: defaultOutputMaxBytes}
+	if schedule.Output == nil {
+		return f
+	}
+	if schedule.Output.MaxBytes > 0 {
+		f.maxBytes = int(schedule.Output.MaxBytes)
+	}
+	for _, pattern := range schedule.Output.Redact {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			f.err = fmt.Errorf("invalid redact pattern %q of schedule %s: %w", pattern, schedule.Name, err)
+			return f
+		}
+		f.redact = append(f.redact, re)
+	}
+	return f
+}
+
+// apply redacts and then truncates output. Nothing is captured while a redaction
+// pattern is invalid, since the output could then reveal what it should hide.
+func (f outputFilter) apply(output string) string {
+	if f.err != nil || output == "" {
+		return ""
+	}
+	for _, re := range f.redact {
+		output = re.ReplaceAllLiteralString(output, redactedOutput)
+	}
+	return truncateOutput(output, f.maxBytes)
+}
+
+// truncateOutput keeps the end of output, where errors are usually reported, within
+// maxBytes without splitting a UTF-8 character.
+func truncateOutput(output string, maxBytes int) string {
+	if len(output) <= maxBytes {
+		return output
+	}
+	start := len(output) - maxBytes + len(truncatedOutput)
+	for start < len(output) && !utf8.RuneStart(output[start]) {
+		start++
+	}
+	return truncatedOutput + output[start:]
+}
+
+// capturesLogs reports whether the logs of a finished run of a schedule are captured.
+func capturesLogs(schedule schedulingapiv1.Schedule, phase schedulingapiv1.RunPhase) bool {
+	if schedule.Output == nil || schedule.Output.LogLines == 0 {
+		return false
+	}
+	return phase == schedulingapiv1.RunPhaseFailed || (phase == schedulingapiv1.RunPhaseSucceeded && schedule.Output.LogsOnSuccess)
+}
+
+// captureLogs returns the end of the log of the job container of a pod, filtered for
+// its schedule. Logs that cannot be read, for instance because the pod is gone, are
+// not captured.
+func (r *SchedulerReconciler) captureLogs(ctx context.Context, schedule schedulingapiv1.Schedule, pod *corev1.Pod) string {
+	if r.PodLogs == nil {
+		return ""
+	}
+	filter := newOutputFilter(schedule)
+	if filter.err != nil {
+		log.FromContext(ctx).Error(filter.err, "Not capturing logs")
+		return ""
+	}
+	// The limit applies from the first line returned, so it only guards against huge lines
+	logs, err := r.PodLogs.Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{
+		Container:  cronjobbuilder.JobContainerName,
+		TailLines:  ptr.To(int64(schedule.Output.LogLines)),
+		LimitBytes: ptr.To(int64(maxLogBytes)),
+	}).DoRaw(ctx)
+	if err != nil {
+		log.FromContext(ctx).Info("Failed to capture logs", "pod", pod.Name, "error", err.Error())
+		return ""
+	}
+	return filter.apply(string(logs))
+}
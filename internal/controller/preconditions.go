@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cronjobbuilder"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/httpclient"
+)
+
+const (
+	// preconditionTimeout bounds the requests of HTTP preconditions.
+	preconditionTimeout = 10 * time.Second
+
+	// completionPreconditionFailed is the CompletionReason of a one-off schedule whose
+	// run was skipped.
+	completionPreconditionFailed = "PreconditionFailed"
+)
+
+// preconditionClient checks HTTP preconditions, without following redirects.
+var preconditionClient = httpclient.New(preconditionTimeout)
+
+// checkPreconditions returns why a run of a schedule may not start, or an empty string
+// when all of its preconditions are met.
+func (r *SchedulerReconciler) checkPreconditions(ctx context.Context, scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule) (string, error) {
+	for _, p := range schedule.Preconditions {
+		met, err := r.preconditionMet(ctx, scheduler.Namespace, p)
+		if err != nil {
+			return "", err
+		}
+		if !met {
+			return cronjobbuilder.PreconditionReason(p), nil
+		}
+	}
+	return "", nil
+}
+
+// preconditionMet checks a precondition. Missing objects and unreachable endpoints do
+// not meet it, while failing to read an object is an error.
+func (r *SchedulerReconciler) preconditionMet(ctx context.Context, namespace string, p schedulingapiv1.Precondition) (bool, error) {
+	switch {
+	case p.ConfigMap != nil:
+		// ConfigMaps and Deployments are read directly, rather than cached for the whole cluster
+		var configMap corev1.ConfigMap
+		if err := r.reader().Get(ctx, types.NamespacedName{Namespace: namespace, Name: p.ConfigMap.Name}, &configMap); err != nil {
+			if apierrors.IsNotFound(err) {
+				return false, nil
+			}
+			return false, fmt.Errorf("failed to get ConfigMap %s of precondition: %w", p.ConfigMap.Name, err)
+		}
+		value, ok := configMap.Data[p.ConfigMap.Key]
+		return ok && value == p.ConfigMap.Value, nil
+
+	case p.Deployment != nil:
+		var deployment appsv1.Deployment
+		if err := r.reader().Get(ctx, types.NamespacedName{Namespace: namespace, Name: p.Deployment.Name}, &deployment); err != nil {
+			if apierrors.IsNotFound(err) {
+				return false, nil
+			}
+			return false, fmt.Errorf("failed to get Deployment %s of precondition: %w", p.Deployment.Name, err)
+		}
+		for _, c := range deployment.Status.Conditions {
+			if c.Type == appsv1.DeploymentAvailable {
+				return c.Status == corev1.ConditionTrue, nil
+			}
+		}
+		return false, nil
+
+	case p.HTTP != nil:
+		ctx, cancel := context.WithTimeout(ctx, preconditionTimeout)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.HTTP.URL, nil)
+		if err != nil {
+			return false, nil
+		}
+		resp, err := preconditionClient.Do(req)
+		if err != nil {
+			log.FromContext(ctx).Info("HTTP precondition unreachable", "url", p.HTTP.URL, "error", err.Error())
+			return false, nil
+		}
+		defer func() { _ = resp.Body.Close() }()
+		return resp.StatusCode == http.StatusOK, nil
+	}
+	return false, nil
+}
+
+// skipRun records a run of a schedule that did not start because a precondition was
+// not met, under the name its Job would have had.
+func skipRun(ctx context.Context, status *schedulingapiv1.ScheduleStatus, scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule, slot time.Time, reason string) {
+	log.FromContext(ctx).Info("Skipping run", "schedule", schedule.Name, "scheduledTime", slot, "reason", reason)
+	status.Runs = append([]schedulingapiv1.RunStatus{{
+		JobName:       cronjobbuilder.JobName(scheduler, schedule, slot),
+		Phase:         schedulingapiv1.RunPhaseSkipped,
+		ScheduledTime: &metav1.Time{Time: slot},
+		Reason:        reason,
+	}}, status.Runs...)
+}
+
+// preconditionSkip reports whether a Job ended because its preconditions init container
+// found a precondition that was not met, and why.
+func preconditionSkip(job *batchv1.Job, pods []corev1.Pod) (string, bool) {
+	const notMet = "preconditions not met"
+	for _, pod := range pods {
+		for _, cs := range pod.Status.InitContainerStatuses {
+			terminated := cs.State.Terminated
+			if cs.Name != cronjobbuilder.PreconditionsContainerName || terminated == nil || terminated.ExitCode != cronjobbuilder.PreconditionSkipExitCode {
+				continue
+			}
+			if reason := strings.TrimSpace(terminated.Message); reason != "" {
+				return reason, true
+			}
+			return notMet, true
+		}
+	}
+	// The pods may be gone, but the failure policy rule that ended the Job names the container
+	for _, c := range job.Status.Conditions {
+		if c.Type == batchv1.JobFailed && c.Status == corev1.ConditionTrue && c.Reason == batchv1.JobReasonPodFailurePolicy &&
+			strings.Contains(c.Message, "Container "+cronjobbuilder.PreconditionsContainerName+" ") {
+			return notMet, true
+		}
+	}
+	return "", false
+}
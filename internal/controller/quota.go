@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+)
+
+// quotaApplies reports whether a SchedulerQuota exists in a namespace.
+func (r *SchedulerReconciler) quotaApplies(ctx context.Context, namespace string) (bool, error) {
+	var quotas schedulingapiv1.SchedulerQuotaList
+	if err := r.List(ctx, &quotas, client.InNamespace(namespace)); err != nil {
+		return false, fmt.Errorf("failed to list SchedulerQuotas: %w", err)
+	}
+	return len(quotas.Items) > 0, nil
+}
+
+// queueForQuota makes a Job start suspended and queued when a SchedulerQuota applies to
+// its namespace, for the SchedulerQuota controller to release it.
+func (r *SchedulerReconciler) queueForQuota(ctx context.Context, job *batchv1.Job) error {
+	applies, err := r.quotaApplies(ctx, job.Namespace)
+	if err != nil || !applies {
+		return err
+	}
+	queue(&job.ObjectMeta, &job.Spec)
+	return nil
+}
+
+// queue marks a Job, or the template of the Jobs of a CronJob, as queued.
+func queue(meta *metav1.ObjectMeta, spec *batchv1.JobSpec) {
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	meta.Annotations[schedulingapiv1.QueuedAnnotation] = "true"
+	spec.Suspend = ptr.To(true)
+}
+
+// quotaToSchedulers maps a SchedulerQuota to the Schedulers of its namespace, whose
+// CronJobs queue their Jobs while it exists.
+func (r *SchedulerReconciler) quotaToSchedulers(ctx context.Context, obj client.Object) []reconcile.Request {
+	var schedulers schedulingapiv1.SchedulerList
+	if err := r.List(ctx, &schedulers, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list Schedulers for SchedulerQuota", "quota", obj.GetName())
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, scheduler := range schedulers.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&scheduler)})
+	}
+	return requests
+}
@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cronjobbuilder"
+)
+
+// maxOutputs is the number of outputs kept from a termination message.
+const maxOutputs = 50
+
+// parseOutputs returns the outputs a job wrote to its termination message as a JSON
+// object, redacted like the rest of its output, or nil when the message is not one.
+func parseOutputs(message string, filter outputFilter) map[string]string {
+	message = strings.TrimSpace(message)
+	if filter.err != nil || !strings.HasPrefix(message, "{") {
+		return nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(message), &fields); err != nil || len(fields) == 0 {
+		return nil
+	}
+
+	outputs := map[string]string{}
+	for key, raw := range fields {
+		if len(outputs) == maxOutputs {
+			break
+		}
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			value = string(raw)
+		}
+		outputs[key] = filter.apply(value)
+	}
+	return outputs
+}
+
+// runOutputs returns the outputs available to the templates of a run for a logical
+// time: for each schedule of the Scheduler, those of its run for the same time when it
+// succeeded, such as the runs a dependent schedule waited for, and otherwise those of
+// its latest run that succeeded.
+func (r *SchedulerReconciler) runOutputs(ctx context.Context, scheduler *schedulingapiv1.Scheduler, slot time.Time) (cronjobbuilder.Outputs, error) {
+	var runs schedulingapiv1.ScheduleRunList
+	if err := r.List(ctx, &runs, client.InNamespace(scheduler.Namespace), client.MatchingLabels{"scheduler": scheduler.Name}); err != nil {
+		return nil, fmt.Errorf("failed to list ScheduleRuns for outputs: %w", err)
+	}
+
+	outputs := cronjobbuilder.Outputs{}
+	chosen := map[string]time.Time{}
+	for i := range runs.Items {
+		run := &runs.Items[i]
+		if run.Status.Phase != schedulingapiv1.RunPhaseSucceeded || len(run.Status.Outputs) == 0 {
+			continue
+		}
+		at := run.CreationTimestamp.Time
+		if run.Spec.ScheduledTime != nil {
+			at = run.Spec.ScheduledTime.Time
+		}
+		name := run.Spec.ScheduleName
+		if current, ok := chosen[name]; ok && (current.Equal(slot) || (!at.Equal(slot) && !at.After(current))) {
+			continue
+		}
+		chosen[name] = at
+		outputs[name] = run.Status.Outputs
+	}
+	return outputs, nil
+}
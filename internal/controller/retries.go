@@ -0,0 +1,222 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cronjobbuilder"
+)
+
+const (
+	// defaultMaxAttempts, defaultRetryDelay, defaultRetryMultiplier and
+	// defaultMaxRetryDelay apply to the fields of a RetryPolicy that are not set.
+	defaultMaxAttempts     = 3
+	defaultRetryDelay      = 10 * time.Second
+	defaultRetryMultiplier = 2
+	defaultMaxRetryDelay   = time.Hour
+)
+
+// runAttempts indexes the Job of the latest attempt of every run by schedule and run ID.
+type runAttempts map[string]map[string]*batchv1.Job
+
+// reconcileRetries starts the next attempt of the failed runs of schedules with a
+// RetryPolicy once their delay has passed, and returns when the next one is due. Runs
+// are not retried while their schedule is suspended or blacked out.
+func (r *SchedulerReconciler) reconcileRetries(ctx context.Context, scheduler *schedulingapiv1.Scheduler, blackouts blackoutState) (time.Duration, []error) {
+	log := log.FromContext(ctx)
+	var jobs batchv1.JobList
+	if err := r.List(ctx, &jobs, client.InNamespace(scheduler.Namespace), client.MatchingLabels{"scheduler": scheduler.Name}); err != nil {
+		return 0, []error{fmt.Errorf("failed to list Jobs for retries: %w", err)}
+	}
+	podsByJob, err := r.listPodsByJob(ctx, scheduler)
+	if err != nil {
+		return 0, []error{err}
+	}
+	latest := indexRunAttempts(jobs.Items)
+	steps := indexStepJobs(jobs.Items)
+
+	now := r.now()
+	var requeueAfter time.Duration
+	var reconcileErrors []error
+	for _, schedule := range scheduler.Spec.Schedules {
+		if schedule.RetryPolicy == nil {
+			continue
+		}
+		if !blackouts.isActive(schedule.Name) && !suspended(scheduler, schedule) {
+			for _, job := range latest[schedule.Name] {
+				next, ok := nextRetry(schedule, job, podsByJob, steps)
+				if !ok {
+					continue
+				}
+				if next.After(now) {
+					requeueAfter = shortestRequeue(requeueAfter, next.Sub(now))
+					continue
+				}
+				if waiting, err := r.waitForLock(ctx, scheduler, schedule); err != nil || waiting {
+					if err != nil {
+						reconcileErrors = append(reconcileErrors, err)
+					}
+					continue
+				}
+				if err := r.retryRun(ctx, scheduler, schedule, job); err != nil {
+					log.Error(err, "Failed to retry run", "schedule", schedule.Name, "run", cronjobbuilder.RunID(job))
+					reconcileErrors = append(reconcileErrors, err)
+				}
+			}
+		}
+
+		// The Jobs of CronJob schedules are cleaned up by their CronJob, but not their retries
+		if !firedByController(schedule) {
+			if err := r.pruneJobHistory(ctx, scheduler, schedule); err != nil {
+				reconcileErrors = append(reconcileErrors, err)
+			}
+		}
+	}
+	return requeueAfter, reconcileErrors
+}
+
+// retryRun creates the Job of the next attempt of the run of a failed Job.
+func (r *SchedulerReconciler) retryRun(ctx context.Context, scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule, failed *batchv1.Job) error {
+	run := cronjobbuilder.RunInfo{
+		ScheduledTime: failed.CreationTimestamp.Time,
+		Trigger:       cronjobbuilder.TriggerCron,
+		Attempt:       cronjobbuilder.JobAttempt(failed) + 1,
+	}
+	if scheduledTime := jobScheduledTime(failed); scheduledTime != nil {
+		run.ScheduledTime = scheduledTime.Time
+	}
+	if trigger, ok := failed.Labels[schedulingapiv1.TriggerLabel]; ok {
+		run.Trigger = trigger
+	}
+
+	outputs, err := r.runOutputs(ctx, scheduler, run.ScheduledTime)
+	if err != nil {
+		return err
+	}
+	run.Outputs = outputs
+
+	job, err := cronjobbuilder.BuildRetryJob(scheduler, schedule, cronjobbuilder.RunID(failed), run)
+	if err != nil {
+		return err
+	}
+	if err := ctrl.SetControllerReference(scheduler, job, r.Scheme); err != nil {
+		return fmt.Errorf("failed to set owner reference for Job %s: %w", job.Name, err)
+	}
+
+	if err := r.queueForQuota(ctx, job); err != nil {
+		return err
+	}
+
+	log.FromContext(ctx).Info("Creating retry Job", "name", job.Name, "run", cronjobbuilder.RunID(failed), "attempt", run.Attempt)
+	if err := r.createJob(ctx, job); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create Job %s: %w", job.Name, err)
+	}
+	return nil
+}
+
+// nextRetry returns when the next attempt of the run of a Job starts, if the Job is the
+// latest attempt of a run that failed and is to be retried.
+func nextRetry(schedule schedulingapiv1.Schedule, job *batchv1.Job, podsByJob map[string][]corev1.Pod, steps stepIndex) (time.Time, bool) {
+	policy := schedule.RetryPolicy
+	if policy == nil {
+		return time.Time{}, false
+	}
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	attempt := cronjobbuilder.JobAttempt(job)
+	if attempt >= maxAttempts {
+		return time.Time{}, false
+	}
+
+	run := runStatus(job, podsByJob[job.Name])
+	applySteps(&run, job, podsByJob, steps)
+	if run.Phase != schedulingapiv1.RunPhaseFailed || run.CompletionTime == nil {
+		return time.Time{}, false
+	}
+
+	if len(policy.RetryOn) > 0 {
+		pods := slices.Clone(podsByJob[job.Name])
+		for _, stepJob := range steps[job.Name] {
+			pods = append(pods, podsByJob[stepJob.Name]...)
+		}
+		if !slices.ContainsFunc(exitCodes(pods), func(code int32) bool { return slices.Contains(policy.RetryOn, code) }) {
+			return time.Time{}, false
+		}
+	}
+	return run.CompletionTime.Add(retryDelay(policy, attempt)), true
+}
+
+// retryDelay returns how long after the given attempt failed the next one starts.
+func retryDelay(policy *schedulingapiv1.RetryPolicy, attempt int32) time.Duration {
+	delay, maxDelay := defaultRetryDelay, defaultMaxRetryDelay
+	if policy.InitialDelay != nil {
+		delay = policy.InitialDelay.Duration
+	}
+	if policy.MaxDelay != nil {
+		maxDelay = policy.MaxDelay.Duration
+	}
+	multiplier := time.Duration(policy.Multiplier)
+	if multiplier == 0 {
+		multiplier = defaultRetryMultiplier
+	}
+	for i := int32(1); i < attempt && delay < maxDelay; i++ {
+		// Stop before the delay overflows
+		if delay > maxDelay/multiplier {
+			return maxDelay
+		}
+		delay *= multiplier
+	}
+	return min(delay, maxDelay)
+}
+
+// exitCodes returns the exit codes of the containers that failed in the pods of a run.
+func exitCodes(pods []corev1.Pod) []int32 {
+	var codes []int32
+	for _, pod := range pods {
+		for _, cs := range slices.Concat(pod.Status.InitContainerStatuses, pod.Status.ContainerStatuses) {
+			for _, terminated := range []*corev1.ContainerStateTerminated{cs.State.Terminated, cs.LastTerminationState.Terminated} {
+				if terminated != nil && terminated.ExitCode != 0 {
+					codes = append(codes, terminated.ExitCode)
+				}
+			}
+		}
+	}
+	return codes
+}
+
+// indexRunAttempts indexes the Jobs of the latest attempt of every run.
+func indexRunAttempts(jobs []batchv1.Job) runAttempts {
+	runs := runAttempts{}
+	for i := range jobs {
+		job := &jobs[i]
+		if isStepJob(job) {
+			continue
+		}
+		name := job.Labels["schedule"]
+		if runs[name] == nil {
+			runs[name] = map[string]*batchv1.Job{}
+		}
+		runID := cronjobbuilder.RunID(job)
+		if existing := runs[name][runID]; existing == nil || cronjobbuilder.JobAttempt(existing) < cronjobbuilder.JobAttempt(job) {
+			runs[name][runID] = job
+		}
+	}
+	return runs
+}
+
+// isLatestAttempt reports whether a Job executes the latest attempt of its run.
+func (a runAttempts) isLatestAttempt(job *batchv1.Job) bool {
+	return a[job.Labels["schedule"]][cronjobbuilder.RunID(job)] == job
+}
@@ -0,0 +1,180 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cronjobbuilder"
+)
+
+// runHistoryLimit is the number of most recent runs reported per schedule.
+const runHistoryLimit = 5
+
+// recordRuns rebuilds the run history of every schedule from its Jobs and their pods.
+// Retried runs are reported with their latest attempt, and runs skipped by the controller,
+// which have no Job, are kept until newer runs push them out.
+func (r *SchedulerReconciler) recordRuns(ctx context.Context, scheduler *schedulingapiv1.Scheduler, jobs []batchv1.Job) error {
+	podsByJob, err := r.listPodsByJob(ctx, scheduler)
+	if err != nil {
+		return err
+	}
+	schedules := map[string]schedulingapiv1.Schedule{}
+	for _, schedule := range scheduler.Spec.Schedules {
+		schedules[schedule.Name] = schedule
+	}
+
+	type sortableRun struct {
+		run schedulingapiv1.RunStatus
+		at  time.Time
+	}
+	steps := indexStepJobs(jobs)
+	attempts := indexRunAttempts(jobs)
+	runsBySchedule := map[string][]sortableRun{}
+	jobNames := map[string]bool{}
+	for i := range jobs {
+		jobNames[jobs[i].Name] = true
+	}
+	for _, status := range scheduler.Status.Schedules {
+		for _, run := range status.Runs {
+			if run.Phase == schedulingapiv1.RunPhaseSkipped && run.ScheduledTime != nil && !jobNames[run.JobName] {
+				runsBySchedule[status.Name] = append(runsBySchedule[status.Name], sortableRun{run, run.ScheduledTime.Time})
+			}
+		}
+	}
+	for i := range jobs {
+		job := &jobs[i]
+		if isStepJob(job) || !attempts.isLatestAttempt(job) {
+			continue
+		}
+		run := runStatus(job, podsByJob[job.Name])
+		applySteps(&run, job, podsByJob, steps)
+		run.Attempt = cronjobbuilder.JobAttempt(job)
+		if next, ok := nextRetry(schedules[job.Labels["schedule"]], job, podsByJob, steps); ok {
+			run.NextRetryTime = &metav1.Time{Time: next}
+		}
+		at := job.CreationTimestamp.Time
+		if run.ScheduledTime != nil {
+			at = run.ScheduledTime.Time
+		}
+		runsBySchedule[job.Labels["schedule"]] = append(runsBySchedule[job.Labels["schedule"]], sortableRun{run, at})
+	}
+
+	for _, schedule := range scheduler.Spec.Schedules {
+		sortable := runsBySchedule[schedule.Name]
+		sort.SliceStable(sortable, func(i, j int) bool {
+			if !sortable[i].at.Equal(sortable[j].at) {
+				return sortable[i].at.After(sortable[j].at)
+			}
+			return sortable[i].run.JobName > sortable[j].run.JobName
+		})
+
+		var runs []schedulingapiv1.RunStatus
+		for i := 0; i < len(sortable) && i < runHistoryLimit; i++ {
+			runs = append(runs, sortable[i].run)
+		}
+		scheduleStatus(&scheduler.Status, schedule.Name).Runs = runs
+	}
+
+	recordChains(scheduler, jobs, podsByJob, steps)
+	return nil
+}
+
+// listPodsByJob lists the pods of the Jobs of a Scheduler, indexed by Job name.
+func (r *SchedulerReconciler) listPodsByJob(ctx context.Context, scheduler *schedulingapiv1.Scheduler) (map[string][]corev1.Pod, error) {
+	var pods corev1.PodList
+	if err := r.List(ctx, &pods, client.InNamespace(scheduler.Namespace), client.MatchingLabels{"scheduler": scheduler.Name}); err != nil {
+		return nil, fmt.Errorf("failed to list pods for run status: %w", err)
+	}
+	podsByJob := map[string][]corev1.Pod{}
+	for _, pod := range pods.Items {
+		jobName := pod.Labels[batchv1.JobNameLabel]
+		podsByJob[jobName] = append(podsByJob[jobName], pod)
+	}
+	return podsByJob, nil
+}
+
+// runStatus describes the run executed by a Job.
+func runStatus(job *batchv1.Job, pods []corev1.Pod) schedulingapiv1.RunStatus {
+	run := schedulingapiv1.RunStatus{
+		JobName:       job.Name,
+		Phase:         schedulingapiv1.RunPhasePending,
+		Trigger:       job.Labels[schedulingapiv1.TriggerLabel],
+		ScheduledTime: jobScheduledTime(job),
+	}
+
+	if started := jobContainerStartTime(pods); started != nil {
+		run.Phase = schedulingapiv1.RunPhaseRunning
+		run.StartTime = started
+		if run.ScheduledTime != nil {
+			run.StartOffset = &metav1.Duration{Duration: started.Sub(run.ScheduledTime.Time)}
+		}
+	}
+
+	for _, c := range job.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobComplete:
+			run.Phase = schedulingapiv1.RunPhaseSucceeded
+			run.CompletionTime = &c.LastTransitionTime
+		case batchv1.JobFailed:
+			run.Phase = schedulingapiv1.RunPhaseFailed
+			run.CompletionTime = &c.LastTransitionTime
+		}
+	}
+	if job.Status.CompletionTime != nil {
+		run.CompletionTime = job.Status.CompletionTime
+	}
+	if run.Phase == schedulingapiv1.RunPhaseFailed {
+		if reason, ok := preconditionSkip(job, pods); ok {
+			run.Phase = schedulingapiv1.RunPhaseSkipped
+			run.Reason = reason
+		}
+	}
+	return run
+}
+
+// jobScheduledTime returns the logical time a Job was created for, as recorded by the
+// Native engine or by the CronJob controller.
+func jobScheduledTime(job *batchv1.Job) *metav1.Time {
+	for _, annotation := range []string{schedulingapiv1.ScheduledTimeAnnotation, batchv1.CronJobScheduledTimestampAnnotation} {
+		if value, ok := job.Annotations[annotation]; ok {
+			if t, err := time.Parse(time.RFC3339, value); err == nil {
+				return &metav1.Time{Time: t}
+			}
+		}
+	}
+	return nil
+}
+
+// jobContainerStartTime returns when the job container first started in any of the pods.
+func jobContainerStartTime(pods []corev1.Pod) *metav1.Time {
+	var earliest *metav1.Time
+	for _, pod := range pods {
+		for _, cs := range pod.Status.ContainerStatuses {
+			if cs.Name != cronjobbuilder.JobContainerName {
+				continue
+			}
+			var started *metav1.Time
+			switch {
+			case cs.State.Running != nil:
+				started = &cs.State.Running.StartedAt
+			case cs.State.Terminated != nil:
+				started = &cs.State.Terminated.StartedAt
+			}
+			if started != nil && !started.IsZero() && (earliest == nil || started.Before(earliest)) {
+				earliest = started
+			}
+		}
+	}
+	return earliest
+}
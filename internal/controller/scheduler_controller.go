@@ -2,6 +2,7 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -11,35 +12,97 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	"k8s.io/utils/ptr"
 	"sort"
+	"sync"
 	"time"
 
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cronexpr"
 	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cronjobbuilder"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/notify"
 )
 
 // SchedulerReconciler reconciles a Scheduler object
 type SchedulerReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// Clock is used by the Native engine to compute fire times. Defaults to the real clock.
+	Clock clock.PassiveClock
+
+	// StaggerWindow is the controller-wide stagger window, used for Schedulers that do not
+	// set their own. Zero disables staggering.
+	StaggerWindow time.Duration
+
+	// APIReader reads the Jobs of lock groups without going through the cache. Defaults
+	// to the client.
+	APIReader client.Reader
+
+	// Recorder records events on Schedulers, such as their circuits opening. Events are
+	// not recorded without it.
+	Recorder record.EventRecorder
+
+	// PodLogs reads the logs of the pods of finished runs, for schedules that capture
+	// them. Logs are not captured without it.
+	PodLogs corev1client.PodsGetter
+
+	// CloudEvents publishes the changes of schedules and runs. Events are not published
+	// without it.
+	CloudEvents CloudEventEmitter
+
+	// Notifier delivers notifications in the background. SetupWithManager provides one;
+	// notifications stay pending without it.
+	Notifier NotificationDispatcher
+
+	// deliveries holds the notifications handed to the Notifier, until their outcome is
+	// collected by a reconcile of their Scheduler.
+	deliveries sync.Map
+
+	// wakeUps enqueues the Schedulers whose notifications were delivered.
+	wakeUps chan event.GenericEvent
+
+	// knownSchedules holds the schedule names of every Scheduler reconciled, to report
+	// their deletion along with the Scheduler.
+	knownSchedules sync.Map
+}
+
+// now returns the current time in UTC, the time zone cron expressions are evaluated in.
+func (r *SchedulerReconciler) now() time.Time {
+	if r.Clock == nil {
+		return time.Now().UTC()
+	}
+	return r.Clock.Now().UTC()
 }
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
 func (r *SchedulerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
+	ctx, span := startSpan(ctx, "Reconcile", namespaceAttribute.String(req.Namespace), schedulerAttribute.String(req.Name))
+	defer span.End()
 
 	var scheduler schedulingapiv1.Scheduler
 	if err := r.Get(ctx, req.NamespacedName, &scheduler); err != nil {
 		if apierrors.IsNotFound(err) {
 			log.Info("Scheduler resource not found. Ignoring since object must be deleted")
+			r.emitSchedulerDeleted(ctx, req.NamespacedName)
+			r.forgetDeliveries(req.NamespacedName, nil)
 			return ctrl.Result{}, nil
 		}
 		log.Error(err, "Failed to get Scheduler")
+		recordError(span, err)
 		return ctrl.Result{}, err
 	}
 
@@ -49,69 +112,67 @@ func (r *SchedulerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	if originalStatus.Conditions == nil {
 		originalStatus.Conditions = []metav1.Condition{}
 	}
+	scheduleEvents := r.scheduleEvents(ctx, &scheduler)
 
 	// --- 2. Perform reconciliation of CronJobs (create/update/delete) ---
-	desiredCronJobsMap := map[string]struct{}{}
 	var reconcileErrors []error // Collect errors during CronJob reconciliation
-	var latestScheduleTime *metav1.Time
+	var requeueAfter time.Duration
 
-	for _, schedule := range scheduler.Spec.Schedules {
-		cronJob := cronjobbuilder.BuildCronJob(&scheduler, schedule)
+	now := r.now()
+	blackouts, blackoutErrors := evaluateBlackouts(&scheduler, now)
+	for _, err := range blackoutErrors {
+		log.Error(err, "Ignoring blackout window")
+	}
+	reconcileErrors = append(reconcileErrors, blackoutErrors...)
+	calendars := r.resolveCalendars(ctx, &scheduler)
 
-		if err := ctrl.SetControllerReference(&scheduler, cronJob, r.Scheme); err != nil {
-			log.Error(err, "Failed to set owner reference for CronJob", "name", cronJob.Name)
+	paused := scheduler.Annotations[schedulingapiv1.ReconcileAnnotation] == schedulingapiv1.ReconcilePaused
+	if paused {
+		// Leave the children alone so they can be edited by hand, but keep reporting status.
+		log.Info("Reconciliation paused by annotation, skipping CronJob changes")
+	} else {
+		circuitAfter, err := r.reconcileCircuits(ctx, &scheduler)
+		if err != nil {
+			log.Error(err, "Failed to evaluate circuits")
 			reconcileErrors = append(reconcileErrors, err)
-			continue // Continue to next schedule, try to reconcile others
 		}
 
-		desiredCronJobsMap[cronJob.Name] = struct{}{}
+		reconcileErrors = append(reconcileErrors, r.reconcileCronJobs(ctx, &scheduler, blackouts, calendars)...)
 
-		var existing batchv1.CronJob
-		err := r.Get(ctx, types.NamespacedName{Name: cronJob.Name, Namespace: cronJob.Namespace}, &existing)
-		if err != nil && apierrors.IsNotFound(err) {
-			log.Info("Creating CronJob", "name", cronJob.Name)
-			if err := r.Create(ctx, cronJob); err != nil {
-				log.Error(err, "Failed to create CronJob", "name", cronJob.Name)
-				reconcileErrors = append(reconcileErrors, err)
-			}
-		} else if err != nil {
-			log.Error(err, "Failed to get CronJob", "name", cronJob.Name)
+		var nativeErrors []error
+		requeueAfter, nativeErrors = r.reconcileNativeSchedules(ctx, &scheduler, blackouts, calendars)
+		reconcileErrors = append(reconcileErrors, nativeErrors...)
+		if circuitAfter > 0 {
+			requeueAfter = shortestRequeue(requeueAfter, circuitAfter)
+		}
+
+		if err := r.reconcileTrigger(ctx, &scheduler); err != nil {
+			log.Error(err, "Failed to trigger run")
 			reconcileErrors = append(reconcileErrors, err)
-		} else {
-			// Update existing CronJob if spec changed
-			if !cronJobSpecEqual(&existing.Spec, &cronJob.Spec) {
-				existing.Spec = cronJob.Spec // Update spec
-				log.Info("Updating CronJob", "name", cronJob.Name)
-				if err := r.Update(ctx, &existing); err != nil {
-					log.Error(err, "Failed to update CronJob", "name", cronJob.Name)
-					reconcileErrors = append(reconcileErrors, err)
-				}
-			}
+		}
 
-			// Update latestScheduleTime
-			if existing.Status.LastScheduleTime != nil {
-				if latestScheduleTime == nil || existing.Status.LastScheduleTime.After(latestScheduleTime.Time) {
-					latestScheduleTime = existing.Status.LastScheduleTime
-				}
-			}
+		backfillErrors := r.reconcileBackfills(ctx, &scheduler, calendars)
+		reconcileErrors = append(reconcileErrors, backfillErrors...)
+
+		reconcileErrors = append(reconcileErrors, r.reconcileSteps(ctx, &scheduler)...)
+
+		retryAfter, retryErrors := r.reconcileRetries(ctx, &scheduler, blackouts)
+		reconcileErrors = append(reconcileErrors, retryErrors...)
+		if retryAfter > 0 {
+			requeueAfter = shortestRequeue(requeueAfter, retryAfter)
 		}
+		reconcileErrors = append(reconcileErrors, r.reconcileDependencies(ctx, &scheduler, blackouts)...)
 	}
 
-	// Cleanup old CronJobs that are no longer desired
-	if err := r.cleanupCronJobs(ctx, &scheduler, desiredCronJobsMap); err != nil {
-		log.Error(err, "Failed to cleanup old CronJobs")
-		reconcileErrors = append(reconcileErrors, err)
-	}
+	lockWaiting, lockErrors := r.reconcileLocks(ctx, &scheduler)
+	reconcileErrors = append(reconcileErrors, lockErrors...)
 
 	// --- 3. Update Status Fields ---
-	newStatus := scheduler.Status // Reference to the actual status in scheduler object
+	newStatus := &scheduler.Status // Reference to the actual status in scheduler object
 
 	// Set ObservedGeneration
 	newStatus.ObservedGeneration = scheduler.Generation
 
-	// Set LastScheduleTime
-	newStatus.LastScheduleTime = latestScheduleTime
-
 	// Set Active Jobs
 	var activeJobRefs []corev1.ObjectReference
 	var activeJobs batchv1.JobList
@@ -137,6 +198,35 @@ func (r *SchedulerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 			return activeJobRefs[i].Name < activeJobRefs[j].Name
 		})
 		newStatus.Active = activeJobRefs
+
+		if err := r.recordRuns(ctx, &scheduler, activeJobs.Items); err != nil {
+			log.Error(err, "Failed to record run status")
+			reconcileErrors = append(reconcileErrors, err)
+		}
+		if err := r.recordScheduleRuns(ctx, &scheduler, activeJobs.Items); err != nil {
+			log.Error(err, "Failed to record ScheduleRuns")
+			reconcileErrors = append(reconcileErrors, err)
+		}
+		notifyAfter, err := r.reconcileNotifications(ctx, &scheduler, activeJobs.Items, blackouts, calendars)
+		if err != nil {
+			log.Error(err, "Failed to notify runs")
+			reconcileErrors = append(reconcileErrors, err)
+		}
+		if notifyAfter > 0 {
+			requeueAfter = shortestRequeue(requeueAfter, notifyAfter)
+		}
+	}
+
+	// Drop the status of removed schedules and finished one-off runs, and set
+	// LastScheduleTime from the remaining ones
+	pruneScheduleStatuses(newStatus, scheduler.Spec.Schedules)
+	newStatus.LastScheduleTime = nil
+	for _, scheduleStatus := range newStatus.Schedules {
+		if scheduleStatus.LastFireTime != nil {
+			if newStatus.LastScheduleTime == nil || scheduleStatus.LastFireTime.After(newStatus.LastScheduleTime.Time) {
+				newStatus.LastScheduleTime = scheduleStatus.LastFireTime
+			}
+		}
 	}
 
 	readyCondition := metav1.Condition{
@@ -154,22 +244,328 @@ func (r *SchedulerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 	meta.SetStatusCondition(&newStatus.Conditions, readyCondition)
 
+	pausedCondition := metav1.Condition{
+		Type:    "Paused",
+		Status:  metav1.ConditionFalse,
+		Reason:  "ReconcileActive",
+		Message: "The controller is reconciling the CronJobs of this Scheduler.",
+	}
+	if paused {
+		pausedCondition.Status = metav1.ConditionTrue
+		pausedCondition.Reason = "PausedByAnnotation"
+		pausedCondition.Message = fmt.Sprintf("Reconciliation paused by the %s=%s annotation.",
+			schedulingapiv1.ReconcileAnnotation, schedulingapiv1.ReconcilePaused)
+	}
+	meta.SetStatusCondition(&newStatus.Conditions, pausedCondition)
+
+	blackoutCondition := metav1.Condition{
+		Type:    "Blackout",
+		Status:  metav1.ConditionFalse,
+		Reason:  "NoActiveWindow",
+		Message: "No schedule is in a blackout window.",
+	}
+	if len(blackouts.active) > 0 {
+		blackoutCondition.Status = metav1.ConditionTrue
+		blackoutCondition.Reason = "WindowActive"
+		blackoutCondition.Message = blackouts.describe()
+	}
+	meta.SetStatusCondition(&newStatus.Conditions, blackoutCondition)
+	meta.SetStatusCondition(&newStatus.Conditions, circuitCondition(&scheduler))
+
 	// --- 4. Update the Scheduler's Status subresource if it has changed ---
-	if !equality.Semantic.DeepEqual(newStatus, originalStatus) {
+	if !equality.Semantic.DeepEqual(*newStatus, *originalStatus) {
 		log.Info("Updating Scheduler status")
-		if err := r.Status().Update(ctx, &scheduler); err != nil {
+		if err := traced(ctx, "Update status", func(ctx context.Context) error { return r.Status().Update(ctx, &scheduler) }); err != nil {
 			log.Error(err, "Failed to update Scheduler status")
+			recordError(span, err)
 			return ctrl.Result{}, err
 		}
 	}
+	r.publish(scheduleEvents)
 
 	// --- 5. Determine reconcile result ---
 	if len(reconcileErrors) > 0 {
+		recordError(span, reconcileErrors[0])
 		// If there were errors, requeue with backoff to retry
-		return ctrl.Result{RequeueAfter: 30 * time.Second}, nil // Requeue after 30 seconds
+		requeueAfter = shortestRequeue(requeueAfter, 30*time.Second) // Requeue after 30 seconds at most
+	}
+
+	// Blackout windows are enforced and lifted when they start and end
+	if !blackouts.next.IsZero() {
+		requeueAfter = shortestRequeue(requeueAfter, blackouts.next.Sub(now))
+	}
+
+	// Schedules restricted by Calendars are re-evaluated when a new day starts, which also
+	// picks up changes to imported iCalendar data
+	for _, filter := range calendars {
+		if !filter.isEmpty() {
+			requeueAfter = shortestRequeue(requeueAfter, filter.nextChange(now).Sub(now))
+		}
+	}
+
+	// StartAt and EndAt start and complete schedules when they are reached
+	if next := nextLimitChange(&scheduler, now); !next.IsZero() {
+		requeueAfter = shortestRequeue(requeueAfter, next.Sub(now))
+	}
+
+	// Resumed CronJobs get their starting deadline back as the resume recedes
+	if next := nextResumeDeadline(&scheduler, now); !next.IsZero() {
+		requeueAfter = shortestRequeue(requeueAfter, next.Sub(now))
+	}
+
+	// Runs waiting for a lock held by another Scheduler check it again periodically
+	if lockWaiting {
+		requeueAfter = shortestRequeue(requeueAfter, lockPollInterval)
+	}
+
+	// Native schedules are woken up when their next slot is due
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// reconcileCronJobs creates, updates and deletes the CronJobs owned by the Scheduler and
+// records the last schedule time they report in the schedule statuses.
+func (r *SchedulerReconciler) reconcileCronJobs(ctx context.Context, scheduler *schedulingapiv1.Scheduler, blackouts blackoutState, calendars map[string]calendarFilter) []error {
+	log := log.FromContext(ctx)
+	desiredCronJobsMap := map[string]struct{}{}
+	var reconcileErrors []error
+
+	// Jobs of CronJobs start queued while a SchedulerQuota limits the namespace
+	quota, err := r.quotaApplies(ctx, scheduler.Namespace)
+	if err != nil {
+		return []error{err}
+	}
+
+	for _, schedule := range scheduler.Spec.Schedules {
+		// Native and RunAt schedules have no CronJob, so any left over from CronJob mode is
+		// cleaned up
+		if firedByController(schedule) {
+			continue
+		}
+
+		// Keep the current CronJob, if any, while its schedule cannot be translated
+		desiredCronJobsMap[cronjobbuilder.CronJobName(scheduler, schedule)] = struct{}{}
+
+		reconcileErrors = append(reconcileErrors, r.reconcileCronJob(ctx, scheduler, schedule, blackouts, calendars[schedule.Name], quota)...)
+	}
+
+	// Cleanup old CronJobs that are no longer desired
+	if err := traced(ctx, "Cleanup CronJobs", func(ctx context.Context) error {
+		return r.cleanupCronJobs(ctx, scheduler, desiredCronJobsMap)
+	}); err != nil {
+		log.Error(err, "Failed to cleanup old CronJobs")
+		reconcileErrors = append(reconcileErrors, err)
+	}
+
+	return reconcileErrors
+}
+
+// reconcileCronJob creates or updates the CronJob of a schedule, in a span of its own.
+func (r *SchedulerReconciler) reconcileCronJob(ctx context.Context, scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule, blackouts blackoutState, filter calendarFilter, quota bool) (reconcileErrors []error) {
+	log := log.FromContext(ctx)
+	ctx, span := startScheduleSpan(ctx, schedule)
+	defer func() {
+		if len(reconcileErrors) > 0 {
+			recordError(span, reconcileErrors[0])
+		}
+		span.End()
+	}()
+
+	// The CronJob controller runs at most the latest missed slot
+	if schedule.CatchUpPolicy == schedulingapiv1.CatchUpAll {
+		err := fmt.Errorf("schedule %s: catchUpPolicy All requires mode: Native", schedule.Name)
+		log.Error(err, "Unsupported catch-up policy", "schedule", schedule.Name)
+		return []error{err}
+	}
+
+	expr, err := r.cronJobExpression(scheduler, schedule)
+	if err != nil {
+		log.Error(err, "Failed to translate cron expression", "schedule", schedule.Name)
+		return []error{err}
+	}
+	schedule.CronExpression = expr
+	status := scheduleStatus(&scheduler.Status, schedule.Name)
+	status.EffectiveSchedule = expr
+
+	// Keep the current CronJob while its Calendars cannot be loaded, rather than run it
+	// on a day it may be excluded from
+	if filter.err != nil {
+		log.Error(filter.err, "Failed to load Calendars", "schedule", schedule.Name)
+		return []error{filter.err}
+	}
+
+	now := r.now()
+	completed := updateCompletion(schedule, status, now)
+	var cronJob *batchv1.CronJob
+	err = traced(ctx, "Build CronJob", func(context.Context) error {
+		cronJob, err = cronjobbuilder.BuildCronJob(scheduler, schedule)
+		return err
+	})
+	if err != nil {
+		log.Error(err, "Failed to render CronJob", "schedule", schedule.Name)
+		return []error{err}
+	}
+	held := blackouts.isActive(schedule.Name) || !filter.allows(now) || notStarted(schedule, now) || completed || circuitOpen(scheduler, schedule)
+	if held {
+		cronJob.Spec.Suspend = ptr.To(true)
+	}
+	recordHold(status, held, now)
+	if quota {
+		queue(&cronJob.Spec.JobTemplate.ObjectMeta, &cronJob.Spec.JobTemplate.Spec)
+	}
+
+	// The CronJob controller knows nothing about Calendars, so publish the next run
+	// on an allowed day instead
+	status.NextFireTime = nil
+	if !filter.isEmpty() && !completed && !suspended(scheduler, schedule) && !blackouts.isActive(schedule.Name) {
+		if sched, err := cronexpr.Parse(expr, scheduleKey(scheduler, schedule)); err == nil {
+			if next := beforeEnd(schedule, filter.nextAllowed(sched, activeFrom(schedule, now))); !next.IsZero() {
+				status.NextFireTime = &metav1.Time{Time: next}
+			}
+		}
+	}
+
+	if err := ctrl.SetControllerReference(scheduler, cronJob, r.Scheme); err != nil {
+		log.Error(err, "Failed to set owner reference for CronJob", "name", cronJob.Name)
+		return []error{err}
+	}
+
+	var existing batchv1.CronJob
+	_ = traced(ctx, "Get CronJob", func(ctx context.Context) error {
+		err = r.Get(ctx, types.NamespacedName{Name: cronJob.Name, Namespace: cronJob.Namespace}, &existing)
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	})
+	if err == nil && status.ResumedAt != nil {
+		if last := existing.Status.LastScheduleTime; last != nil && last.After(status.ResumedAt.Time) {
+			status.ResumedAt = nil
+		}
 	}
+	cronJob.Spec.StartingDeadlineSeconds = resumeDeadline(cronJob.Spec.StartingDeadlineSeconds, status, now)
 
-	return ctrl.Result{}, nil
+	if err != nil && apierrors.IsNotFound(err) {
+		log.Info("Creating CronJob", "name", cronJob.Name)
+		if err := traced(ctx, "Create CronJob", func(ctx context.Context) error { return r.Create(ctx, cronJob) }); err != nil {
+			log.Error(err, "Failed to create CronJob", "name", cronJob.Name)
+			reconcileErrors = append(reconcileErrors, err)
+		}
+	} else if err != nil {
+		log.Error(err, "Failed to get CronJob", "name", cronJob.Name)
+		reconcileErrors = append(reconcileErrors, err)
+	} else {
+		// Count the run started since the last reconcile, if any, so that reaching
+		// MaxRuns suspends the CronJob right away
+		if last := existing.Status.LastScheduleTime; last != nil {
+			if status.LastFireTime == nil || last.After(status.LastFireTime.Time) {
+				status.RunCount++
+			}
+			status.LastFireTime = last
+		}
+		if updateCompletion(schedule, status, now) {
+			cronJob.Spec.Suspend = ptr.To(true)
+			recordHold(status, true, now)
+		}
+
+		// Update existing CronJob if spec changed
+		if !cronJobSpecEqual(&existing.Spec, &cronJob.Spec) {
+			existing.Spec = cronJob.Spec // Update spec
+			log.Info("Updating CronJob", "name", cronJob.Name)
+			if err := traced(ctx, "Update CronJob", func(ctx context.Context) error { return r.Update(ctx, &existing) }); err != nil {
+				log.Error(err, "Failed to update CronJob", "name", cronJob.Name)
+				reconcileErrors = append(reconcileErrors, err)
+			}
+		}
+	}
+
+	// Manually triggered runs are owned by the Scheduler rather than the CronJob, so
+	// their history is pruned here
+	if err := r.pruneJobHistory(ctx, scheduler, schedule); err != nil {
+		log.Error(err, "Failed to prune Job history", "schedule", schedule.Name)
+		reconcileErrors = append(reconcileErrors, err)
+	}
+	return reconcileErrors
+}
+
+// scheduleKey identifies a schedule across the cluster. It seeds the hashing of H tokens
+// and staggering so that equally written schedules of different Schedulers are spread apart.
+func scheduleKey(scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule) string {
+	return scheduler.Namespace + "/" + scheduler.Name + "/" + schedule.Name
+}
+
+// effectiveExpression returns the cron expression of a schedule with staggering applied,
+// using the Scheduler's window or, when it has none, the controller-wide one.
+func (r *SchedulerReconciler) effectiveExpression(scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule) (string, error) {
+	window := r.StaggerWindow
+	if scheduler.Spec.Stagger != nil {
+		window = scheduler.Spec.Stagger.Window.Duration
+	}
+
+	expr, err := cronexpr.Stagger(schedule.CronExpression, scheduleKey(scheduler, schedule), window)
+	if err != nil {
+		return "", fmt.Errorf("invalid cron expression for schedule %s: %w", schedule.Name, err)
+	}
+	return expr, nil
+}
+
+// cronJobExpression returns the effective expression of a schedule translated to the
+// syntax of a CronJob, explaining when the schedule needs the Native engine instead.
+func (r *SchedulerReconciler) cronJobExpression(scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule) (string, error) {
+	expr, err := r.effectiveExpression(scheduler, schedule)
+	if err != nil {
+		return "", err
+	}
+
+	expr, err = cronexpr.ToCronJob(expr, scheduleKey(scheduler, schedule))
+	if errors.Is(err, cronexpr.ErrNotRepresentable) {
+		return "", fmt.Errorf("schedule %s: %w; set mode: Native to run it", schedule.Name, err)
+	} else if err != nil {
+		return "", fmt.Errorf("invalid cron expression for schedule %s: %w", schedule.Name, err)
+	}
+	return expr, nil
+}
+
+// scheduleStatus returns the status of the named schedule, adding an empty one if missing.
+// The returned pointer is only valid until the next call adds an entry.
+func scheduleStatus(status *schedulingapiv1.SchedulerStatus, name string) *schedulingapiv1.ScheduleStatus {
+	if found := findScheduleStatus(status, name); found != nil {
+		return found
+	}
+	status.Schedules = append(status.Schedules, schedulingapiv1.ScheduleStatus{Name: name})
+	return &status.Schedules[len(status.Schedules)-1]
+}
+
+// findScheduleStatus returns the status of the named schedule, or nil if it has none.
+func findScheduleStatus(status *schedulingapiv1.SchedulerStatus, name string) *schedulingapiv1.ScheduleStatus {
+	for i := range status.Schedules {
+		if status.Schedules[i].Name == name {
+			return &status.Schedules[i]
+		}
+	}
+	return nil
+}
+
+// pruneScheduleStatuses drops the status of schedules no longer in the spec, and of
+// finished RunAt schedules that ask for it, and sorts the remaining ones by name for a
+// stable comparison.
+func pruneScheduleStatuses(status *schedulingapiv1.SchedulerStatus, schedules []schedulingapiv1.Schedule) {
+	desired := make(map[string]schedulingapiv1.Schedule, len(schedules))
+	for _, schedule := range schedules {
+		desired[schedule.Name] = schedule
+	}
+
+	kept := status.Schedules[:0]
+	for _, scheduleStatus := range status.Schedules {
+		schedule, found := desired[scheduleStatus.Name]
+		if !found || (schedule.RunAt != nil && schedule.RemoveStatusAfterRun && scheduleStatus.Completed) {
+			continue
+		}
+		kept = append(kept, scheduleStatus)
+	}
+	sort.Slice(kept, func(i, j int) bool {
+		return kept[i].Name < kept[j].Name
+	})
+	status.Schedules = kept
 }
 
 // cleanupCronJobs remains the same
@@ -191,6 +587,48 @@ func (r *SchedulerReconciler) cleanupCronJobs(ctx context.Context, scheduler *sc
 	return nil
 }
 
+// recordHold records when the controller starts and stops holding the runs of a
+// schedule.
+func recordHold(status *schedulingapiv1.ScheduleStatus, held bool, now time.Time) {
+	switch {
+	case held && status.HeldSince == nil:
+		status.HeldSince = &metav1.Time{Time: now}
+	case !held && status.HeldSince != nil:
+		status.HeldSince = nil
+		status.ResumedAt = &metav1.Time{Time: now}
+	}
+}
+
+// resumeDeadline returns the starting deadline of the CronJob of a schedule. Once resumed,
+// the CronJob controller would start the latest slot missed while the CronJob was held,
+// so until it runs again, the deadline reaches no further back than the resume.
+func resumeDeadline(deadline *int64, status *schedulingapiv1.ScheduleStatus, now time.Time) *int64 {
+	if status.ResumedAt == nil {
+		return deadline
+	}
+	since := min(now.Sub(status.ResumedAt.Time), cronjobbuilder.MissedRunGrace)
+	seconds := max(int64(since/time.Second), 1)
+	if deadline != nil && *deadline < seconds {
+		return deadline
+	}
+	return &seconds
+}
+
+// nextResumeDeadline returns when the starting deadline of a resumed CronJob next grows,
+// or the zero time.
+func nextResumeDeadline(scheduler *schedulingapiv1.Scheduler, now time.Time) time.Time {
+	var next time.Time
+	for _, status := range scheduler.Status.Schedules {
+		if status.ResumedAt == nil {
+			continue
+		}
+		if end := status.ResumedAt.Add(cronjobbuilder.MissedRunGrace); end.After(now) && (next.IsZero() || end.Before(next)) {
+			next = end
+		}
+	}
+	return next
+}
+
 // cronJobSpecEqual remains the same
 func cronJobSpecEqual(a, b *batchv1.CronJobSpec) bool {
 	return equality.Semantic.DeepEqual(a, b)
@@ -198,9 +636,45 @@ func cronJobSpecEqual(a, b *batchv1.CronJobSpec) bool {
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *SchedulerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.Notifier == nil {
+		dispatcher := notify.NewDispatcher(notify.DefaultQueueSize, notify.DefaultWorkers)
+		if err := mgr.Add(dispatcher); err != nil {
+			return err
+		}
+		r.Notifier = dispatcher
+	}
+	r.wakeUps = make(chan event.GenericEvent, notify.DefaultQueueSize)
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&schedulingapiv1.Scheduler{}).
 		Owns(&batchv1.CronJob{}).
-		Owns(&batchv1.Job{}).
+		// Jobs are watched by label rather than ownership so that runs started by the
+		// CronJob controller also refresh the run status of their Scheduler.
+		Watches(&batchv1.Job{}, handler.EnqueueRequestsFromMapFunc(jobToScheduler)).
+		Watches(&schedulingapiv1.Calendar{}, handler.EnqueueRequestsFromMapFunc(r.calendarToSchedulers)).
+		Watches(&schedulingapiv1.SchedulerQuota{}, handler.EnqueueRequestsFromMapFunc(r.quotaToSchedulers)).
+		WatchesRawSource(source.Channel(r.wakeUps, &handler.EnqueueRequestForObject{})).
 		Complete(r)
 }
+
+// wakeUp enqueues a Scheduler, without blocking: the Schedulers that cannot be enqueued
+// are reconciled when they requeue.
+func (r *SchedulerReconciler) wakeUp(key types.NamespacedName) {
+	scheduler := &schedulingapiv1.Scheduler{ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}}
+	select {
+	case r.wakeUps <- event.GenericEvent{Object: scheduler}:
+	default:
+	}
+}
+
+// jobToScheduler maps a Job created for a schedule to the Scheduler it belongs to.
+func jobToScheduler(_ context.Context, obj client.Object) []reconcile.Request {
+	labels := obj.GetLabels()
+	if labels["app"] != "scheduler-controller" || labels["scheduler"] == "" {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{
+		Namespace: obj.GetNamespace(),
+		Name:      labels["scheduler"],
+	}}}
+}
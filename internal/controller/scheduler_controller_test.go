@@ -18,16 +18,36 @@ package controller
 
 import (
 	"context"
+	"fmt"
+	"maps"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/types"
+	clocktesting "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cloudevents"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cronjobbuilder"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/notify"
 )
 
 var _ = Describe("Scheduler Controller", func() {
@@ -81,4 +101,1724 @@ var _ = Describe("Scheduler Controller", func() {
 			// Example: If you expect a certain status condition after reconciliation, verify it here.
 		})
 	})
+
+	Context("When reconciliation is paused", func() {
+		const resourceName = "paused-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			resource := &schedulingapiv1.Scheduler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+					Annotations: map[string]string{
+						schedulingapiv1.ReconcileAnnotation: schedulingapiv1.ReconcilePaused,
+					},
+				},
+				Spec: schedulingapiv1.SchedulerSpec{
+					Suspend: ptr.To(true),
+					Schedules: []schedulingapiv1.Schedule{{
+						Name:           "ping",
+						Image:          "busybox",
+						CronExpression: "*/5 * * * *",
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &schedulingapiv1.Scheduler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+
+		It("should report Paused and leave CronJobs alone until resumed", func() {
+			controllerReconciler := &SchedulerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			var cronJobs batchv1.CronJobList
+			Expect(k8sClient.List(ctx, &cronJobs, client.InNamespace("default"),
+				client.MatchingLabels{"scheduler": resourceName})).To(Succeed())
+			Expect(cronJobs.Items).To(BeEmpty())
+
+			scheduler := &schedulingapiv1.Scheduler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, scheduler)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(scheduler.Status.Conditions, "Paused")).To(BeTrue())
+
+			By("removing the pause annotation")
+			delete(scheduler.Annotations, schedulingapiv1.ReconcileAnnotation)
+			Expect(k8sClient.Update(ctx, scheduler)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.List(ctx, &cronJobs, client.InNamespace("default"),
+				client.MatchingLabels{"scheduler": resourceName})).To(Succeed())
+			Expect(cronJobs.Items).To(HaveLen(1))
+			Expect(cronJobs.Items[0].Spec.Suspend).To(HaveValue(BeTrue()))
+		})
+	})
+
+	Context("When reconciling a Native schedule", func() {
+		const resourceName = "native-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			resource := &schedulingapiv1.Scheduler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: schedulingapiv1.SchedulerSpec{
+					Schedules: []schedulingapiv1.Schedule{{
+						Name:           "tick",
+						Image:          "busybox",
+						CronExpression: "* * * * *",
+						Mode:           schedulingapiv1.ScheduleModeNative,
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &schedulingapiv1.Scheduler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(k8sClient.DeleteAllOf(ctx, &batchv1.Job{}, client.InNamespace("default"),
+				client.MatchingLabels{"scheduler": resourceName})).To(Succeed())
+		})
+
+		It("should fire the most recent slot exactly once and record it", func() {
+			scheduler := &schedulingapiv1.Scheduler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, scheduler)).To(Succeed())
+
+			now := scheduler.CreationTimestamp.Add(3*time.Minute + 10*time.Second).UTC()
+			controllerReconciler := &SchedulerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				Clock:  clocktesting.NewFakePassiveClock(now),
+			}
+
+			for range 2 {
+				result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.RequeueAfter).To(BeNumerically("~", 50*time.Second, time.Second))
+			}
+
+			var jobs batchv1.JobList
+			Expect(k8sClient.List(ctx, &jobs, client.InNamespace("default"),
+				client.MatchingLabels{"scheduler": resourceName})).To(Succeed())
+			Expect(jobs.Items).To(HaveLen(1))
+
+			slot := now.Truncate(time.Minute)
+			Expect(jobs.Items[0].Annotations).To(HaveKeyWithValue(schedulingapiv1.ScheduledTimeAnnotation, slot.Format(time.RFC3339)))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, scheduler)).To(Succeed())
+			Expect(scheduler.Status.Schedules).To(HaveLen(1))
+			Expect(scheduler.Status.Schedules[0].LastFireTime.Time).To(BeTemporally("==", slot))
+
+			var cronJobs batchv1.CronJobList
+			Expect(k8sClient.List(ctx, &cronJobs, client.InNamespace("default"),
+				client.MatchingLabels{"scheduler": resourceName})).To(Succeed())
+			Expect(cronJobs.Items).To(BeEmpty())
+		})
+
+		It("should not fire the slots of a blackout window once it ends", func() {
+			scheduler := &schedulingapiv1.Scheduler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, scheduler)).To(Succeed())
+			base := scheduler.CreationTimestamp.Truncate(time.Minute).UTC()
+			scheduler.Spec.Schedules[0].BlackoutWindows = []schedulingapiv1.BlackoutWindow{{
+				Start: &metav1.Time{Time: base.Add(90 * time.Second)},
+				End:   &metav1.Time{Time: base.Add(3*time.Minute + 30*time.Second)},
+			}}
+			Expect(k8sClient.Update(ctx, scheduler)).To(Succeed())
+
+			clock := clocktesting.NewFakePassiveClock(base.Add(3*time.Minute + 40*time.Second))
+			controllerReconciler := &SchedulerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				Clock:  clock,
+			}
+			jobs := func() []batchv1.Job {
+				var jobs batchv1.JobList
+				Expect(k8sClient.List(ctx, &jobs, client.InNamespace("default"),
+					client.MatchingLabels{"scheduler": resourceName})).To(Succeed())
+				return jobs.Items
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(jobs()).To(BeEmpty())
+
+			clock.SetTime(base.Add(4*time.Minute + 10*time.Second))
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(jobs()).To(ConsistOf(HaveField("ObjectMeta.Annotations", HaveKeyWithValue(
+				schedulingapiv1.ScheduledTimeAnnotation, base.Add(4*time.Minute).Format(time.RFC3339)))))
+		})
+
+		It("should pass the trace of the reconcile to the Job in TRACEPARENT", func() {
+			scheduler := &schedulingapiv1.Scheduler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, scheduler)).To(Succeed())
+
+			controllerReconciler := &SchedulerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				Clock:  clocktesting.NewFakePassiveClock(scheduler.CreationTimestamp.Add(time.Minute).UTC()),
+			}
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+			DeferCleanup(provider.Shutdown, context.Background())
+			tracedCtx, span := provider.Tracer("test").Start(ctx, "Test")
+			_, err := controllerReconciler.Reconcile(tracedCtx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			span.End()
+
+			var jobs batchv1.JobList
+			Expect(k8sClient.List(ctx, &jobs, client.InNamespace("default"),
+				client.MatchingLabels{"scheduler": resourceName})).To(Succeed())
+			Expect(jobs.Items).To(HaveLen(1))
+			env := jobs.Items[0].Spec.Template.Spec.Containers[0].Env
+			Expect(env).NotTo(BeEmpty())
+			Expect(env[0].Name).To(Equal("TRACEPARENT"))
+
+			var createJob sdktrace.ReadOnlySpan
+			for _, s := range recorder.Ended() {
+				if s.Name() == "Create Job" {
+					createJob = s
+				}
+			}
+			Expect(createJob).NotTo(BeNil())
+			Expect(createJob.SpanContext().TraceID()).To(Equal(span.SpanContext().TraceID()))
+			Expect(env[0].Value).To(Equal(fmt.Sprintf("00-%s-%s-01", createJob.SpanContext().TraceID(), createJob.SpanContext().SpanID())))
+		})
+	})
+
+	Context("When a schedule has jitter", func() {
+		scheduler := &schedulingapiv1.Scheduler{ObjectMeta: metav1.ObjectMeta{Name: "jittered", Namespace: "default"}}
+		schedule := schedulingapiv1.Schedule{
+			Name:           "report",
+			Image:          "busybox",
+			CronExpression: "0 * * * *",
+			Jitter:         &metav1.Duration{Duration: 10 * time.Minute},
+		}
+		slot := time.Date(2025, 3, 14, 2, 0, 0, 0, time.UTC)
+
+		It("should delay each slot by an offset that is stable and within the jitter", func() {
+			offsets := map[time.Duration]struct{}{}
+			for i := range 48 {
+				at := slot.Add(time.Duration(i) * time.Hour)
+				offset := jitterOffset(scheduler, schedule, at)
+				Expect(offset).To(BeNumerically(">=", 0))
+				Expect(offset).To(BeNumerically("<", schedule.Jitter.Duration))
+				Expect(offset % time.Second).To(BeZero())
+				Expect(jitterOffset(scheduler, schedule, at)).To(Equal(offset))
+				offsets[offset] = struct{}{}
+			}
+			Expect(len(offsets)).To(BeNumerically(">", 1))
+
+			unjittered := schedule
+			unjittered.Jitter = nil
+			Expect(jitterOffset(scheduler, unjittered, slot)).To(BeZero())
+		})
+
+		It("should report how long after its slot the job container started", func() {
+			job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+				Name:        "jittered-report-1741917600",
+				Annotations: map[string]string{batchv1.CronJobScheduledTimestampAnnotation: slot.Format(time.RFC3339)},
+			}}
+			Expect(runStatus(job, nil).StartOffset).To(BeNil())
+
+			started := metav1.NewTime(slot.Add(4*time.Minute + 2*time.Second))
+			pods := []corev1.Pod{{Status: corev1.PodStatus{
+				InitContainerStatuses: []corev1.ContainerStatus{{
+					Name:  "jitter",
+					State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{StartedAt: metav1.NewTime(slot)}},
+				}},
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name:  cronjobbuilder.JobContainerName,
+					State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: started}},
+				}},
+			}}}
+			run := runStatus(job, pods)
+			Expect(run.Phase).To(Equal(schedulingapiv1.RunPhaseRunning))
+			Expect(run.StartTime).To(Equal(&started))
+			Expect(run.StartOffset).To(Equal(&metav1.Duration{Duration: 4*time.Minute + 2*time.Second}))
+		})
+	})
+
+	Context("When a schedule is in a blackout window", func() {
+		const resourceName = "blackout-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			now := time.Now()
+			resource := &schedulingapiv1.Scheduler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: schedulingapiv1.SchedulerSpec{
+					BlackoutWindows: []schedulingapiv1.BlackoutWindow{{
+						Name:  "release-freeze",
+						Start: &metav1.Time{Time: now.Add(-time.Hour)},
+						End:   &metav1.Time{Time: now.Add(time.Hour)},
+					}},
+					Schedules: []schedulingapiv1.Schedule{{
+						Name:           "report",
+						Image:          "busybox",
+						CronExpression: "0 * * * *",
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &schedulingapiv1.Scheduler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+
+		It("should suspend the CronJob until the window ends", func() {
+			controllerReconciler := &SchedulerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(BeNumerically("~", time.Hour, time.Minute))
+
+			var cronJobs batchv1.CronJobList
+			Expect(k8sClient.List(ctx, &cronJobs, client.InNamespace("default"),
+				client.MatchingLabels{"scheduler": resourceName})).To(Succeed())
+			Expect(cronJobs.Items).To(HaveLen(1))
+			Expect(cronJobs.Items[0].Spec.Suspend).To(HaveValue(BeTrue()))
+
+			scheduler := &schedulingapiv1.Scheduler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, scheduler)).To(Succeed())
+			Expect(meta.IsStatusConditionTrue(scheduler.Status.Conditions, "Blackout")).To(BeTrue())
+		})
+
+		It("should not run the slots of the window once it ends", func() {
+			scheduler := &schedulingapiv1.Scheduler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, scheduler)).To(Succeed())
+			end := scheduler.Spec.BlackoutWindows[0].End.Time
+			clock := clocktesting.NewFakePassiveClock(end.Add(-time.Minute))
+			controllerReconciler := &SchedulerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				Clock:  clock,
+			}
+			cronJob := func() *batchv1.CronJob {
+				var cronJobs batchv1.CronJobList
+				Expect(k8sClient.List(ctx, &cronJobs, client.InNamespace("default"),
+					client.MatchingLabels{"scheduler": resourceName})).To(Succeed())
+				Expect(cronJobs.Items).To(HaveLen(1))
+				return &cronJobs.Items[0]
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cronJob().Spec.Suspend).To(HaveValue(BeTrue()))
+
+			// The CronJob controller starts the latest slot after now - startingDeadlineSeconds
+			clock.SetTime(end.Add(30 * time.Second))
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			resumed := cronJob()
+			Expect(resumed.Spec.Suspend).To(HaveValue(BeFalse()))
+			Expect(resumed.Spec.StartingDeadlineSeconds).NotTo(BeNil())
+			Expect(clock.Now().Add(-time.Duration(*resumed.Spec.StartingDeadlineSeconds) * time.Second)).To(BeTemporally(">=", end))
+			Expect(result.RequeueAfter).To(BeNumerically("<=", cronjobbuilder.MissedRunGrace))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, scheduler)).To(Succeed())
+			status := findScheduleStatus(&scheduler.Status, "report")
+			Expect(status.HeldSince).To(BeNil())
+			Expect(status.ResumedAt).NotTo(BeNil())
+
+			// Once the CronJob ran again, it gets its usual deadline back
+			resumed.Status.LastScheduleTime = &metav1.Time{Time: clock.Now().Add(time.Hour).Truncate(time.Second)}
+			Expect(k8sClient.Status().Update(ctx, resumed)).To(Succeed())
+			clock.SetTime(clock.Now().Add(2 * time.Hour))
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cronJob().Spec.StartingDeadlineSeconds).To(BeNil())
+			Expect(k8sClient.Get(ctx, typeNamespacedName, scheduler)).To(Succeed())
+			Expect(findScheduleStatus(&scheduler.Status, "report").ResumedAt).To(BeNil())
+		})
+	})
+
+	Context("When a schedule skips the days of a Calendar", func() {
+		const resourceName = "calendar-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+		holiday := time.Date(2025, 12, 25, 0, 0, 0, 0, time.UTC)
+
+		BeforeEach(func() {
+			Expect(k8sClient.Create(ctx, &schedulingapiv1.Calendar{
+				ObjectMeta: metav1.ObjectMeta{Name: "holidays"},
+				Spec:       schedulingapiv1.CalendarSpec{Dates: []string{"2025-12-25"}},
+			})).To(Succeed())
+			Expect(k8sClient.Create(ctx, &schedulingapiv1.Scheduler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: schedulingapiv1.SchedulerSpec{
+					Schedules: []schedulingapiv1.Schedule{{
+						Name:           "report",
+						Image:          "busybox",
+						CronExpression: "0 2 * * *",
+						SkipOn:         []string{"holidays"},
+					}},
+				},
+			})).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &schedulingapiv1.Scheduler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, &schedulingapiv1.Calendar{ObjectMeta: metav1.ObjectMeta{Name: "holidays"}})).To(Succeed())
+		})
+
+		It("should not run the slot of the skipped day once the CronJob resumes", func() {
+			clock := clocktesting.NewFakePassiveClock(holiday.Add(12 * time.Hour))
+			controllerReconciler := &SchedulerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				Clock:  clock,
+			}
+			cronJob := func() *batchv1.CronJob {
+				var cronJobs batchv1.CronJobList
+				Expect(k8sClient.List(ctx, &cronJobs, client.InNamespace("default"),
+					client.MatchingLabels{"scheduler": resourceName})).To(Succeed())
+				Expect(cronJobs.Items).To(HaveLen(1))
+				return &cronJobs.Items[0]
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cronJob().Spec.Suspend).To(HaveValue(BeTrue()))
+
+			// The CronJob controller starts the latest slot after now - startingDeadlineSeconds
+			nextDay := holiday.AddDate(0, 0, 1)
+			clock.SetTime(nextDay.Add(10 * time.Second))
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			resumed := cronJob()
+			Expect(resumed.Spec.Suspend).To(HaveValue(BeFalse()))
+			Expect(resumed.Spec.StartingDeadlineSeconds).NotTo(BeNil())
+			Expect(clock.Now().Add(-time.Duration(*resumed.Spec.StartingDeadlineSeconds) * time.Second)).To(BeTemporally(">", holiday.Add(2*time.Hour)))
+
+			scheduler := &schedulingapiv1.Scheduler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, scheduler)).To(Succeed())
+			Expect(scheduler.Status.Schedules[0].NextFireTime.Time).To(BeTemporally("==", nextDay.Add(2*time.Hour)))
+		})
+	})
+
+	Context("When a CronJob schedule starts later", func() {
+		const resourceName = "start-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+		startAt := time.Date(2025, 3, 14, 9, 0, 0, 0, time.UTC)
+
+		BeforeEach(func() {
+			Expect(k8sClient.Create(ctx, &schedulingapiv1.Scheduler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: schedulingapiv1.SchedulerSpec{
+					Schedules: []schedulingapiv1.Schedule{{
+						Name:           "sync",
+						Image:          "busybox",
+						CronExpression: "* * * * *",
+						StartAt:        &metav1.Time{Time: startAt},
+					}},
+				},
+			})).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &schedulingapiv1.Scheduler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+		})
+
+		It("should run the first slot at startAt, but none before", func() {
+			clock := clocktesting.NewFakePassiveClock(startAt.Add(-30 * time.Second))
+			controllerReconciler := &SchedulerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				Clock:  clock,
+			}
+			cronJob := func() *batchv1.CronJob {
+				var cronJobs batchv1.CronJobList
+				Expect(k8sClient.List(ctx, &cronJobs, client.InNamespace("default"),
+					client.MatchingLabels{"scheduler": resourceName})).To(Succeed())
+				Expect(cronJobs.Items).To(HaveLen(1))
+				return &cronJobs.Items[0]
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cronJob().Spec.Suspend).To(HaveValue(BeTrue()))
+
+			// The CronJob controller starts the latest slot after now - startingDeadlineSeconds
+			clock.SetTime(startAt)
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			started := cronJob()
+			Expect(started.Spec.Suspend).To(HaveValue(BeFalse()))
+			Expect(started.Spec.StartingDeadlineSeconds).NotTo(BeNil())
+			earliest := clock.Now().Add(-time.Duration(*started.Spec.StartingDeadlineSeconds) * time.Second)
+			Expect(earliest).To(BeTemporally("<", startAt))
+			Expect(earliest).To(BeTemporally(">", startAt.Add(-time.Minute)))
+		})
+	})
+
+	Context("When a Native schedule has a run limit", func() {
+		const resourceName = "limited-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			resource := &schedulingapiv1.Scheduler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: schedulingapiv1.SchedulerSpec{
+					Schedules: []schedulingapiv1.Schedule{{
+						Name:           "migrate",
+						Image:          "busybox",
+						CronExpression: "* * * * *",
+						Mode:           schedulingapiv1.ScheduleModeNative,
+						MaxRuns:        ptr.To[int32](1),
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &schedulingapiv1.Scheduler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(k8sClient.DeleteAllOf(ctx, &batchv1.Job{}, client.InNamespace("default"),
+				client.MatchingLabels{"scheduler": resourceName})).To(Succeed())
+		})
+
+		It("should stop and report Completed once MaxRuns is reached", func() {
+			scheduler := &schedulingapiv1.Scheduler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, scheduler)).To(Succeed())
+
+			clock := clocktesting.NewFakePassiveClock(scheduler.CreationTimestamp.Add(90 * time.Second).UTC())
+			controllerReconciler := &SchedulerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				Clock:  clock,
+			}
+
+			for range 2 {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				Expect(err).NotTo(HaveOccurred())
+				clock.SetTime(clock.Now().Add(time.Minute))
+			}
+
+			var jobs batchv1.JobList
+			Expect(k8sClient.List(ctx, &jobs, client.InNamespace("default"),
+				client.MatchingLabels{"scheduler": resourceName})).To(Succeed())
+			Expect(jobs.Items).To(HaveLen(1))
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, scheduler)).To(Succeed())
+			Expect(scheduler.Status.Schedules).To(HaveLen(1))
+			Expect(scheduler.Status.Schedules[0].RunCount).To(Equal(int32(1)))
+			Expect(scheduler.Status.Schedules[0].Completed).To(BeTrue())
+			Expect(scheduler.Status.Schedules[0].NextFireTime).To(BeNil())
+		})
+	})
+
+	Context("When a schedule runs once at a given time", func() {
+		const resourceName = "oneoff-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		runAt := time.Date(2030, time.March, 2, 3, 0, 0, 0, time.UTC)
+
+		BeforeEach(func() {
+			resource := &schedulingapiv1.Scheduler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: schedulingapiv1.SchedulerSpec{
+					Schedules: []schedulingapiv1.Schedule{{
+						Name:  "backfill",
+						Image: "busybox",
+						RunAt: &metav1.Time{Time: runAt},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &schedulingapiv1.Scheduler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(k8sClient.DeleteAllOf(ctx, &batchv1.Job{}, client.InNamespace("default"),
+				client.MatchingLabels{"scheduler": resourceName})).To(Succeed())
+		})
+
+		It("should wait for runAt and then create a single Job", func() {
+			clock := clocktesting.NewFakePassiveClock(runAt.Add(-time.Hour))
+			controllerReconciler := &SchedulerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				Clock:  clock,
+			}
+
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(time.Hour))
+
+			clock.SetTime(runAt.Add(time.Second))
+			for range 2 {
+				_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			var jobs batchv1.JobList
+			Expect(k8sClient.List(ctx, &jobs, client.InNamespace("default"),
+				client.MatchingLabels{"scheduler": resourceName})).To(Succeed())
+			Expect(jobs.Items).To(HaveLen(1))
+
+			var cronJobs batchv1.CronJobList
+			Expect(k8sClient.List(ctx, &cronJobs, client.InNamespace("default"),
+				client.MatchingLabels{"scheduler": resourceName})).To(Succeed())
+			Expect(cronJobs.Items).To(BeEmpty())
+
+			scheduler := &schedulingapiv1.Scheduler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, scheduler)).To(Succeed())
+			Expect(scheduler.Status.Schedules).To(HaveLen(1))
+			Expect(scheduler.Status.Schedules[0].RunCount).To(Equal(int32(1)))
+		})
+	})
+
+	Context("When a run is triggered through the annotation", func() {
+		const resourceName = "triggered-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			resource := &schedulingapiv1.Scheduler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+					Annotations: map[string]string{
+						schedulingapiv1.TriggerAnnotation: "report@first",
+					},
+				},
+				Spec: schedulingapiv1.SchedulerSpec{
+					Schedules: []schedulingapiv1.Schedule{{
+						Name:           "report",
+						Image:          "busybox",
+						CronExpression: "0 0 1 1 *",
+						Suspend:        ptr.To(true),
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &schedulingapiv1.Scheduler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(k8sClient.DeleteAllOf(ctx, &batchv1.Job{}, client.InNamespace("default"),
+				client.MatchingLabels{"scheduler": resourceName})).To(Succeed())
+		})
+
+		It("should start one manual Job per nonce", func() {
+			controllerReconciler := &SchedulerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			for range 2 {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			var jobs batchv1.JobList
+			Expect(k8sClient.List(ctx, &jobs, client.InNamespace("default"),
+				client.MatchingLabels{"scheduler": resourceName})).To(Succeed())
+			Expect(jobs.Items).To(HaveLen(1))
+			Expect(jobs.Items[0].Labels).To(HaveKeyWithValue(schedulingapiv1.TriggerLabel, schedulingapiv1.TriggerManual))
+
+			scheduler := &schedulingapiv1.Scheduler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, scheduler)).To(Succeed())
+			Expect(scheduler.Status.LastTrigger).NotTo(BeNil())
+			Expect(scheduler.Status.LastTrigger.Nonce).To(Equal("first"))
+			Expect(scheduler.Status.LastTrigger.JobName).To(Equal(jobs.Items[0].Name))
+			Expect(scheduler.Status.LastTrigger.TriggeredBy).NotTo(BeEmpty())
+
+			By("changing the nonce")
+			scheduler.Annotations[schedulingapiv1.TriggerAnnotation] = "report@second"
+			Expect(k8sClient.Update(ctx, scheduler)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.List(ctx, &jobs, client.InNamespace("default"),
+				client.MatchingLabels{"scheduler": resourceName})).To(Succeed())
+			Expect(jobs.Items).To(HaveLen(2))
+		})
+	})
+
+	Context("When a schedule is backfilled", func() {
+		const resourceName = "backfill-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		start := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+		BeforeEach(func() {
+			resource := &schedulingapiv1.Scheduler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: schedulingapiv1.SchedulerSpec{
+					Schedules: []schedulingapiv1.Schedule{{
+						Name:           "hourly",
+						Image:          "busybox",
+						CronExpression: "0 * * * *",
+						Suspend:        ptr.To(true),
+						Backfill: &schedulingapiv1.BackfillRequest{
+							Start: metav1.Time{Time: start},
+							End:   metav1.Time{Time: start.Add(5 * time.Hour)},
+						},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &schedulingapiv1.Scheduler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(k8sClient.DeleteAllOf(ctx, &batchv1.Job{}, client.InNamespace("default"),
+				client.MatchingLabels{"scheduler": resourceName})).To(Succeed())
+		})
+
+		It("should start the first slots with their logical time", func() {
+			controllerReconciler := &SchedulerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			var jobs batchv1.JobList
+			Expect(k8sClient.List(ctx, &jobs, client.InNamespace("default"),
+				client.MatchingLabels{"scheduler": resourceName, schedulingapiv1.TriggerLabel: schedulingapiv1.TriggerBackfill})).To(Succeed())
+			Expect(jobs.Items).To(HaveLen(backfillParallelism))
+			for _, job := range jobs.Items {
+				Expect(job.Spec.Template.Spec.Containers[0].Env).To(ContainElement(HaveField("Name", "SCHEDULED_TIME")))
+			}
+
+			scheduler := &schedulingapiv1.Scheduler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, scheduler)).To(Succeed())
+			Expect(scheduler.Status.Schedules).To(HaveLen(1))
+			Expect(scheduler.Status.Schedules[0].Backfill).NotTo(BeNil())
+			Expect(scheduler.Status.Schedules[0].Backfill.Runs).To(Equal(int32(backfillParallelism)))
+			Expect(scheduler.Status.Schedules[0].Backfill.Completed).To(BeFalse())
+			Expect(scheduler.Status.Schedules[0].Backfill.LastScheduledTime.Time).To(BeTemporally("==", start.Add(2*time.Hour)))
+		})
+	})
+
+	Context("When schedules depend on each other", func() {
+		const resourceName = "dependency-cycle-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			resource := &schedulingapiv1.Scheduler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: schedulingapiv1.SchedulerSpec{
+					Schedules: []schedulingapiv1.Schedule{
+						{Name: "extract", Image: "busybox", CronExpression: "0 * * * *", Mode: schedulingapiv1.ScheduleModeNative},
+						{Name: "transform", Image: "busybox", DependsOn: []string{"extract", "load"}},
+						{Name: "load", Image: "busybox", DependsOn: []string{"transform"}},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &schedulingapiv1.Scheduler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(k8sClient.DeleteAllOf(ctx, &batchv1.Job{}, client.InNamespace("default"),
+				client.MatchingLabels{"scheduler": resourceName})).To(Succeed())
+		})
+
+		It("should report the dependency cycle", func() {
+			controllerReconciler := &SchedulerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			scheduler := &schedulingapiv1.Scheduler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, scheduler)).To(Succeed())
+			ready := meta.FindStatusCondition(scheduler.Status.Conditions, "Ready")
+			Expect(ready).NotTo(BeNil())
+			Expect(ready.Status).To(Equal(metav1.ConditionFalse))
+			Expect(ready.Message).To(ContainSubstring("dependency cycle: transform -> load -> transform"))
+			Expect(scheduler.Status.Chains).To(BeEmpty())
+		})
+	})
+
+	Context("When schedules share a lock group", func() {
+		const resourceName = "lock-group-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			resource := &schedulingapiv1.Scheduler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: schedulingapiv1.SchedulerSpec{
+					Schedules: []schedulingapiv1.Schedule{
+						{
+							Name:           "writer-a",
+							Image:          "busybox",
+							CronExpression: "* * * * *",
+							LockGroup:      &schedulingapiv1.LockGroup{Name: "database"},
+						},
+						{
+							Name:           "writer-b",
+							Image:          "busybox",
+							CronExpression: "* * * * *",
+							Mode:           schedulingapiv1.ScheduleModeNative,
+							LockGroup:      &schedulingapiv1.LockGroup{Name: "database", Policy: schedulingapiv1.LockPolicySkip},
+						},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &schedulingapiv1.Scheduler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(k8sClient.DeleteAllOf(ctx, &batchv1.Job{}, client.InNamespace("default"),
+				client.MatchingLabels{"scheduler": resourceName})).To(Succeed())
+		})
+
+		It("should run one member at a time and report the holder", func() {
+			scheduler := &schedulingapiv1.Scheduler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, scheduler)).To(Succeed())
+
+			now := scheduler.CreationTimestamp.Add(time.Minute + 10*time.Second).UTC()
+			controllerReconciler := &SchedulerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				Clock:  clocktesting.NewFakePassiveClock(now),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			var jobs batchv1.JobList
+			Expect(k8sClient.List(ctx, &jobs, client.InNamespace("default"),
+				client.MatchingLabels{"scheduler": resourceName})).To(Succeed())
+			Expect(jobs.Items).To(HaveLen(1))
+			Expect(jobs.Items[0].Labels).To(HaveKeyWithValue("schedule", "writer-a"))
+			Expect(jobs.Items[0].Labels).To(HaveKeyWithValue(schedulingapiv1.LockGroupLabel, "database"))
+
+			var cronJobs batchv1.CronJobList
+			Expect(k8sClient.List(ctx, &cronJobs, client.InNamespace("default"),
+				client.MatchingLabels{"scheduler": resourceName})).To(Succeed())
+			Expect(cronJobs.Items).To(BeEmpty())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, scheduler)).To(Succeed())
+			for _, status := range scheduler.Status.Schedules {
+				Expect(status.Lock).NotTo(BeNil())
+				Expect(status.Lock.Holder).To(Equal("default/" + jobs.Items[0].Name))
+				if status.Name == "writer-b" {
+					Expect(status.Lock.Skipped).To(Equal(int32(1)))
+				}
+			}
+		})
+	})
+
+	Context("When a schedule has a retry policy", func() {
+		schedule := schedulingapiv1.Schedule{
+			Name:  "flaky",
+			Image: "busybox",
+			RetryPolicy: &schedulingapiv1.RetryPolicy{
+				MaxAttempts:  3,
+				InitialDelay: &metav1.Duration{Duration: 30 * time.Second},
+				Multiplier:   4,
+				MaxDelay:     &metav1.Duration{Duration: time.Minute},
+				RetryOn:      []int32{75},
+			},
+		}
+		failedAt := metav1.NewTime(time.Date(2025, 3, 14, 2, 0, 0, 0, time.UTC))
+
+		failedJob := func(name string, attempt string) *batchv1.Job {
+			job := &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        name,
+					Labels:      map[string]string{"schedule": schedule.Name},
+					Annotations: map[string]string{},
+				},
+				Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{{
+					Type:               batchv1.JobFailed,
+					Status:             corev1.ConditionTrue,
+					LastTransitionTime: failedAt,
+				}}},
+			}
+			if attempt != "" {
+				job.Annotations[schedulingapiv1.RetryOfAnnotation] = "run"
+				job.Annotations[schedulingapiv1.AttemptAnnotation] = attempt
+			}
+			return job
+		}
+		failedPods := func(job string, exitCode int32) map[string][]corev1.Pod {
+			return map[string][]corev1.Pod{job: {{Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+				Name:  "job",
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: exitCode}},
+			}}}}}}
+		}
+
+		It("should back off exponentially up to the maximum delay", func() {
+			Expect(retryDelay(schedule.RetryPolicy, 1)).To(Equal(30 * time.Second))
+			Expect(retryDelay(schedule.RetryPolicy, 2)).To(Equal(time.Minute))
+			Expect(retryDelay(&schedulingapiv1.RetryPolicy{}, 3)).To(Equal(40 * time.Second))
+		})
+
+		It("should not overflow the delay of late attempts", func() {
+			policy := &schedulingapiv1.RetryPolicy{
+				InitialDelay: &metav1.Duration{Duration: time.Hour},
+				Multiplier:   10,
+				MaxDelay:     &metav1.Duration{Duration: time.Duration(math.MaxInt64)},
+			}
+			for attempt := int32(1); attempt <= 100; attempt++ {
+				Expect(retryDelay(policy, attempt)).To(BeNumerically(">=", time.Hour))
+			}
+			Expect(retryDelay(policy, 100)).To(Equal(time.Duration(math.MaxInt64)))
+		})
+
+		It("should retry listed exit codes until the last attempt", func() {
+			next, ok := nextRetry(schedule, failedJob("run", ""), failedPods("run", 75), stepIndex{})
+			Expect(ok).To(BeTrue())
+			Expect(next).To(Equal(failedAt.Add(30 * time.Second)))
+
+			_, ok = nextRetry(schedule, failedJob("run", ""), failedPods("run", 1), stepIndex{})
+			Expect(ok).To(BeFalse())
+
+			_, ok = nextRetry(schedule, failedJob("run-retry-3", "3"), failedPods("run-retry-3", 75), stepIndex{})
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should report the latest attempt of a run", func() {
+			jobs := []batchv1.Job{*failedJob("run-retry-2", "2"), *failedJob("run", "")}
+			attempts := indexRunAttempts(jobs)
+			Expect(attempts.isLatestAttempt(&jobs[0])).To(BeTrue())
+			Expect(attempts.isLatestAttempt(&jobs[1])).To(BeFalse())
+		})
+
+		It("should not prune the failed runs waiting for a retry", func() {
+			scheduler := &schedulingapiv1.Scheduler{ObjectMeta: metav1.ObjectMeta{Name: "retried", UID: "retried-uid"}}
+			owned := func(job *batchv1.Job, createdAfter time.Duration) batchv1.Job {
+				job.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(scheduler, schedulingapiv1.GroupVersion.WithKind("Scheduler"))}
+				job.CreationTimestamp = metav1.NewTime(failedAt.Add(createdAfter))
+				return *job
+			}
+			pods := map[string][]corev1.Pod{}
+			for job, exitCode := range map[string]int32{"first": 75, "second": 75, "old": 1, "older": 1} {
+				maps.Copy(pods, failedPods(job, exitCode))
+			}
+			names := func(jobs []*batchv1.Job) []string {
+				var names []string
+				for _, job := range jobs {
+					names = append(names, job.Name)
+				}
+				return names
+			}
+
+			jobs := []batchv1.Job{
+				owned(failedJob("older", ""), -2*time.Hour),
+				owned(failedJob("old", ""), -time.Hour),
+				owned(failedJob("first", ""), 0),
+				owned(failedJob("second", ""), time.Minute),
+			}
+			Expect(names(expiredJobs(scheduler, schedule, jobs, pods))).To(Equal([]string{"older"}))
+
+			// Once retried, a failed run counts towards the history limit again
+			retry := failedJob("first-retry-2", "2")
+			retry.Annotations[schedulingapiv1.RetryOfAnnotation] = "first"
+			jobs = append(jobs, owned(retry, 2*time.Minute))
+			Expect(names(expiredJobs(scheduler, schedule, jobs, pods))).To(Equal([]string{"older", "old", "first"}))
+
+			// The failed Jobs of the schedule's CronJob are pruned with the same exception
+			cronJob := func(job *batchv1.Job, name string, createdAfter time.Duration) batchv1.Job {
+				cronJob := &batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID(name)}}
+				job.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(cronJob, batchv1.SchemeGroupVersion.WithKind("CronJob"))}
+				job.CreationTimestamp = metav1.NewTime(failedAt.Add(createdAfter))
+				return *job
+			}
+			name := cronjobbuilder.CronJobName(scheduler, schedule)
+			jobs = []batchv1.Job{
+				cronJob(failedJob("old", ""), name, -time.Hour),
+				cronJob(failedJob("first", ""), name, 0),
+				cronJob(failedJob("second", ""), name, time.Minute),
+				cronJob(failedJob("older", ""), "other", -2*time.Hour),
+				owned(failedJob("third", ""), 2*time.Minute),
+			}
+			Expect(names(expiredJobs(scheduler, schedule, jobs, pods))).To(Equal([]string{"old"}))
+		})
+	})
+
+	Context("When a schedule has a failure threshold", func() {
+		schedule := schedulingapiv1.Schedule{
+			Name:             "broken",
+			Image:            "busybox",
+			CronExpression:   "* * * * *",
+			FailureThreshold: ptr.To(int32(2)),
+		}
+		finishedAt := time.Date(2025, 3, 14, 2, 0, 0, 0, time.UTC)
+
+		finishedJob := func(name string, condition batchv1.JobConditionType, after time.Duration) *batchv1.Job {
+			return &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:   name,
+					Labels: map[string]string{"schedule": schedule.Name},
+				},
+				Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{{
+					Type:               condition,
+					Status:             corev1.ConditionTrue,
+					Reason:             "BackoffLimitExceeded",
+					Message:            "Job has reached the specified backoff limit",
+					LastTransitionTime: metav1.NewTime(finishedAt.Add(after)),
+				}}},
+			}
+		}
+
+		It("should count the failures in a row once", func() {
+			circuit := &schedulingapiv1.CircuitStatus{}
+			jobs := map[string]*batchv1.Job{
+				"a": finishedJob("a", batchv1.JobFailed, 0),
+				"b": finishedJob("b", batchv1.JobComplete, time.Minute),
+				"c": finishedJob("c", batchv1.JobFailed, 2*time.Minute),
+				"d": finishedJob("d", batchv1.JobFailed, 3*time.Minute),
+			}
+			countRuns(circuit, schedule, jobs, nil, stepIndex{})
+			Expect(circuit.ConsecutiveFailures).To(Equal(int32(2)))
+			Expect(circuit.LastObservedJob).To(Equal("d"))
+			Expect(circuit.LastFailure).To(ContainSubstring("BackoffLimitExceeded"))
+
+			countRuns(circuit, schedule, jobs, nil, stepIndex{})
+			Expect(circuit.ConsecutiveFailures).To(Equal(int32(2)))
+		})
+
+		It("should report open circuits in the CircuitOpen condition", func() {
+			scheduler := &schedulingapiv1.Scheduler{
+				Spec: schedulingapiv1.SchedulerSpec{Schedules: []schedulingapiv1.Schedule{schedule}},
+			}
+			Expect(circuitCondition(scheduler).Status).To(Equal(metav1.ConditionFalse))
+
+			scheduleStatus(&scheduler.Status, schedule.Name).Circuit = &schedulingapiv1.CircuitStatus{
+				ConsecutiveFailures: 2,
+				LastFailure:         "Job d failed",
+				OpenedAt:            &metav1.Time{Time: finishedAt},
+			}
+			Expect(suspended(scheduler, schedule)).To(BeTrue())
+			condition := circuitCondition(scheduler)
+			Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+			Expect(condition.Message).To(ContainSubstring("Job d failed"))
+		})
+	})
+
+	Context("When the circuit of a schedule closes", func() {
+		const resourceName = "circuit-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			schedule := schedulingapiv1.Schedule{
+				Image:            "busybox",
+				CronExpression:   "* * * * *",
+				FailureThreshold: ptr.To(int32(1)),
+				CircuitCooldown:  &metav1.Duration{Duration: 10 * time.Minute},
+			}
+			cron, native := schedule, schedule
+			cron.Name = "cron"
+			native.Name = "native"
+			native.Mode = schedulingapiv1.ScheduleModeNative
+			Expect(k8sClient.Create(ctx, &schedulingapiv1.Scheduler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: schedulingapiv1.SchedulerSpec{Schedules: []schedulingapiv1.Schedule{cron, native}},
+			})).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &schedulingapiv1.Scheduler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(k8sClient.DeleteAllOf(ctx, &batchv1.Job{}, client.InNamespace("default"),
+				client.MatchingLabels{"scheduler": resourceName})).To(Succeed())
+		})
+
+		It("should not run the slots missed while it was open", func() {
+			scheduler := &schedulingapiv1.Scheduler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, scheduler)).To(Succeed())
+			base := scheduler.CreationTimestamp.Truncate(time.Minute).UTC()
+			for _, schedule := range scheduler.Spec.Schedules {
+				scheduleStatus(&scheduler.Status, schedule.Name).Circuit = &schedulingapiv1.CircuitStatus{
+					ConsecutiveFailures: 1,
+					OpenedAt:            &metav1.Time{Time: base.Add(time.Minute)},
+					SpecHash:            scheduleHash(schedule),
+				}
+			}
+			Expect(k8sClient.Status().Update(ctx, scheduler)).To(Succeed())
+
+			clock := clocktesting.NewFakePassiveClock(base.Add(5*time.Minute + 30*time.Second))
+			controllerReconciler := &SchedulerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				Clock:  clock,
+			}
+			cronJob := func() *batchv1.CronJob {
+				var cronJobs batchv1.CronJobList
+				Expect(k8sClient.List(ctx, &cronJobs, client.InNamespace("default"),
+					client.MatchingLabels{"scheduler": resourceName})).To(Succeed())
+				Expect(cronJobs.Items).To(HaveLen(1))
+				return &cronJobs.Items[0]
+			}
+			jobs := func() []batchv1.Job {
+				var jobs batchv1.JobList
+				Expect(k8sClient.List(ctx, &jobs, client.InNamespace("default"),
+					client.MatchingLabels{"scheduler": resourceName})).To(Succeed())
+				return jobs.Items
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cronJob().Spec.Suspend).To(HaveValue(BeTrue()))
+			Expect(jobs()).To(BeEmpty())
+
+			// The CronJob controller starts the latest slot after now - startingDeadlineSeconds
+			clock.SetTime(base.Add(11*time.Minute + 10*time.Second))
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			resumed := cronJob()
+			Expect(resumed.Spec.Suspend).To(HaveValue(BeFalse()))
+			Expect(resumed.Spec.StartingDeadlineSeconds).NotTo(BeNil())
+			Expect(clock.Now().Add(-time.Duration(*resumed.Spec.StartingDeadlineSeconds) * time.Second)).To(BeTemporally(">", base.Add(11*time.Minute)))
+			Expect(jobs()).To(BeEmpty())
+
+			clock.SetTime(base.Add(12*time.Minute + 5*time.Second))
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(jobs()).To(ConsistOf(HaveField("ObjectMeta.Annotations", HaveKeyWithValue(
+				schedulingapiv1.ScheduledTimeAnnotation, base.Add(12*time.Minute).Format(time.RFC3339)))))
+		})
+	})
+
+	Context("When a precondition of a schedule is not met", func() {
+		const resourceName = "precondition-resource"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		BeforeEach(func() {
+			resource := &schedulingapiv1.Scheduler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      resourceName,
+					Namespace: "default",
+				},
+				Spec: schedulingapiv1.SchedulerSpec{
+					Schedules: []schedulingapiv1.Schedule{{
+						Name:           "gated",
+						Image:          "busybox",
+						CronExpression: "* * * * *",
+						Mode:           schedulingapiv1.ScheduleModeNative,
+						Preconditions: []schedulingapiv1.Precondition{{
+							ConfigMap: &schedulingapiv1.ConfigMapPrecondition{Name: "missing-flags", Key: "enabled", Value: "true"},
+						}},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, resource)).To(Succeed())
+		})
+
+		AfterEach(func() {
+			resource := &schedulingapiv1.Scheduler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, resource)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, resource)).To(Succeed())
+			Expect(k8sClient.DeleteAllOf(ctx, &batchv1.Job{}, client.InNamespace("default"),
+				client.MatchingLabels{"scheduler": resourceName})).To(Succeed())
+		})
+
+		It("should record the run as Skipped without creating a Job", func() {
+			scheduler := &schedulingapiv1.Scheduler{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, scheduler)).To(Succeed())
+
+			now := scheduler.CreationTimestamp.Add(time.Minute + 10*time.Second).UTC()
+			controllerReconciler := &SchedulerReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+				Clock:  clocktesting.NewFakePassiveClock(now),
+			}
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			var jobs batchv1.JobList
+			Expect(k8sClient.List(ctx, &jobs, client.InNamespace("default"),
+				client.MatchingLabels{"scheduler": resourceName})).To(Succeed())
+			Expect(jobs.Items).To(BeEmpty())
+
+			Expect(k8sClient.Get(ctx, typeNamespacedName, scheduler)).To(Succeed())
+			Expect(scheduler.Status.Schedules).To(HaveLen(1))
+			runs := scheduler.Status.Schedules[0].Runs
+			Expect(runs).NotTo(BeEmpty())
+			Expect(runs[0].Phase).To(Equal(schedulingapiv1.RunPhaseSkipped))
+			Expect(runs[0].Reason).To(ContainSubstring("missing-flags"))
+			Expect(scheduler.Status.Schedules[0].LastFireTime).NotTo(BeNil())
+		})
+
+		It("should not follow the redirects of HTTP preconditions", func() {
+			mux := http.NewServeMux()
+			mux.HandleFunc("/ready", func(http.ResponseWriter, *http.Request) {})
+			mux.Handle("/moved", http.RedirectHandler("/ready", http.StatusFound))
+			server := httptest.NewServer(mux)
+			defer server.Close()
+			controllerReconciler := &SchedulerReconciler{Client: k8sClient, Scheme: k8sClient.Scheme()}
+
+			met, err := controllerReconciler.preconditionMet(ctx, "default", schedulingapiv1.Precondition{
+				HTTP: &schedulingapiv1.HTTPPrecondition{URL: server.URL + "/ready"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(met).To(BeTrue())
+
+			met, err = controllerReconciler.preconditionMet(ctx, "default", schedulingapiv1.Precondition{
+				HTTP: &schedulingapiv1.HTTPPrecondition{URL: server.URL + "/moved"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(met).To(BeFalse())
+		})
+	})
+
+	Context("When runs are recorded as ScheduleRuns", func() {
+		start := time.Date(2025, 3, 14, 2, 0, 0, 0, time.UTC)
+
+		record := func(name string, schedule string, after time.Duration, finished bool) *runRecord {
+			run := &schedulingapiv1.ScheduleRun{Spec: schedulingapiv1.ScheduleRunSpec{ScheduleName: schedule}}
+			if finished {
+				run.Status.CompletionTime = &metav1.Time{Time: start.Add(after + time.Minute)}
+			}
+			return &runRecord{name: name, schedule: schedule, at: start.Add(after), desired: run}
+		}
+		names := func(records []*runRecord) []string {
+			var names []string
+			for _, rec := range records {
+				names = append(names, rec.name)
+			}
+			return names
+		}
+
+		It("should keep the most recent finished runs of each schedule", func() {
+			records := map[string]*runRecord{
+				"a-1": record("a-1", "a", 0, true),
+				"a-2": record("a-2", "a", time.Hour, true),
+				"a-3": record("a-3", "a", 2*time.Hour, true),
+				"a-4": record("a-4", "a", 3*time.Hour, false),
+				"b-1": record("b-1", "b", 0, true),
+			}
+			keep, drop := retainRuns(records, 2, 0, start.Add(4*time.Hour))
+			Expect(names(keep)).To(Equal([]string{"a-4", "a-3", "b-1"}))
+			Expect(names(drop)).To(Equal([]string{"a-2", "a-1"}))
+		})
+
+		It("should remove finished runs older than the TTL", func() {
+			records := map[string]*runRecord{
+				"a-1": record("a-1", "a", 0, true),
+				"a-2": record("a-2", "a", time.Hour, false),
+				"a-3": record("a-3", "a", 2*time.Hour, true),
+			}
+			keep, drop := retainRuns(records, 100, 90*time.Minute, start.Add(3*time.Hour))
+			Expect(names(keep)).To(Equal([]string{"a-3", "a-2"}))
+			Expect(names(drop)).To(Equal([]string{"a-1"}))
+		})
+
+		It("should record the exit code, message and node of the last termination", func() {
+			pod := func(node string, finishedAt time.Time, exitCode int32) corev1.Pod {
+				return corev1.Pod{
+					Spec: corev1.PodSpec{NodeName: node},
+					Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+						Name: "job",
+						State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+							ExitCode:   exitCode,
+							Message:    node + " failed",
+							FinishedAt: metav1.NewTime(finishedAt),
+						}},
+					}}},
+				}
+			}
+			scheduler := &schedulingapiv1.Scheduler{ObjectMeta: metav1.ObjectMeta{Name: "history", Namespace: "default"}}
+			job := &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{Name: "history-report-1", Labels: map[string]string{"schedule": "report"}},
+				Status: batchv1.JobStatus{Conditions: []batchv1.JobCondition{{
+					Type:               batchv1.JobFailed,
+					Status:             corev1.ConditionTrue,
+					LastTransitionTime: metav1.NewTime(start.Add(2 * time.Minute)),
+				}}},
+			}
+			podsByJob := map[string][]corev1.Pod{job.Name: {
+				pod("node-b", start.Add(time.Minute), 2),
+				pod("node-a", start, 1),
+			}}
+
+			run := scheduleRunFor(scheduler, schedulingapiv1.Schedule{Name: "report"}, job, podsByJob, stepIndex{})
+			Expect(run.Name).To(Equal(job.Name))
+			Expect(run.Labels).To(HaveKeyWithValue("scheduler", "history"))
+			Expect(run.Spec.ScheduleName).To(Equal("report"))
+			Expect(run.Status.Phase).To(Equal(schedulingapiv1.RunPhaseFailed))
+			Expect(run.Status.ExitCode).To(Equal(ptr.To(int32(2))))
+			Expect(run.Status.TerminationMessage).To(Equal("node-b failed"))
+			Expect(run.Status.NodeName).To(Equal("node-b"))
+		})
+	})
+
+	Context("When the output of a run is captured", func() {
+		schedule := schedulingapiv1.Schedule{
+			Name: "export",
+			Output: &schedulingapiv1.OutputCapture{
+				LogLines: 20,
+				MaxBytes: 64,
+				Redact:   []string{`password=\S+`, `Bearer [A-Za-z0-9.]+`},
+			},
+		}
+
+		It("should redact the configured patterns", func() {
+			filter := newOutputFilter(schedule)
+			Expect(filter.apply("login failed with password=hunter2 and Bearer abc.def")).
+				To(Equal("login failed with [REDACTED] and [REDACTED]"))
+		})
+
+		It("should keep the end of long output", func() {
+			output := newOutputFilter(schedule).apply(strings.Repeat("é", 40) + " exit status 3")
+			Expect(len(output)).To(BeNumerically("<=", 64))
+			Expect(output).To(HavePrefix("[truncated] "))
+			Expect(output).To(HaveSuffix("é exit status 3"))
+			Expect(utf8.ValidString(output)).To(BeTrue())
+		})
+
+		It("should capture nothing while a pattern is invalid", func() {
+			invalid := schedule
+			invalid.Output = &schedulingapiv1.OutputCapture{Redact: []string{"("}}
+			Expect(newOutputFilter(invalid).apply("secret")).To(BeEmpty())
+		})
+
+		It("should capture the logs of failed runs unless asked for all", func() {
+			Expect(capturesLogs(schedule, schedulingapiv1.RunPhaseFailed)).To(BeTrue())
+			Expect(capturesLogs(schedule, schedulingapiv1.RunPhaseSucceeded)).To(BeFalse())
+			Expect(capturesLogs(schedulingapiv1.Schedule{}, schedulingapiv1.RunPhaseFailed)).To(BeFalse())
+		})
+	})
+
+	Context("When a job writes outputs to its termination message", func() {
+		It("should parse a JSON object into outputs", func() {
+			outputs := parseOutputs(`{"rows": 1200, "path": "s3://bucket/out.csv", "ok": true, "stats": {"max": 3}}`,
+				newOutputFilter(schedulingapiv1.Schedule{}))
+			Expect(outputs).To(Equal(map[string]string{
+				"rows":  "1200",
+				"path":  "s3://bucket/out.csv",
+				"ok":    "true",
+				"stats": `{"max": 3}`,
+			}))
+		})
+
+		It("should ignore messages that are not a JSON object", func() {
+			filter := newOutputFilter(schedulingapiv1.Schedule{})
+			Expect(parseOutputs("exit status 1", filter)).To(BeNil())
+			Expect(parseOutputs(`["a"]`, filter)).To(BeNil())
+			Expect(parseOutputs(`{"rows": `, filter)).To(BeNil())
+		})
+
+		It("should redact outputs", func() {
+			filter := newOutputFilter(schedulingapiv1.Schedule{
+				Output: &schedulingapiv1.OutputCapture{Redact: []string{`token=\w+`}},
+			})
+			Expect(parseOutputs(`{"url": "https://example.com/?token=abc"}`, filter)).
+				To(HaveKeyWithValue("url", "https://example.com/?[REDACTED]"))
+		})
+	})
+
+	Context("When runs are notified", func() {
+		schedule := schedulingapiv1.Schedule{Name: "sync", Image: "busybox", CronExpression: "0 * * * *"}
+		start := time.Date(2025, 3, 14, 0, 30, 0, 0, time.UTC)
+
+		job := func(name string, condition batchv1.JobConditionType, finishedAt time.Time) *batchv1.Job {
+			job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				Labels:            map[string]string{"schedule": schedule.Name},
+				CreationTimestamp: metav1.NewTime(start),
+			}}
+			if condition != "" {
+				job.Status.Conditions = []batchv1.JobCondition{{
+					Type:               condition,
+					Status:             corev1.ConditionTrue,
+					LastTransitionTime: metav1.NewTime(finishedAt),
+				}}
+			}
+			return job
+		}
+		triggers := func(notifications []schedulingapiv1.PendingNotification) []string {
+			var triggers []string
+			for _, n := range notifications {
+				triggers = append(triggers, string(n.Trigger)+" "+n.Run)
+			}
+			return triggers
+		}
+
+		It("should notify failures and recoveries once, after the first observation", func() {
+			status := &schedulingapiv1.ScheduleStatus{Name: schedule.Name}
+			jobs := map[string]*batchv1.Job{"sync-1": job("sync-1", batchv1.JobFailed, start.Add(time.Hour))}
+			observed, _ := observeRuns(status, schedule, jobs, nil, stepIndex{}, time.Hour, start.Add(2*time.Hour))
+			Expect(observed).To(BeEmpty())
+			Expect(status.Notifications.LastPhase).To(Equal(schedulingapiv1.RunPhaseFailed))
+
+			jobs["sync-2"] = job("sync-2", batchv1.JobFailed, start.Add(2*time.Hour))
+			jobs["sync-3"] = job("sync-3", batchv1.JobComplete, start.Add(3*time.Hour))
+			observed, _ = observeRuns(status, schedule, jobs, nil, stepIndex{}, time.Hour, start.Add(4*time.Hour))
+			Expect(triggers(observed)).To(Equal([]string{"Failure sync-2", "Recovery sync-3"}))
+
+			observed, _ = observeRuns(status, schedule, jobs, nil, stepIndex{}, time.Hour, start.Add(4*time.Hour))
+			Expect(observed).To(BeEmpty())
+		})
+
+		It("should notify runs running for too long once", func() {
+			status := &schedulingapiv1.ScheduleStatus{Name: schedule.Name}
+			jobs := map[string]*batchv1.Job{"sync-1": job("sync-1", "", time.Time{})}
+			observed, after := observeRuns(status, schedule, jobs, nil, stepIndex{}, time.Hour, start.Add(20*time.Minute))
+			Expect(observed).To(BeEmpty())
+			Expect(after).To(Equal(40 * time.Minute))
+
+			observed, _ = observeRuns(status, schedule, jobs, nil, stepIndex{}, time.Hour, start.Add(time.Hour))
+			Expect(triggers(observed)).To(Equal([]string{"LongRunning sync-1"}))
+			Expect(status.Notifications.LongRunning).To(Equal([]string{"sync-1"}))
+
+			observed, _ = observeRuns(status, schedule, jobs, nil, stepIndex{}, time.Hour, start.Add(2*time.Hour))
+			Expect(observed).To(BeEmpty())
+		})
+
+		It("should notify a slot that passed without a run", func() {
+			reconciler := &SchedulerReconciler{}
+			scheduler := &schedulingapiv1.Scheduler{
+				ObjectMeta: metav1.ObjectMeta{Name: "notified", Namespace: "default", CreationTimestamp: metav1.NewTime(start)},
+				Spec: schedulingapiv1.SchedulerSpec{
+					Notifications: &schedulingapiv1.Notifications{},
+					Schedules:     []schedulingapiv1.Schedule{schedule},
+				},
+			}
+			status := scheduleStatus(&scheduler.Status, schedule.Name)
+			status.LastFireTime = &metav1.Time{Time: start.Add(30 * time.Minute)}
+			status.Notifications = &schedulingapiv1.ScheduleNotificationStatus{}
+
+			missed, after, err := reconciler.observeMissedSlot(scheduler, schedule, status, blackoutState{}, calendarFilter{}, start.Add(60*time.Minute))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(missed).To(BeNil())
+			Expect(after).To(Equal(35 * time.Minute))
+
+			now := start.Add(100 * time.Minute)
+			missed, _, err = reconciler.observeMissedSlot(scheduler, schedule, status, blackoutState{}, calendarFilter{}, now)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(missed).NotTo(BeNil())
+			Expect(missed.Trigger).To(Equal(schedulingapiv1.NotifyMissed))
+			Expect(missed.ScheduledTime.Time).To(Equal(start.Add(90 * time.Minute)))
+
+			missed, _, err = reconciler.observeMissedSlot(scheduler, schedule, status, blackoutState{}, calendarFilter{}, now)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(missed).To(BeNil())
+		})
+
+		It("should back off deliveries up to a limit", func() {
+			Expect(notificationDelay(1)).To(Equal(30 * time.Second))
+			Expect(notificationDelay(3)).To(Equal(2 * time.Minute))
+			Expect(notificationDelay(10)).To(Equal(10 * time.Minute))
+		})
+
+		It("should deliver notifications in the background and collect their outcome", func() {
+			var done []func(error)
+			reconciler := &SchedulerReconciler{Notifier: dispatcherFunc(func(_ notify.Sender, _ notify.Notification, d func(error)) bool {
+				done = append(done, d)
+				return len(done) != 2
+			})}
+			scheduler := &schedulingapiv1.Scheduler{
+				ObjectMeta: metav1.ObjectMeta{Name: "notified", Namespace: "default"},
+				Spec: schedulingapiv1.SchedulerSpec{Notifications: &schedulingapiv1.Notifications{Sinks: []schedulingapiv1.NotificationSink{
+					{Name: "ops", Webhook: &schedulingapiv1.WebhookSink{URL: "http://example.com"}},
+				}}},
+			}
+			now := start.Add(time.Hour)
+			pending := []schedulingapiv1.PendingNotification{
+				{Sink: "ops", Trigger: schedulingapiv1.NotifyFailure, Run: "sync-1", NextAttemptTime: metav1.NewTime(now)},
+				{Sink: "ops", Trigger: schedulingapiv1.NotifyFailure, Run: "sync-2", NextAttemptTime: metav1.NewTime(now)},
+			}
+
+			// The dispatcher takes the first notification only, and neither is attempted yet
+			remaining, after := reconciler.deliverNotifications(context.Background(), scheduler, pending, now)
+			Expect(remaining).To(Equal(pending))
+			Expect(after).To(Equal(notify.Timeout))
+
+			// The first one is not dispatched again while it is being delivered, the second one is
+			remaining, _ = reconciler.deliverNotifications(context.Background(), scheduler, remaining, now)
+			Expect(remaining).To(HaveLen(2))
+			Expect(done).To(HaveLen(3))
+
+			done[0](errors.NewServiceUnavailable("down"))
+			done[2](nil)
+			remaining, after = reconciler.deliverNotifications(context.Background(), scheduler, remaining, now)
+			Expect(remaining).To(HaveLen(1))
+			Expect(remaining[0].Run).To(Equal("sync-1"))
+			Expect(remaining[0].Attempts).To(BeEquivalentTo(1))
+			Expect(remaining[0].NextAttemptTime.Time).To(Equal(now.Add(notificationRetryDelay)))
+			Expect(after).To(Equal(notificationRetryDelay))
+		})
+	})
+
+	Context("When CloudEvents are published", func() {
+		var emitted []string
+		var reconciler *SchedulerReconciler
+
+		BeforeEach(func() {
+			emitted = nil
+			reconciler = &SchedulerReconciler{CloudEvents: emitterFunc(func(event cloudevents.Event) bool {
+				emitted = append(emitted, event.Type+" "+event.Subject)
+				return true
+			})}
+		})
+
+		It("should publish the schedules created, updated and deleted", func() {
+			scheduler := &schedulingapiv1.Scheduler{
+				ObjectMeta: metav1.ObjectMeta{Name: "published", Namespace: "default"},
+				Spec: schedulingapiv1.SchedulerSpec{Schedules: []schedulingapiv1.Schedule{
+					{Name: "a", Image: "busybox", CronExpression: "0 * * * *"},
+					{Name: "b", Image: "busybox", CronExpression: "0 * * * *"},
+				}},
+			}
+			events := reconciler.scheduleEvents(context.Background(), scheduler)
+			Expect(emitted).To(BeEmpty())
+			reconciler.publish(events)
+			Expect(emitted).To(Equal([]string{ScheduleCreatedEvent + " a", ScheduleCreatedEvent + " b"}))
+
+			emitted = nil
+			reconciler.publish(reconciler.scheduleEvents(context.Background(), scheduler))
+			Expect(emitted).To(BeEmpty())
+
+			scheduler.Spec.Schedules = []schedulingapiv1.Schedule{{Name: "a", Image: "busybox", CronExpression: "30 * * * *"}}
+			reconciler.publish(reconciler.scheduleEvents(context.Background(), scheduler))
+			Expect(emitted).To(Equal([]string{ScheduleUpdatedEvent + " a", ScheduleDeletedEvent + " b"}))
+
+			emitted = nil
+			reconciler.emitSchedulerDeleted(context.Background(), types.NamespacedName{Namespace: "default", Name: "published"})
+			Expect(emitted).To(Equal([]string{ScheduleDeletedEvent + " a"}))
+		})
+
+		It("should not publish the schedules of Schedulers reconciled before", func() {
+			scheduler := &schedulingapiv1.Scheduler{
+				ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "default"},
+				Spec:       schedulingapiv1.SchedulerSpec{Schedules: []schedulingapiv1.Schedule{{Name: "a", Image: "busybox"}}},
+				Status:     schedulingapiv1.SchedulerStatus{Schedules: []schedulingapiv1.ScheduleStatus{{Name: "a"}}},
+			}
+			Expect(reconciler.scheduleEvents(context.Background(), scheduler)).To(BeEmpty())
+			Expect(scheduler.Status.ScheduleHashes).To(HaveKey("a"))
+		})
+
+		It("should publish when schedules run, but not what they run", func() {
+			scheduler := &schedulingapiv1.Scheduler{
+				ObjectMeta: metav1.ObjectMeta{Name: "published", Namespace: "default"},
+				Spec: schedulingapiv1.SchedulerSpec{Schedules: []schedulingapiv1.Schedule{{
+					Name:           "a",
+					Image:          "busybox",
+					CronExpression: "0 * * * *",
+					Params:         []string{"--password=hunter2"},
+					Env:            []corev1.EnvVar{{Name: "TOKEN", Value: "hunter2"}},
+				}}},
+			}
+			events := reconciler.scheduleEvents(context.Background(), scheduler)
+			Expect(events).To(HaveLen(1))
+			Expect(string(events[0].Data)).To(ContainSubstring(`"cronExpression":"0 * * * *"`))
+			Expect(string(events[0].Data)).NotTo(ContainSubstring("hunter2"))
+		})
+
+		It("should publish runs starting and finishing once", func() {
+			scheduler := &schedulingapiv1.Scheduler{ObjectMeta: metav1.ObjectMeta{Name: "published", Namespace: "default"}}
+			run := func(phase schedulingapiv1.RunPhase) *schedulingapiv1.ScheduleRun {
+				return &schedulingapiv1.ScheduleRun{
+					Spec:   schedulingapiv1.ScheduleRunSpec{ScheduleName: "a", JobName: "published-a-1"},
+					Status: schedulingapiv1.ScheduleRunStatus{Phase: phase},
+				}
+			}
+			ctx := context.Background()
+			reconciler.emitRunEvents(ctx, scheduler, nil, run(schedulingapiv1.RunPhasePending))
+			reconciler.emitRunEvents(ctx, scheduler, run(schedulingapiv1.RunPhasePending), run(schedulingapiv1.RunPhaseRunning))
+			reconciler.emitRunEvents(ctx, scheduler, run(schedulingapiv1.RunPhaseRunning), run(schedulingapiv1.RunPhaseSucceeded))
+			reconciler.emitRunEvents(ctx, scheduler, run(schedulingapiv1.RunPhaseSucceeded), run(schedulingapiv1.RunPhaseSucceeded))
+			Expect(emitted).To(Equal([]string{RunStartedEvent + " published-a-1", RunSucceededEvent + " published-a-1"}))
+
+			emitted = nil
+			reconciler.emitRunEvents(ctx, scheduler, nil, run(schedulingapiv1.RunPhaseFailed))
+			reconciler.emitRunEvents(ctx, scheduler, nil, run(schedulingapiv1.RunPhaseSkipped))
+			Expect(emitted).To(Equal([]string{RunStartedEvent + " published-a-1", RunFailedEvent + " published-a-1"}))
+		})
+	})
+
+	Context("When reconciles are traced", func() {
+		It("should pass the trace context to the containers of Jobs, behind their own variables", func() {
+			podSpec := corev1.PodSpec{
+				InitContainers: []corev1.Container{{Name: "preconditions"}},
+				Containers: []corev1.Container{{
+					Name: "job",
+					Env:  []corev1.EnvVar{{Name: "TRACEPARENT", Value: "set-by-schedule"}},
+				}},
+			}
+			injectEnv(&podSpec, corev1.EnvVar{Name: "TRACEPARENT", Value: "00-trace-span-01"})
+			Expect(podSpec.InitContainers[0].Env).To(Equal([]corev1.EnvVar{{Name: "TRACEPARENT", Value: "00-trace-span-01"}}))
+			Expect(podSpec.Containers[0].Env).To(Equal([]corev1.EnvVar{
+				{Name: "TRACEPARENT", Value: "00-trace-span-01"},
+				{Name: "TRACEPARENT", Value: "set-by-schedule"},
+			}))
+		})
+
+		It("should record the errors of traced steps", func() {
+			recorder := tracetest.NewSpanRecorder()
+			provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+			DeferCleanup(provider.Shutdown, context.Background())
+			ctx, span := provider.Tracer("test").Start(context.Background(), "Reconcile")
+
+			err := traced(ctx, "Get CronJob", func(context.Context) error { return errors.NewBadRequest("invalid") })
+			Expect(err).To(HaveOccurred())
+			span.End()
+
+			spans := recorder.Ended()
+			Expect(spans).To(HaveLen(2))
+			Expect(spans[0].Name()).To(Equal("Get CronJob"))
+			Expect(spans[0].Parent().SpanID()).To(Equal(span.SpanContext().SpanID()))
+			Expect(spans[0].Status().Code).To(Equal(codes.Error))
+		})
+	})
 })
+
+// emitterFunc adapts a function to a CloudEventEmitter.
+type emitterFunc func(event cloudevents.Event) bool
+
+func (f emitterFunc) Emit(event cloudevents.Event) bool {
+	return f(event)
+}
+
+// dispatcherFunc adapts a function to a NotificationDispatcher.
+type dispatcherFunc func(sender notify.Sender, n notify.Notification, done func(err error)) bool
+
+func (f dispatcherFunc) Dispatch(sender notify.Sender, n notify.Notification, done func(err error)) bool {
+	return f(sender, n, done)
+}
@@ -0,0 +1,163 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+)
+
+// pendingListLimit is the number of queued runs listed in the status of a SchedulerQuota.
+const pendingListLimit = 20
+
+// SchedulerQuotaReconciler releases the Jobs of Schedulers queued by SchedulerQuotas
+type SchedulerQuotaReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+// Reconcile releases queued Jobs in the namespace of a SchedulerQuota, highest priority
+// first, while fewer Jobs than the smallest limit of its quotas are running. Once the last
+// quota of a namespace is deleted, every queued Job is released.
+func (r *SchedulerQuotaReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	var quotas schedulingapiv1.SchedulerQuotaList
+	if err := r.List(ctx, &quotas, client.InNamespace(req.Namespace)); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list SchedulerQuotas: %w", err)
+	}
+	var limit *int32
+	var quota *schedulingapiv1.SchedulerQuota
+	for i := range quotas.Items {
+		if limit == nil || quotas.Items[i].Spec.MaxRunning < *limit {
+			limit = &quotas.Items[i].Spec.MaxRunning
+		}
+		if quotas.Items[i].Name == req.Name {
+			quota = &quotas.Items[i]
+		}
+	}
+
+	var jobs batchv1.JobList
+	if err := r.List(ctx, &jobs, client.InNamespace(req.Namespace), client.MatchingLabels{"app": "scheduler-controller"}); err != nil {
+		return ctrl.Result{}, fmt.Errorf("failed to list Jobs: %w", err)
+	}
+	var running int32
+	var queued []*batchv1.Job
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if finished, _ := jobFinished(job); finished || job.DeletionTimestamp != nil {
+			continue
+		}
+		switch {
+		case isQueued(job):
+			queued = append(queued, job)
+		case !ptr.Deref(job.Spec.Suspend, false):
+			running++
+		}
+	}
+	sort.SliceStable(queued, func(i, j int) bool {
+		if pi, pj := jobPriority(queued[i]), jobPriority(queued[j]); pi != pj {
+			return pi > pj
+		}
+		if !queued[i].CreationTimestamp.Equal(&queued[j].CreationTimestamp) {
+			return queued[i].CreationTimestamp.Before(&queued[j].CreationTimestamp)
+		}
+		return queued[i].Name < queued[j].Name
+	})
+
+	for len(queued) > 0 && (limit == nil || running < *limit) {
+		job := queued[0]
+		log.Info("Releasing queued Job", "name", job.Name, "running", running)
+		patch := client.MergeFrom(job.DeepCopy())
+		job.Spec.Suspend = ptr.To(false)
+		delete(job.Annotations, schedulingapiv1.QueuedAnnotation)
+		if err := r.Patch(ctx, job, patch); client.IgnoreNotFound(err) != nil {
+			return ctrl.Result{}, fmt.Errorf("failed to release Job %s: %w", job.Name, err)
+		}
+		queued = queued[1:]
+		running++
+	}
+
+	if quota == nil {
+		return ctrl.Result{}, nil
+	}
+	status := schedulingapiv1.SchedulerQuotaStatus{
+		Running:            running,
+		Queued:             int32(len(queued)),
+		ObservedGeneration: quota.Generation,
+	}
+	for i := 0; i < len(queued) && i < pendingListLimit; i++ {
+		status.Pending = append(status.Pending, schedulingapiv1.QueuedRun{
+			JobName:     queued[i].Name,
+			Scheduler:   queued[i].Labels["scheduler"],
+			Schedule:    queued[i].Labels["schedule"],
+			Priority:    jobPriority(queued[i]),
+			QueuedSince: queued[i].CreationTimestamp,
+		})
+	}
+	if !equality.Semantic.DeepEqual(status, quota.Status) {
+		quota.Status = status
+		if err := r.Status().Update(ctx, quota); err != nil {
+			if apierrors.IsConflict(err) {
+				return ctrl.Result{Requeue: true}, nil
+			}
+			return ctrl.Result{}, fmt.Errorf("failed to update SchedulerQuota status: %w", err)
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *SchedulerQuotaReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&schedulingapiv1.SchedulerQuota{}).
+		Watches(&batchv1.Job{}, handler.EnqueueRequestsFromMapFunc(r.jobToQuotas)).
+		Named("schedulerquota").
+		Complete(r)
+}
+
+// jobToQuotas maps a Job created for a schedule to the SchedulerQuotas of its namespace.
+func (r *SchedulerQuotaReconciler) jobToQuotas(ctx context.Context, obj client.Object) []reconcile.Request {
+	if obj.GetLabels()["app"] != "scheduler-controller" {
+		return nil
+	}
+	var quotas schedulingapiv1.SchedulerQuotaList
+	if err := r.List(ctx, &quotas, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list SchedulerQuotas for Job", "job", obj.GetName())
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, quota := range quotas.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+			Namespace: quota.Namespace,
+			Name:      quota.Name,
+		}})
+	}
+	return requests
+}
+
+// isQueued reports whether a Job is suspended waiting for a SchedulerQuota.
+func isQueued(job *batchv1.Job) bool {
+	_, ok := job.Annotations[schedulingapiv1.QueuedAnnotation]
+	return ok && ptr.Deref(job.Spec.Suspend, false)
+}
+
+// jobPriority returns the priority of the schedule of a Job.
+func jobPriority(job *batchv1.Job) int32 {
+	priority, _ := strconv.ParseInt(job.Annotations[schedulingapiv1.PriorityAnnotation], 10, 32)
+	return int32(priority)
+}
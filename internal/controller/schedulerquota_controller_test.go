@@ -0,0 +1,113 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+)
+
+var _ = Describe("SchedulerQuota Controller", func() {
+	Context("When Jobs are queued for a quota", func() {
+		const resourceName = "test-quota"
+
+		ctx := context.Background()
+
+		typeNamespacedName := types.NamespacedName{
+			Name:      resourceName,
+			Namespace: "default",
+		}
+
+		queuedJob := func(name string, priority string) *batchv1.Job {
+			return &batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: "default",
+					Labels:    map[string]string{"app": "scheduler-controller", "scheduler": "quota-scheduler", "schedule": name},
+					Annotations: map[string]string{
+						schedulingapiv1.QueuedAnnotation:   "true",
+						schedulingapiv1.PriorityAnnotation: priority,
+					},
+				},
+				Spec: batchv1.JobSpec{
+					Suspend: ptr.To(true),
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							RestartPolicy: corev1.RestartPolicyNever,
+							Containers:    []corev1.Container{{Name: "job", Image: "busybox"}},
+						},
+					},
+				},
+			}
+		}
+
+		BeforeEach(func() {
+			By("creating a quota and two queued Jobs")
+			Expect(k8sClient.Create(ctx, &schedulingapiv1.SchedulerQuota{
+				ObjectMeta: metav1.ObjectMeta{Name: resourceName, Namespace: "default"},
+				Spec:       schedulingapiv1.SchedulerQuotaSpec{MaxRunning: 1},
+			})).To(Succeed())
+			Expect(k8sClient.Create(ctx, queuedJob("quota-low", "1"))).To(Succeed())
+			Expect(k8sClient.Create(ctx, queuedJob("quota-high", "10"))).To(Succeed())
+		})
+
+		AfterEach(func() {
+			quota := &schedulingapiv1.SchedulerQuota{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, quota)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, quota)).To(Succeed())
+			Expect(k8sClient.DeleteAllOf(ctx, &batchv1.Job{}, client.InNamespace("default"),
+				client.MatchingLabels{"scheduler": "quota-scheduler"},
+				client.PropagationPolicy(metav1.DeletePropagationBackground))).To(Succeed())
+		})
+
+		It("should release the Job of higher priority first", func() {
+			controllerReconciler := &SchedulerQuotaReconciler{
+				Client: k8sClient,
+				Scheme: k8sClient.Scheme(),
+			}
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: typeNamespacedName})
+			Expect(err).NotTo(HaveOccurred())
+
+			high := &batchv1.Job{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "quota-high", Namespace: "default"}, high)).To(Succeed())
+			Expect(high.Spec.Suspend).To(HaveValue(BeFalse()))
+			Expect(high.Annotations).NotTo(HaveKey(schedulingapiv1.QueuedAnnotation))
+
+			low := &batchv1.Job{}
+			Expect(k8sClient.Get(ctx, types.NamespacedName{Name: "quota-low", Namespace: "default"}, low)).To(Succeed())
+			Expect(low.Spec.Suspend).To(HaveValue(BeTrue()))
+
+			quota := &schedulingapiv1.SchedulerQuota{}
+			Expect(k8sClient.Get(ctx, typeNamespacedName, quota)).To(Succeed())
+			Expect(quota.Status.Running).To(Equal(int32(1)))
+			Expect(quota.Status.Queued).To(Equal(int32(1)))
+			Expect(quota.Status.Pending).To(HaveLen(1))
+			Expect(quota.Status.Pending[0].JobName).To(Equal("quota-low"))
+		})
+	})
+})
@@ -0,0 +1,331 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cronjobbuilder"
+)
+
+// defaultRunHistoryLimit is the number of ScheduleRuns kept per schedule when the
+// Scheduler does not set a RunHistory limit.
+const defaultRunHistoryLimit = 100
+
+// runRecord is a ScheduleRun that exists, should exist, or both.
+type runRecord struct {
+	name     string
+	schedule string
+	at       time.Time
+	existing *schedulingapiv1.ScheduleRun
+	desired  *schedulingapiv1.ScheduleRun
+
+	// pod is the pod of the Job whose output is captured.
+	pod *corev1.Pod
+}
+
+// finished reports whether the run of a record has ended, which makes it subject to
+// the retention of the run history.
+func (rec runRecord) finished() bool {
+	if rec.desired != nil {
+		return scheduleRunFinished(rec.desired)
+	}
+	return scheduleRunFinished(rec.existing)
+}
+
+// scheduleRunFinished reports whether a recorded run has ended.
+func scheduleRunFinished(run *schedulingapiv1.ScheduleRun) bool {
+	return run.Status.CompletionTime != nil || run.Status.Phase == schedulingapiv1.RunPhaseSkipped
+}
+
+// endTime returns when the run of a finished record ended, or was skipped.
+func (rec runRecord) endTime() time.Time {
+	current := rec.desired
+	if current == nil {
+		current = rec.existing
+	}
+	if current.Status.CompletionTime != nil {
+		return current.Status.CompletionTime.Time
+	}
+	return rec.at
+}
+
+// recordScheduleRuns creates a ScheduleRun for every run of the Scheduler, keeps those
+// of running Jobs up to date and removes the finished ones that fall out of the run
+// history. Runs of removed Jobs keep their last recorded state.
+func (r *SchedulerReconciler) recordScheduleRuns(ctx context.Context, scheduler *schedulingapiv1.Scheduler, jobs []batchv1.Job) error {
+	var existing schedulingapiv1.ScheduleRunList
+	if err := r.List(ctx, &existing, client.InNamespace(scheduler.Namespace), client.MatchingLabels{"scheduler": scheduler.Name}); err != nil {
+		return fmt.Errorf("failed to list ScheduleRuns: %w", err)
+	}
+	podsByJob, err := r.listPodsByJob(ctx, scheduler)
+	if err != nil {
+		return err
+	}
+
+	schedules := map[string]schedulingapiv1.Schedule{}
+	for _, schedule := range scheduler.Spec.Schedules {
+		schedules[schedule.Name] = schedule
+	}
+
+	records := map[string]*runRecord{}
+	for i := range existing.Items {
+		run := &existing.Items[i]
+		at := run.CreationTimestamp.Time
+		if run.Spec.ScheduledTime != nil {
+			at = run.Spec.ScheduledTime.Time
+		}
+		records[run.Name] = &runRecord{name: run.Name, schedule: run.Spec.ScheduleName, at: at, existing: run}
+	}
+	desire := func(run *schedulingapiv1.ScheduleRun, at time.Time) *runRecord {
+		rec, ok := records[run.Name]
+		if !ok {
+			rec = &runRecord{name: run.Name, schedule: run.Spec.ScheduleName, at: at}
+			records[run.Name] = rec
+		}
+		rec.desired = run
+		return rec
+	}
+
+	steps := indexStepJobs(jobs)
+	for i := range jobs {
+		job := &jobs[i]
+		if isStepJob(job) {
+			continue
+		}
+		run := scheduleRunFor(scheduler, schedules[job.Labels["schedule"]], job, podsByJob, steps)
+		at := job.CreationTimestamp.Time
+		if run.Spec.ScheduledTime != nil {
+			at = run.Spec.ScheduledTime.Time
+		}
+		rec := desire(run, at)
+		rec.pod, _ = lastTermination(podsByJob[job.Name])
+	}
+	// Runs skipped by the controller have no Job and are only known from the status
+	for _, status := range scheduler.Status.Schedules {
+		for _, run := range status.Runs {
+			if run.Phase == schedulingapiv1.RunPhaseSkipped && run.ScheduledTime != nil {
+				if _, ok := records[run.JobName]; !ok {
+					desire(newScheduleRun(scheduler, status.Name, run), run.ScheduledTime.Time)
+				}
+			}
+		}
+	}
+
+	limit, ttl := runHistoryRetention(scheduler)
+	keep, drop := retainRuns(records, limit, ttl, r.now())
+
+	var errs []error
+	for _, rec := range drop {
+		if rec.existing == nil {
+			continue
+		}
+		if err := r.Delete(ctx, rec.existing); err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to delete ScheduleRun %s: %w", rec.name, err))
+			continue
+		}
+		// Without a run history, runs are removed as soon as they finish
+		if rec.desired != nil {
+			r.emitRunEvents(ctx, scheduler, rec.existing, rec.desired)
+		}
+	}
+	for _, rec := range keep {
+		// Logs are captured once, when the run is first seen finished
+		if rec.desired != nil && rec.pod != nil && rec.finished() && (rec.existing == nil || !scheduleRunFinished(rec.existing)) {
+			if schedule := schedules[rec.schedule]; capturesLogs(schedule, rec.desired.Status.Phase) {
+				rec.desired.Status.Logs = r.captureLogs(ctx, schedule, rec.pod)
+			}
+		}
+		if err := r.applyScheduleRun(ctx, scheduler, rec); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// applyScheduleRun creates the ScheduleRun of a record, or updates its status.
+func (r *SchedulerReconciler) applyScheduleRun(ctx context.Context, scheduler *schedulingapiv1.Scheduler, rec *runRecord) error {
+	if rec.desired == nil {
+		return nil
+	}
+	if rec.existing != nil {
+		// Pods are removed before their Job, so keep what was recorded from them
+		if rec.desired.Status.ExitCode == nil {
+			rec.desired.Status.ExitCode = rec.existing.Status.ExitCode
+			rec.desired.Status.TerminationMessage = rec.existing.Status.TerminationMessage
+			rec.desired.Status.Outputs = rec.existing.Status.Outputs
+		}
+		if rec.desired.Status.Logs == "" {
+			rec.desired.Status.Logs = rec.existing.Status.Logs
+		}
+		if rec.desired.Status.NodeName == "" {
+			rec.desired.Status.NodeName = rec.existing.Status.NodeName
+		}
+		if equality.Semantic.DeepEqual(rec.existing.Status, rec.desired.Status) {
+			return nil
+		}
+		previous := rec.existing.DeepCopy()
+		rec.existing.Status = rec.desired.Status
+		if err := r.Status().Update(ctx, rec.existing); err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil
+			}
+			return fmt.Errorf("failed to update ScheduleRun %s: %w", rec.name, err)
+		}
+		r.emitRunEvents(ctx, scheduler, previous, rec.existing)
+		return nil
+	}
+
+	run := rec.desired
+	status := run.Status
+	if err := ctrl.SetControllerReference(scheduler, run, r.Scheme); err != nil {
+		return fmt.Errorf("failed to set owner of ScheduleRun %s: %w", rec.name, err)
+	}
+	if err := r.Create(ctx, run); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			// The cache has not seen it yet; the next reconcile updates it
+			return nil
+		}
+		return fmt.Errorf("failed to create ScheduleRun %s: %w", rec.name, err)
+	}
+	log.FromContext(ctx).Info("Recorded run", "scheduleRun", run.Name)
+	run.Status = status
+	if err := r.Status().Update(ctx, run); err != nil {
+		return fmt.Errorf("failed to update ScheduleRun %s: %w", rec.name, err)
+	}
+	r.emitRunEvents(ctx, scheduler, nil, run)
+	return nil
+}
+
+// runHistoryRetention returns how many ScheduleRuns of each schedule a Scheduler keeps
+// and for how long, zero meaning forever.
+func runHistoryRetention(scheduler *schedulingapiv1.Scheduler) (int32, time.Duration) {
+	limit := int32(defaultRunHistoryLimit)
+	var ttl time.Duration
+	if history := scheduler.Spec.RunHistory; history != nil {
+		if history.Limit != nil {
+			limit = *history.Limit
+		}
+		if history.TTL != nil {
+			ttl = history.TTL.Duration
+		}
+	}
+	return limit, ttl
+}
+
+// retainRuns splits records between those kept in the run history and those removed
+// from it: the finished runs of a schedule beyond its limit most recent runs, and those
+// that ended more than ttl ago. Runs that have not finished are always kept.
+func retainRuns(records map[string]*runRecord, limit int32, ttl time.Duration, now time.Time) (keep, drop []*runRecord) {
+	sorted := make([]*runRecord, 0, len(records))
+	for _, rec := range records {
+		sorted = append(sorted, rec)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if !sorted[i].at.Equal(sorted[j].at) {
+			return sorted[i].at.After(sorted[j].at)
+		}
+		return sorted[i].name > sorted[j].name
+	})
+
+	kept := map[string]int32{}
+	for _, rec := range sorted {
+		expired := ttl > 0 && now.Sub(rec.endTime()) > ttl
+		if rec.finished() && (kept[rec.schedule] >= limit || expired) {
+			drop = append(drop, rec)
+			continue
+		}
+		kept[rec.schedule]++
+		keep = append(keep, rec)
+	}
+	return keep, drop
+}
+
+// scheduleRunFor returns the ScheduleRun recording the run executed by a Job, with the
+// termination message filtered as configured for its schedule and the outputs it holds.
+func scheduleRunFor(scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule, job *batchv1.Job, podsByJob map[string][]corev1.Pod, steps stepIndex) *schedulingapiv1.ScheduleRun {
+	pods := podsByJob[job.Name]
+	status := runStatus(job, pods)
+	applySteps(&status, job, podsByJob, steps)
+
+	run := newScheduleRun(scheduler, job.Labels["schedule"], status)
+	run.Spec.Attempt = cronjobbuilder.JobAttempt(job)
+	run.Status.StartTime = status.StartTime
+	run.Status.CompletionTime = status.CompletionTime
+	if pod, terminated := lastTermination(pods); pod != nil {
+		run.Status.NodeName = pod.Spec.NodeName
+		if terminated != nil {
+			filter := newOutputFilter(schedule)
+			run.Status.ExitCode = ptr.To(terminated.ExitCode)
+			run.Status.TerminationMessage = filter.apply(terminated.Message)
+			run.Status.Outputs = parseOutputs(terminated.Message, filter)
+		}
+	}
+	return run
+}
+
+// newScheduleRun returns a ScheduleRun for the run of a schedule.
+func newScheduleRun(scheduler *schedulingapiv1.Scheduler, schedule string, status schedulingapiv1.RunStatus) *schedulingapiv1.ScheduleRun {
+	return &schedulingapiv1.ScheduleRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      status.JobName,
+			Namespace: scheduler.Namespace,
+			Labels:    cronjobbuilder.Labels(scheduler, schedulingapiv1.Schedule{Name: schedule}),
+		},
+		Spec: schedulingapiv1.ScheduleRunSpec{
+			SchedulerName: scheduler.Name,
+			ScheduleName:  schedule,
+			JobName:       status.JobName,
+			ScheduledTime: status.ScheduledTime,
+			Trigger:       status.Trigger,
+		},
+		Status: schedulingapiv1.ScheduleRunStatus{
+			Phase:  status.Phase,
+			Reason: status.Reason,
+		},
+	}
+}
+
+// lastTermination returns the most recently created pod of a run and the last
+// termination of its job container, if any.
+func lastTermination(pods []corev1.Pod) (*corev1.Pod, *corev1.ContainerStateTerminated) {
+	var last *corev1.Pod
+	for i := range pods {
+		if last == nil || last.CreationTimestamp.Before(&pods[i].CreationTimestamp) {
+			last = &pods[i]
+		}
+	}
+	var terminated *corev1.ContainerStateTerminated
+	var terminatedPod *corev1.Pod
+	for i := range pods {
+		for _, cs := range pods[i].Status.ContainerStatuses {
+			t := cs.State.Terminated
+			if cs.Name != cronjobbuilder.JobContainerName || t == nil {
+				continue
+			}
+			if terminated == nil || terminated.FinishedAt.Before(&t.FinishedAt) {
+				terminated = t
+				terminatedPod = &pods[i]
+			}
+		}
+	}
+	if terminatedPod != nil {
+		return terminatedPod, terminated
+	}
+	return last, nil
+}
@@ -0,0 +1,294 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cronjobbuilder"
+)
+
+// stepIndex indexes the Jobs of steps run in Jobs mode by the Job of their run and step.
+type stepIndex map[string]map[string]*batchv1.Job
+
+// reconcileSteps advances the pipelines of runs in Jobs mode: the Job of each step is
+// started once the previous one has finished, and the outcome of the pipeline is recorded
+// on the Job of the run.
+func (r *SchedulerReconciler) reconcileSteps(ctx context.Context, scheduler *schedulingapiv1.Scheduler) []error {
+	log := log.FromContext(ctx)
+	var jobs batchv1.JobList
+	if err := r.List(ctx, &jobs, client.InNamespace(scheduler.Namespace), client.MatchingLabels{"scheduler": scheduler.Name}); err != nil {
+		return []error{fmt.Errorf("failed to list Jobs for steps: %w", err)}
+	}
+	steps := indexStepJobs(jobs.Items)
+
+	var reconcileErrors []error
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		names, ok := job.Annotations[schedulingapiv1.StepsAnnotation]
+		if !ok || isStepJob(job) || job.Annotations[schedulingapiv1.PipelinePhaseAnnotation] != "" {
+			continue
+		}
+		// Runs of removed schedules are left as they are, until the Jobs are cleaned up
+		var schedule *schedulingapiv1.Schedule
+		for j := range scheduler.Spec.Schedules {
+			if scheduler.Spec.Schedules[j].Name == job.Labels["schedule"] {
+				schedule = &scheduler.Spec.Schedules[j]
+			}
+		}
+		if schedule == nil {
+			continue
+		}
+		if err := r.advancePipeline(ctx, scheduler, *schedule, job, strings.Split(names, ","), steps[job.Name]); err != nil {
+			log.Error(err, "Failed to advance pipeline", "run", job.Name)
+			reconcileErrors = append(reconcileErrors, err)
+		}
+	}
+	return reconcileErrors
+}
+
+// advancePipeline starts the next step of a run once the previous one has finished, and
+// records the outcome of the pipeline once a step failed without ContinueOnError or the
+// last step finished.
+func (r *SchedulerReconciler) advancePipeline(ctx context.Context, scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule, runJob *batchv1.Job, names []string, stepJobs map[string]*batchv1.Job) error {
+	for i, name := range names {
+		job := runJob
+		if i > 0 {
+			if job = stepJobs[name]; job == nil {
+				return r.startStep(ctx, scheduler, schedule, runJob, name)
+			}
+		}
+		finished, condition := jobFinished(job)
+		if !finished {
+			return nil
+		}
+		if condition == batchv1.JobFailed && !continuesOnError(schedule, name) {
+			return r.finishPipeline(ctx, runJob, schedulingapiv1.RunPhaseFailed)
+		}
+	}
+	return r.finishPipeline(ctx, runJob, schedulingapiv1.RunPhaseSucceeded)
+}
+
+// startStep creates the Job of a step of a run, owned by the Job of the run so that it is
+// deleted with it. A step removed from the schedule fails the pipeline.
+func (r *SchedulerReconciler) startStep(ctx context.Context, scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule, runJob *batchv1.Job, step string) error {
+	log := log.FromContext(ctx)
+	run := cronjobbuilder.RunInfo{
+		ScheduledTime: runJob.CreationTimestamp.Time,
+		Trigger:       cronjobbuilder.TriggerCron,
+		Attempt:       cronjobbuilder.JobAttempt(runJob),
+	}
+	if scheduledTime := jobScheduledTime(runJob); scheduledTime != nil {
+		run.ScheduledTime = scheduledTime.Time
+	}
+	if trigger, ok := runJob.Labels[schedulingapiv1.TriggerLabel]; ok {
+		run.Trigger = trigger
+	}
+
+	outputs, err := r.runOutputs(ctx, scheduler, run.ScheduledTime)
+	if err != nil {
+		return err
+	}
+	run.Outputs = outputs
+
+	job, err := cronjobbuilder.BuildStepJob(scheduler, schedule, runJob, step, run)
+	if err != nil {
+		log.Error(err, "Failing pipeline", "run", runJob.Name, "step", step)
+		return r.finishPipeline(ctx, runJob, schedulingapiv1.RunPhaseFailed)
+	}
+	if err := ctrl.SetControllerReference(runJob, job, r.Scheme); err != nil {
+		return fmt.Errorf("failed to set owner reference for Job %s: %w", job.Name, err)
+	}
+
+	if err := r.queueForQuota(ctx, job); err != nil {
+		return err
+	}
+
+	log.Info("Creating step Job", "name", job.Name, "run", runJob.Name, "step", step)
+	if err := r.createJob(ctx, job); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create Job %s: %w", job.Name, err)
+	}
+	return nil
+}
+
+// finishPipeline records the outcome of the pipeline of a run on its Job.
+func (r *SchedulerReconciler) finishPipeline(ctx context.Context, runJob *batchv1.Job, phase schedulingapiv1.RunPhase) error {
+	log.FromContext(ctx).Info("Pipeline finished", "run", runJob.Name, "phase", phase)
+	patch := client.MergeFrom(runJob.DeepCopy())
+	runJob.Annotations[schedulingapiv1.PipelinePhaseAnnotation] = string(phase)
+	if err := r.Patch(ctx, runJob, patch); client.IgnoreNotFound(err) != nil {
+		return fmt.Errorf("failed to record outcome of Job %s: %w", runJob.Name, err)
+	}
+	return nil
+}
+
+// continuesOnError reports whether the pipeline goes on after the given step failed.
+func continuesOnError(schedule schedulingapiv1.Schedule, step string) bool {
+	for _, s := range schedule.Steps {
+		if s.Name == step {
+			return s.ContinueOnError
+		}
+	}
+	return false
+}
+
+// runFinished reports whether the run of a Job has succeeded or failed, and which of the
+// two. The run of a pipeline in Jobs mode finishes with its last step.
+func runFinished(job *batchv1.Job) (bool, batchv1.JobConditionType) {
+	if _, ok := job.Annotations[schedulingapiv1.StepsAnnotation]; !ok {
+		return jobFinished(job)
+	}
+	switch schedulingapiv1.RunPhase(job.Annotations[schedulingapiv1.PipelinePhaseAnnotation]) {
+	case schedulingapiv1.RunPhaseSucceeded:
+		return true, batchv1.JobComplete
+	case schedulingapiv1.RunPhaseFailed:
+		return true, batchv1.JobFailed
+	}
+	return false, ""
+}
+
+// isStepJob reports whether a Job runs a step of a run in Jobs mode, rather than a run.
+func isStepJob(job *batchv1.Job) bool {
+	_, ok := job.Labels[schedulingapiv1.StepLabel]
+	return ok
+}
+
+// indexStepJobs indexes the Jobs of steps by the Job of their run and step.
+func indexStepJobs(jobs []batchv1.Job) stepIndex {
+	steps := stepIndex{}
+	for i := range jobs {
+		job := &jobs[i]
+		if !isStepJob(job) {
+			continue
+		}
+		run := job.Labels[schedulingapiv1.RunLabel]
+		if steps[run] == nil {
+			steps[run] = map[string]*batchv1.Job{}
+		}
+		steps[run][job.Labels[schedulingapiv1.StepLabel]] = job
+	}
+	return steps
+}
+
+// applySteps adds the steps of a run to its status. In Jobs mode, the phase of the run is
+// that of its pipeline.
+func applySteps(run *schedulingapiv1.RunStatus, job *batchv1.Job, podsByJob map[string][]corev1.Pod, steps stepIndex) {
+	if names, ok := job.Annotations[schedulingapiv1.StepsAnnotation]; ok {
+		run.Steps = nil
+		var completed *metav1.Time
+		for i, name := range strings.Split(names, ",") {
+			stepJob := job
+			if i > 0 {
+				stepJob = steps[job.Name][name]
+			}
+			step := schedulingapiv1.StepStatus{Name: name, Phase: schedulingapiv1.RunPhasePending}
+			if stepJob != nil {
+				stepRun := runStatus(stepJob, podsByJob[stepJob.Name])
+				step.JobName = stepJob.Name
+				step.Phase = stepRun.Phase
+				step.Retries = stepJob.Status.Failed + restarts(podsByJob[stepJob.Name], cronjobbuilder.JobContainerName, false)
+				if stepRun.CompletionTime != nil && (completed == nil || completed.Before(stepRun.CompletionTime)) {
+					completed = stepRun.CompletionTime
+				}
+			}
+			run.Steps = append(run.Steps, step)
+		}
+
+		switch phase := schedulingapiv1.RunPhase(job.Annotations[schedulingapiv1.PipelinePhaseAnnotation]); phase {
+		case schedulingapiv1.RunPhaseSucceeded, schedulingapiv1.RunPhaseFailed:
+			run.Phase = phase
+			run.CompletionTime = completed
+		default:
+			if run.Phase == schedulingapiv1.RunPhaseSucceeded || run.Phase == schedulingapiv1.RunPhaseFailed {
+				run.Phase = schedulingapiv1.RunPhaseRunning
+			}
+			run.CompletionTime = nil
+		}
+		return
+	}
+
+	// In InitContainers mode, the steps are reported from the containers of the newest pod
+	var names []string
+	for _, c := range job.Spec.Template.Spec.InitContainers {
+		if name, ok := strings.CutPrefix(c.Name, cronjobbuilder.StepContainerPrefix); ok {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	pods := podsByJob[job.Name]
+	var newest *corev1.Pod
+	for i := range pods {
+		if newest == nil || newest.CreationTimestamp.Before(&pods[i].CreationTimestamp) {
+			newest = &pods[i]
+		}
+	}
+	run.Steps = nil
+	for _, name := range append(names, schedulingapiv1.MainStep) {
+		container, init := cronjobbuilder.StepContainerPrefix+name, true
+		if name == schedulingapiv1.MainStep {
+			container, init = cronjobbuilder.JobContainerName, false
+		}
+		step := schedulingapiv1.StepStatus{
+			Name:    name,
+			Phase:   schedulingapiv1.RunPhasePending,
+			Retries: restarts(pods, container, init),
+		}
+		if newest != nil {
+			step.Phase = containerPhase(newest, container, init)
+		}
+		run.Steps = append(run.Steps, step)
+	}
+}
+
+// containerPhase returns the lifecycle phase of a container of a pod.
+func containerPhase(pod *corev1.Pod, container string, init bool) schedulingapiv1.RunPhase {
+	statuses := pod.Status.ContainerStatuses
+	if init {
+		statuses = pod.Status.InitContainerStatuses
+	}
+	for _, cs := range statuses {
+		if cs.Name != container {
+			continue
+		}
+		switch {
+		case cs.State.Running != nil:
+			return schedulingapiv1.RunPhaseRunning
+		case cs.State.Terminated != nil && cs.State.Terminated.ExitCode == 0:
+			return schedulingapiv1.RunPhaseSucceeded
+		case cs.State.Terminated != nil:
+			return schedulingapiv1.RunPhaseFailed
+		case cs.RestartCount > 0:
+			// Waiting to be restarted after a failure
+			return schedulingapiv1.RunPhaseRunning
+		}
+	}
+	return schedulingapiv1.RunPhasePending
+}
+
+// restarts counts the restarts of a container across pods.
+func restarts(pods []corev1.Pod, container string, init bool) int32 {
+	var count int32
+	for _, pod := range pods {
+		statuses := pod.Status.ContainerStatuses
+		if init {
+			statuses = pod.Status.InitContainerStatuses
+		}
+		for _, cs := range statuses {
+			if cs.Name == container {
+				count += cs.RestartCount
+			}
+		}
+	}
+	return count
+}
@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/tracing"
+)
+
+// tracerName is the name of the tracer of reconciles.
+const tracerName = "github.com/lorenzorottigni/k8s-cj-scheduler/internal/controller"
+
+// startSpan starts a span in the trace of ctx, with the tracer provider of its span, or
+// a root span with the global provider. Spans are dropped unless tracing is set up.
+func startSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	tracer := otel.Tracer(tracerName)
+	if parent := trace.SpanFromContext(ctx); parent.SpanContext().IsValid() {
+		tracer = parent.TracerProvider().Tracer(tracerName)
+	}
+	return tracer.Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// Attributes of the spans of reconciles.
+const (
+	namespaceAttribute = attribute.Key("lr.labs.namespace")
+	schedulerAttribute = attribute.Key("lr.labs.scheduler")
+	scheduleAttribute  = attribute.Key("lr.labs.schedule")
+	modeAttribute      = attribute.Key("lr.labs.schedule.mode")
+	jobAttribute       = attribute.Key("lr.labs.job")
+)
+
+// startScheduleSpan starts the span of the reconcile of a schedule.
+func startScheduleSpan(ctx context.Context, schedule schedulingapiv1.Schedule) (context.Context, trace.Span) {
+	mode := string(schedule.Mode)
+	if firedByController(schedule) {
+		mode = string(schedulingapiv1.ScheduleModeNative)
+	}
+	return startSpan(ctx, "Schedule", scheduleAttribute.String(schedule.Name), modeAttribute.String(mode))
+}
+
+// traced runs fn in a child span of ctx, recording the error it returns.
+func traced(ctx context.Context, name string, fn func(ctx context.Context) error, attributes ...attribute.KeyValue) error {
+	ctx, span := startSpan(ctx, name, attributes...)
+	defer span.End()
+	err := fn(ctx)
+	recordError(span, err)
+	return err
+}
+
+// recordError marks a span failed with err, if any.
+func recordError(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// createJob creates a Job in a span of its own, whose trace context the containers of
+// the Job receive in TRACEPARENT so that the spans of the job join the trace.
+func (r *SchedulerReconciler) createJob(ctx context.Context, job *batchv1.Job) error {
+	return traced(ctx, "Create Job", func(ctx context.Context) error {
+		if traceParent := tracing.TraceParent(ctx); traceParent != "" {
+			injectEnv(&job.Spec.Template.Spec, corev1.EnvVar{Name: tracing.TraceParentEnv, Value: traceParent})
+		}
+		return r.Create(ctx, job)
+	}, jobAttribute.String(job.Name))
+}
+
+// injectEnv adds a variable ahead of the environment of every container of a pod, so
+// that a variable of the same name set by the schedule overrides it.
+func injectEnv(podSpec *corev1.PodSpec, v corev1.EnvVar) {
+	for _, containers := range [][]corev1.Container{podSpec.InitContainers, podSpec.Containers} {
+		for i := range containers {
+			containers[i].Env = append([]corev1.EnvVar{v}, containers[i].Env...)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cronjobbuilder"
+)
+
+// reconcileTrigger starts the run requested through the trigger annotation, once per nonce.
+// Suspend, blackout windows, Calendars and run limits do not apply to runs started by hand,
+// but lock groups do.
+func (r *SchedulerReconciler) reconcileTrigger(ctx context.Context, scheduler *schedulingapiv1.Scheduler) error {
+	log := log.FromContext(ctx)
+	value, ok := scheduler.Annotations[schedulingapiv1.TriggerAnnotation]
+	if !ok {
+		return nil
+	}
+
+	name, nonce, found := strings.Cut(value, "@")
+	if !found || name == "" || nonce == "" {
+		return fmt.Errorf("invalid %s annotation %q: expected <schedule>@<nonce>", schedulingapiv1.TriggerAnnotation, value)
+	}
+	if last := scheduler.Status.LastTrigger; last != nil && last.Schedule == name && last.Nonce == nonce {
+		return nil
+	}
+
+	var schedule *schedulingapiv1.Schedule
+	for i := range scheduler.Spec.Schedules {
+		if scheduler.Spec.Schedules[i].Name == name {
+			schedule = &scheduler.Spec.Schedules[i]
+		}
+	}
+	if schedule == nil {
+		return fmt.Errorf("invalid %s annotation %q: no schedule named %s", schedulingapiv1.TriggerAnnotation, value, name)
+	}
+
+	// The trigger is not recorded while the run waits for its lock group, so it is
+	// started once the lock is released
+	if waiting, err := r.waitForLock(ctx, scheduler, *schedule); err != nil || waiting {
+		return err
+	}
+
+	now := r.now()
+	triggeredBy := triggerManager(scheduler)
+	outputs, err := r.runOutputs(ctx, scheduler, now)
+	if err != nil {
+		return err
+	}
+	job, err := cronjobbuilder.BuildManualJob(scheduler, *schedule, nonce, triggeredBy, now, outputs)
+	if err != nil {
+		return err
+	}
+	if err := ctrl.SetControllerReference(scheduler, job, r.Scheme); err != nil {
+		return fmt.Errorf("failed to set owner reference for Job %s: %w", job.Name, err)
+	}
+
+	if err := r.queueForQuota(ctx, job); err != nil {
+		return err
+	}
+
+	// The Job name is derived from the nonce, so an existing Job means the trigger ran
+	// before its status was recorded
+	log.Info("Creating manually triggered Job", "name", job.Name, "schedule", name, "triggeredBy", triggeredBy)
+	if err := r.createJob(ctx, job); err != nil && !apierrors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create Job %s: %w", job.Name, err)
+	}
+
+	scheduler.Status.LastTrigger = &schedulingapiv1.TriggerStatus{
+		Schedule:    name,
+		Nonce:       nonce,
+		JobName:     job.Name,
+		TriggeredBy: triggeredBy,
+		Time:        metav1.Time{Time: now},
+	}
+	return nil
+}
+
+// triggerManager returns the field manager that most recently set the trigger annotation.
+func triggerManager(scheduler *schedulingapiv1.Scheduler) string {
+	var manager string
+	var latest time.Time
+	for _, entry := range scheduler.ManagedFields {
+		if entry.FieldsV1 == nil || !ownsAnnotation(entry.FieldsV1.Raw, schedulingapiv1.TriggerAnnotation) {
+			continue
+		}
+		var t time.Time
+		if entry.Time != nil {
+			t = entry.Time.Time
+		}
+		if manager == "" || t.After(latest) {
+			manager, latest = entry.Manager, t
+		}
+	}
+	return manager
+}
+
+// ownsAnnotation reports whether a managedFields entry owns the given annotation.
+func ownsAnnotation(raw []byte, annotation string) bool {
+	var fields struct {
+		Metadata struct {
+			Annotations map[string]json.RawMessage `json:"f:annotations"`
+		} `json:"f:metadata"`
+	}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return false
+	}
+	_, ok := fields.Metadata.Annotations["f:"+annotation]
+	return ok
+}
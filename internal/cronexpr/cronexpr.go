@@ -0,0 +1,515 @@
+package cronexpr
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotRepresentable is returned by ToCronJob for expressions that only the Native
+// engine can run, such as non-zero seconds or intervals that do not divide a day.
+var ErrNotRepresentable = errors.New("expression cannot be expressed as a CronJob schedule")
+
+// Schedule describes the activation times of a parsed cron expression.
+type Schedule interface {
+	// Next returns the first activation time strictly after t, or the zero time if
+	// the expression never fires again.
+	Next(t time.Time) time.Time
+}
+
+// bounds describes the accepted range and the symbolic names of a cron field.
+type bounds struct {
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	seconds = bounds{0, 59, nil}
+	minutes = bounds{0, 59, nil}
+	hours   = bounds{0, 23, nil}
+	dom     = bounds{1, 31, nil}
+	months  = bounds{1, 12, map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dow = bounds{0, 6, map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// fieldBounds lists the bounds of the six fields in order.
+var fieldBounds = [6]bounds{seconds, minutes, hours, dom, months, dow}
+
+// hashBounds are the default ranges H tokens are hashed into. Days of the month stop at
+// 28 so that a hashed day exists in every month.
+var hashBounds = [6]bounds{seconds, minutes, hours, {1, 28, nil}, months, dow}
+
+// starBit marks a field that was written as "*" or "?", which matters for the
+// day-of-month/day-of-week "or" semantics of cron.
+const starBit = 1 << 63
+
+// macros are the shorthands accepted in place of the five standard fields.
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// specSchedule is a cron expression compiled to one bit set per field.
+type specSchedule struct {
+	second, minute, hour, dom, month, dow uint64
+}
+
+// expression is a cron expression normalized to six fields, with macros expanded and
+// H tokens resolved, or a fixed interval for @every.
+type expression struct {
+	fields  [6]string
+	seconds bool
+	every   time.Duration
+}
+
+// Parse parses a cron expression. On top of the five standard fields and the @-macros it
+// accepts an optional leading seconds field, "@every <duration>" intervals and H tokens
+// ("H", "H(a-b)", "H/n", "H(a-b)/n") whose value is derived from hashKey, so that
+// schedules sharing an expression are spread over the range instead of firing together.
+func Parse(expr, hashKey string) (Schedule, error) {
+	e, err := normalize(expr, hashKey)
+	if err != nil {
+		return nil, err
+	}
+	if e.every > 0 {
+		return everySchedule{every: e.every}, nil
+	}
+
+	spec := &specSchedule{}
+	for i, target := range []*uint64{&spec.second, &spec.minute, &spec.hour, &spec.dom, &spec.month, &spec.dow} {
+		if *target, err = parseField(e.fields[i], fieldBounds[i]); err != nil {
+			return nil, fmt.Errorf("invalid field %q: %w", e.fields[i], err)
+		}
+	}
+	return spec, nil
+}
+
+// ToCronJob translates an expression accepted by Parse into the five-field syntax of a
+// Kubernetes CronJob. Expressions that cannot be translated return an error wrapping
+// ErrNotRepresentable.
+func ToCronJob(expr, hashKey string) (string, error) {
+	if _, err := Parse(expr, hashKey); err != nil {
+		return "", err
+	}
+
+	trimmed := strings.TrimSpace(expr)
+	if _, ok := macros[strings.ToLower(trimmed)]; ok {
+		return trimmed, nil
+	}
+
+	e, err := normalize(expr, hashKey)
+	if err != nil {
+		return "", err
+	}
+	if e.every > 0 {
+		return everyToCron(e.every)
+	}
+	if e.seconds && e.fields[0] != "0" {
+		return "", fmt.Errorf("%w: seconds field %q", ErrNotRepresentable, e.fields[0])
+	}
+	return strings.Join(e.fields[1:], " "), nil
+}
+
+// Stagger deterministically delays an expression by up to window, using an offset derived
+// from key. Only expressions with a single fixed minute are shifted; the carry moves the
+// hour field when it is fixed too, and the result never leaves the original day unless
+// the expression runs every day. Other expressions are returned unchanged.
+func Stagger(expr, key string, window time.Duration) (string, error) {
+	if window < time.Minute {
+		return expr, nil
+	}
+	e, err := normalize(expr, key)
+	if err != nil {
+		return "", err
+	}
+	minute, err := strconv.ParseUint(e.fields[1], 10, 8)
+	if e.every > 0 || err != nil {
+		return expr, nil
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	_, _ = h.Write([]byte("stagger"))
+	windowMinutes := uint64(min(window, 24*time.Hour) / time.Minute)
+	offset := uint64(h.Sum32()) % windowMinutes
+
+	hourSet, err := parseField(e.fields[2], hours)
+	if err != nil {
+		return "", fmt.Errorf("invalid field %q: %w", e.fields[2], err)
+	}
+
+	if carry := uint((minute + offset) / 60); carry > 0 && hourSet&starBit == 0 {
+		values := fieldValues(hourSet)
+		// Moving the hour of a schedule restricted to some days could cross midnight and
+		// change the day it runs on, so such schedules are staggered within the hour.
+		everyDay := e.fields[3] == "*" && e.fields[4] == "*" && e.fields[5] == "*"
+		if everyDay || values[len(values)-1]+carry <= 23 {
+			shifted := make([]string, 0, len(values))
+			for _, hour := range values {
+				shifted = append(shifted, strconv.FormatUint(uint64((hour+carry)%24), 10))
+			}
+			e.fields[2] = strings.Join(shifted, ",")
+		}
+	}
+	e.fields[1] = strconv.FormatUint((minute+offset)%60, 10)
+
+	if e.seconds {
+		return strings.Join(e.fields[:], " "), nil
+	}
+	return strings.Join(e.fields[1:], " "), nil
+}
+
+// normalize expands macros, adds the seconds field when omitted and resolves H tokens.
+func normalize(expr, hashKey string) (*expression, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, fmt.Errorf("empty cron expression")
+	}
+
+	if strings.HasPrefix(expr, "@every ") {
+		every, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(expr, "@every ")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval in %q: %w", expr, err)
+		}
+		if every < time.Second || every%time.Second != 0 {
+			return nil, fmt.Errorf("interval in %q must be a positive whole number of seconds", expr)
+		}
+		return &expression{every: every}, nil
+	}
+
+	if strings.HasPrefix(expr, "@") {
+		expanded, ok := macros[strings.ToLower(expr)]
+		if !ok {
+			return nil, fmt.Errorf("unrecognized descriptor %q", expr)
+		}
+		expr = expanded
+	}
+
+	e := &expression{}
+	fields := strings.Fields(expr)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+		e.seconds = true
+	default:
+		return nil, fmt.Errorf("expected 5 or 6 fields, found %d in %q", len(fields), expr)
+	}
+
+	for i, field := range fields {
+		resolved, err := resolveHash(field, i, hashKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid field %q: %w", field, err)
+		}
+		e.fields[i] = resolved
+	}
+	return e, nil
+}
+
+// resolveHash replaces the H tokens of a field with concrete values derived from the
+// hash key and the position of the field.
+func resolveHash(field string, index int, hashKey string) (string, error) {
+	if !strings.Contains(field, "H") {
+		return field, nil
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(hashKey))
+	_, _ = h.Write([]byte{byte(index)})
+	hash := uint(h.Sum32())
+
+	parts := strings.Split(field, ",")
+	for i, part := range parts {
+		if !strings.HasPrefix(part, "H") {
+			continue
+		}
+
+		rangeAndStep := strings.Split(part, "/")
+		if len(rangeAndStep) > 2 {
+			return "", fmt.Errorf("too many slashes in %q", part)
+		}
+
+		low, high := hashBounds[index].min, hashBounds[index].max
+		if spec := strings.TrimPrefix(rangeAndStep[0], "H"); spec != "" {
+			if !strings.HasPrefix(spec, "(") || !strings.HasSuffix(spec, ")") {
+				return "", fmt.Errorf("malformed hash token %q", part)
+			}
+			lowAndHigh := strings.Split(strings.Trim(spec, "()"), "-")
+			if len(lowAndHigh) != 2 {
+				return "", fmt.Errorf("hash token %q needs a range like H(0-29)", part)
+			}
+			var err error
+			if low, err = parseValue(lowAndHigh[0], fieldBounds[index]); err != nil {
+				return "", err
+			}
+			if high, err = parseValue(lowAndHigh[1], fieldBounds[index]); err != nil {
+				return "", err
+			}
+			if low < fieldBounds[index].min || high > fieldBounds[index].max || low > high {
+				return "", fmt.Errorf("invalid hash range in %q", part)
+			}
+		}
+
+		if len(rangeAndStep) == 1 {
+			parts[i] = strconv.FormatUint(uint64(low+hash%(high-low+1)), 10)
+			continue
+		}
+
+		step, err := parseUint(rangeAndStep[1])
+		if err != nil {
+			return "", err
+		}
+		if step == 0 {
+			return "", fmt.Errorf("step of %q must be positive", part)
+		}
+		// A hashed step keeps the hashed range's upper bound but uses the full field
+		// range when no explicit range was given, e.g. H/15 becomes 7-59/15.
+		if rangeAndStep[0] == "H" {
+			high = fieldBounds[index].max
+		}
+		start := low + hash%min(step, high-low+1)
+		parts[i] = fmt.Sprintf("%d-%d/%d", start, high, step)
+	}
+	return strings.Join(parts, ","), nil
+}
+
+// everyToCron translates an interval into cron fields when it divides an hour or a day
+// evenly, which is when the epoch-aligned slots of an everySchedule match cron's.
+func everyToCron(every time.Duration) (string, error) {
+	switch {
+	case every%time.Minute != 0:
+	case every < time.Hour && time.Hour%every == 0:
+		return fmt.Sprintf("*/%d * * * *", every/time.Minute), nil
+	case every == time.Hour:
+		return "0 * * * *", nil
+	case every%time.Hour == 0 && every < 24*time.Hour && (24*time.Hour)%every == 0:
+		return fmt.Sprintf("0 */%d * * *", every/time.Hour), nil
+	case every == 24*time.Hour:
+		return "0 0 * * *", nil
+	}
+	return "", fmt.Errorf("%w: interval %s does not divide an hour or a day evenly", ErrNotRepresentable, every)
+}
+
+// parseField parses a comma separated list of ranges into a bit set.
+func parseField(field string, b bounds) (uint64, error) {
+	var result uint64
+	for _, part := range strings.Split(field, ",") {
+		bits, err := parseRange(part, b)
+		if err != nil {
+			return 0, err
+		}
+		result |= bits
+	}
+	return result, nil
+}
+
+// parseRange parses "*", "?", "n", "a-b", optionally followed by "/step".
+func parseRange(expr string, b bounds) (uint64, error) {
+	rangeAndStep := strings.Split(expr, "/")
+	if len(rangeAndStep) > 2 {
+		return 0, fmt.Errorf("too many slashes in %q", expr)
+	}
+	lowAndHigh := strings.Split(rangeAndStep[0], "-")
+	if len(lowAndHigh) > 2 {
+		return 0, fmt.Errorf("too many hyphens in %q", expr)
+	}
+
+	var start, end, step uint
+	var extra uint64
+	var err error
+	if lowAndHigh[0] == "*" || lowAndHigh[0] == "?" {
+		start, end = b.min, b.max
+		extra = starBit
+	} else {
+		if start, err = parseValue(lowAndHigh[0], b); err != nil {
+			return 0, err
+		}
+		end = start
+		if len(lowAndHigh) == 2 {
+			if end, err = parseValue(lowAndHigh[1], b); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	step = 1
+	if len(rangeAndStep) == 2 {
+		if step, err = parseUint(rangeAndStep[1]); err != nil {
+			return 0, err
+		}
+		if step == 0 {
+			return 0, fmt.Errorf("step of %q must be positive", expr)
+		}
+		// "n/step" means "from n to the end of the range".
+		if len(lowAndHigh) == 1 && extra == 0 {
+			end = b.max
+		}
+		if step > 1 {
+			extra = 0
+		}
+	}
+
+	if start < b.min || end > b.max {
+		return 0, fmt.Errorf("%q is outside of the range %d-%d", expr, b.min, b.max)
+	}
+	if start > end {
+		return 0, fmt.Errorf("beginning of range %q is after its end", expr)
+	}
+	return bitRange(start, end, step) | extra, nil
+}
+
+// parseValue parses a number or one of the symbolic names of the field.
+func parseValue(s string, b bounds) (uint, error) {
+	if b.names != nil {
+		if v, ok := b.names[strings.ToLower(s)]; ok {
+			return v, nil
+		}
+	}
+	return parseUint(s)
+}
+
+// parseUint parses a small non-negative number.
+func parseUint(s string) (uint, error) {
+	v, err := strconv.ParseUint(s, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %q as a number", s)
+	}
+	return uint(v), nil
+}
+
+// fieldValues returns the values set in a field bit set, in ascending order.
+func fieldValues(set uint64) []uint {
+	var values []uint
+	for i := uint(0); i < 63; i++ {
+		if set&(1<<i) != 0 {
+			values = append(values, i)
+		}
+	}
+	return values
+}
+
+// bitRange returns the bits in [start, end] spaced by step.
+func bitRange(start, end, step uint) uint64 {
+	var bits uint64
+	for i := start; i <= end; i += step {
+		bits |= 1 << i
+	}
+	return bits
+}
+
+// Next implements Schedule. It walks field by field from the most to the least
+// significant one, resetting the smaller fields whenever a larger one moves.
+func (s *specSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Add(time.Second - time.Duration(t.Nanosecond())*time.Nanosecond)
+	added := false
+
+	// Give up if no match is found within five years.
+	yearLimit := t.Year() + 5
+
+WRAP:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for 1<<uint(t.Month())&s.month == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 1, 0)
+		if t.Month() == time.January {
+			goto WRAP
+		}
+	}
+
+	for !s.dayMatches(t) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		}
+		t = t.AddDate(0, 0, 1)
+		// Days can be shorter or longer than 24h around DST changes.
+		if t.Hour() != 0 {
+			if t.Hour() > 12 {
+				t = t.Add(time.Duration(24-t.Hour()) * time.Hour)
+			} else {
+				t = t.Add(time.Duration(-t.Hour()) * time.Hour)
+			}
+		}
+		if t.Day() == 1 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Hour())&s.hour == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+		}
+		t = t.Add(time.Hour)
+		if t.Hour() == 0 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Minute())&s.minute == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Minute)
+		}
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto WRAP
+		}
+	}
+
+	for 1<<uint(t.Second())&s.second == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Second)
+		}
+		t = t.Add(time.Second)
+		if t.Second() == 0 {
+			goto WRAP
+		}
+	}
+
+	return t
+}
+
+// dayMatches applies the cron rule that day-of-month and day-of-week are or-ed
+// together unless one of them is a wildcard.
+func (s *specSchedule) dayMatches(t time.Time) bool {
+	domMatch := 1<<uint(t.Day())&s.dom > 0
+	dowMatch := 1<<uint(t.Weekday())&s.dow > 0
+	if s.dom&starBit > 0 || s.dow&starBit > 0 {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// everySchedule fires at fixed intervals aligned to the Unix epoch, so that its slots do
+// not depend on when the controller happened to look at it.
+type everySchedule struct {
+	every time.Duration
+}
+
+// Next implements Schedule.
+func (s everySchedule) Next(t time.Time) time.Time {
+	every := int64(s.every / time.Second)
+	return time.Unix((t.Unix()/every+1)*every, 0).In(t.Location())
+}
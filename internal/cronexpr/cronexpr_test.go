@@ -0,0 +1,166 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cronexpr
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func mustTime(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	Expect(err).NotTo(HaveOccurred())
+	return t
+}
+
+var _ = Describe("Parse", func() {
+	DescribeTable("computing the next activation",
+		func(expr, from, expected string) {
+			schedule, err := Parse(expr, "default/reports/nightly")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(schedule.Next(mustTime(from))).To(Equal(mustTime(expected)))
+		},
+		Entry("every minute", "* * * * *", "2025-01-01T10:00:30Z", "2025-01-01T10:01:00Z"),
+		Entry("step over minutes", "*/15 * * * *", "2025-01-01T10:16:00Z", "2025-01-01T10:30:00Z"),
+		Entry("range and list", "0 9-17/4,23 * * *", "2025-01-01T13:00:00Z", "2025-01-01T17:00:00Z"),
+		Entry("rolls over the day", "30 2 * * *", "2025-01-01T03:00:00Z", "2025-01-02T02:30:00Z"),
+		Entry("named weekday", "0 0 * * MON", "2025-01-01T00:00:00Z", "2025-01-06T00:00:00Z"),
+		Entry("day-of-month or day-of-week", "0 0 13 * FRI", "2025-01-01T00:00:00Z", "2025-01-03T00:00:00Z"),
+		Entry("named month", "0 0 1 mar *", "2025-01-01T00:00:00Z", "2025-03-01T00:00:00Z"),
+		Entry("macro", "@monthly", "2025-01-15T00:00:00Z", "2025-02-01T00:00:00Z"),
+		Entry("leap day", "0 0 29 2 *", "2025-01-01T00:00:00Z", "2028-02-29T00:00:00Z"),
+		Entry("seconds field", "*/20 * * * * *", "2025-01-01T10:00:25Z", "2025-01-01T10:00:40Z"),
+		Entry("interval aligned to the epoch", "@every 90m", "2025-01-01T10:00:00Z", "2025-01-01T10:30:00Z"),
+	)
+
+	DescribeTable("rejecting invalid expressions",
+		func(expr string) {
+			_, err := Parse(expr, "default/reports/nightly")
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("empty", ""),
+		Entry("too few fields", "* * * *"),
+		Entry("out of range", "60 * * * *"),
+		Entry("reversed range", "0 10-2 * * *"),
+		Entry("zero step", "*/0 * * * *"),
+		Entry("unknown macro", "@fortnightly"),
+		Entry("unknown name", "0 0 * * FUNDAY"),
+		Entry("day-of-week beyond Saturday", "0 0 * * 7"),
+		Entry("too many fields", "0 0 0 * * * *"),
+		Entry("sub-second interval", "@every 500ms"),
+		Entry("hash range outside the field", "H(50-70) * * * *"),
+		Entry("malformed hash token", "H[0-5] * * * *"),
+	)
+
+	It("should never fire for impossible dates", func() {
+		schedule, err := Parse("0 0 30 2 *", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(schedule.Next(mustTime("2025-01-01T00:00:00Z")).IsZero()).To(BeTrue())
+	})
+
+	It("should resolve H tokens deterministically within their range", func() {
+		first, err := ToCronJob("H H(2-5) * * H", "default/reports/nightly")
+		Expect(err).NotTo(HaveOccurred())
+		second, err := ToCronJob("H H(2-5) * * H", "default/reports/nightly")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(first).To(Equal(second))
+
+		schedule, err := Parse("H H(2-5) * * *", "default/reports/nightly")
+		Expect(err).NotTo(HaveOccurred())
+		next := schedule.Next(mustTime("2025-01-01T00:00:00Z"))
+		Expect(next.Hour()).To(BeNumerically(">=", 2))
+		Expect(next.Hour()).To(BeNumerically("<=", 5))
+	})
+})
+
+var _ = Describe("ToCronJob", func() {
+	DescribeTable("translating to CronJob syntax",
+		func(expr, expected string) {
+			Expect(ToCronJob(expr, "default/reports/nightly")).To(Equal(expected))
+		},
+		Entry("standard expression", "*/5 * * * *", "*/5 * * * *"),
+		Entry("macro", "@daily", "@daily"),
+		Entry("zero seconds", "0 30 2 * * *", "30 2 * * *"),
+		Entry("interval dividing an hour", "@every 15m", "*/15 * * * *"),
+		Entry("interval dividing a day", "@every 6h", "0 */6 * * *"),
+		Entry("daily interval", "@every 24h", "0 0 * * *"),
+	)
+
+	DescribeTable("requiring the Native engine",
+		func(expr string) {
+			_, err := ToCronJob(expr, "default/reports/nightly")
+			Expect(err).To(MatchError(ErrNotRepresentable))
+		},
+		Entry("non-zero seconds", "30 * * * * *"),
+		Entry("interval not dividing an hour", "@every 7m"),
+		Entry("interval with seconds", "@every 90s"),
+		Entry("interval not dividing a day", "@every 90m"),
+	)
+
+	It("should keep the offset of a hashed step", func() {
+		expr, err := ToCronJob("H/15 * * * *", "default/reports/nightly")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(expr).To(MatchRegexp(`^([0-9]|1[0-4])-59/15 \* \* \* \*$`))
+	})
+})
+
+var _ = Describe("Stagger", func() {
+	It("should leave expressions alone when the window is disabled", func() {
+		Expect(Stagger("0 * * * *", "default/etl/extract", 0)).To(Equal("0 * * * *"))
+	})
+
+	It("should offset the same schedule by the same amount every time", func() {
+		first, err := Stagger("0 * * * *", "default/etl/extract", 30*time.Minute)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(Stagger("0 * * * *", "default/etl/extract", 30*time.Minute)).To(Equal(first))
+		Expect(first).To(MatchRegexp(`^([0-9]|[12][0-9]) \* \* \* \*$`))
+	})
+
+	It("should spread schedules with the same expression", func() {
+		offsets := map[string]struct{}{}
+		for _, name := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+			expr, err := Stagger("@hourly", "default/team/"+name, time.Hour)
+			Expect(err).NotTo(HaveOccurred())
+			offsets[expr] = struct{}{}
+		}
+		Expect(len(offsets)).To(BeNumerically(">", 1))
+	})
+
+	It("should carry the offset into a fixed hour and stay within the window", func() {
+		expr, err := Stagger("50 2 * * *", "default/etl/extract", 6*time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+
+		staggered, err := Parse(expr, "")
+		Expect(err).NotTo(HaveOccurred())
+		next := staggered.Next(mustTime("2025-01-01T02:49:00Z"))
+		Expect(next).To(BeTemporally(">=", mustTime("2025-01-01T02:50:00Z")))
+		Expect(next).To(BeTemporally("<", mustTime("2025-01-01T08:50:00Z")))
+	})
+
+	It("should not move a weekly schedule to another day", func() {
+		expr, err := Stagger("59 23 * * FRI", "default/etl/extract", 24*time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(expr).To(MatchRegexp(`^\d+ 23 \* \* FRI$`))
+	})
+
+	It("should leave dense and interval expressions alone", func() {
+		Expect(Stagger("*/5 * * * *", "default/etl/extract", time.Hour)).To(Equal("*/5 * * * *"))
+		Expect(Stagger("@every 90m", "default/etl/extract", time.Hour)).To(Equal("@every 90m"))
+	})
+})
@@ -1,49 +1,325 @@
 package cronjobbuilder
 
 import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
 	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
 )
 
-// BuildCronJob creates a Kubernetes CronJob object from a Scheduler custom resource.
-func BuildCronJob(scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule) *batchv1.CronJob {
-	name := scheduler.Name + "-" + schedule.Name
-	labels := map[string]string{
+const (
+	// JobContainerName is the name of the container running the schedule's image.
+	JobContainerName = "job"
+
+	// JitterImage is the image of the init container that delays CronJob runs by their jitter.
+	JitterImage = "busybox:1.36"
+
+	// maxJobNameLength bounds the names of Jobs, which their pods carry in the job-name
+	// label.
+	maxJobNameLength = 63
+
+	// maxCronJobNameLength bounds the names of CronJobs, which the CronJob controller
+	// follows with 11 characters in the names of their Jobs.
+	maxCronJobNameLength = 52
+
+	// MissedRunGrace is how late a run may start before the None catch-up policy
+	// considers it missed.
+	MissedRunGrace = time.Minute
+)
+
+// IsSuspended reports whether a schedule is suspended, giving the schedule's own
+// Suspend precedence over the Scheduler-wide one.
+func IsSuspended(scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule) bool {
+	if schedule.Suspend != nil {
+		return *schedule.Suspend
+	}
+	return scheduler.Spec.Suspend != nil && *scheduler.Spec.Suspend
+}
+
+// Labels returns the labels set on every object created for a schedule.
+func Labels(scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule) map[string]string {
+	return map[string]string{
 		"app":       "scheduler-controller",
 		"scheduler": scheduler.Name,
 		"schedule":  schedule.Name,
 	}
+}
+
+// CronJobName returns the name of the CronJob rendered for a schedule.
+func CronJobName(scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule) string {
+	return boundedName(scheduler.Name+"-"+schedule.Name, "", maxCronJobNameLength)
+}
+
+// boundedName joins a prefix and a suffix into a name of at most limit characters. A
+// prefix too long is truncated and followed by a hash of it, so that names stay
+// deterministic and distinct.
+func boundedName(prefix, suffix string, limit int) string {
+	if len(prefix)+len(suffix) <= limit {
+		return prefix + suffix
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(prefix))
+	hash := fmt.Sprintf("-%08x", h.Sum32())
+	return prefix[:limit-len(hash)-len(suffix)] + hash + suffix
+}
+
+// BuildCronJob creates a Kubernetes CronJob object from a Scheduler custom resource.
+// Templates in the schedule can only refer to the names of the Scheduler and schedule,
+// since the CronJob controller creates the Jobs.
+func BuildCronJob(scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule) (*batchv1.CronJob, error) {
+	jobTemplate := buildJobTemplate(scheduler, schedule)
+	if err := renderPod(&jobTemplate.Spec.Template.Spec, scheduler, schedule, nil, ""); err != nil {
+		return nil, fmt.Errorf("%w (run data such as .ScheduledTime requires mode: Native)", err)
+	}
+	addPreconditions(&jobTemplate.Spec, scheduler, schedule)
+
+	// The CronJob controller starts Jobs on the nominal schedule, so jitter is applied
+	// inside the pod before the preconditions are checked and any step or the job
+	// container starts.
+	if seconds := jitterSeconds(schedule); seconds > 0 {
+		podSpec := &jobTemplate.Spec.Template.Spec
+		podSpec.InitContainers = append([]corev1.Container{{
+			Name:  "jitter",
+			Image: JitterImage,
+			Command: []string{"sh", "-c",
+				fmt.Sprintf("sleep $(( $(od -An -N4 -tu4 /dev/urandom) %% %d ))", seconds)},
+		}}, podSpec.InitContainers...)
+	}
+
+	// Failed Jobs waiting for a retry must outlive the CronJob's history limit, so the
+	// controller prunes them instead
+	var failedJobsHistoryLimit *int32
+	if schedule.RetryPolicy != nil {
+		failedJobsHistoryLimit = ptr.To(int32(math.MaxInt32))
+	}
 
 	return &batchv1.CronJob{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      name,
+			Name:      CronJobName(scheduler, schedule),
 			Namespace: scheduler.Namespace,
-			Labels:    labels,
+			Labels:    Labels(scheduler, schedule),
 			OwnerReferences: []metav1.OwnerReference{
 				*metav1.NewControllerRef(scheduler, schedulingapiv1.GroupVersion.WithKind("Scheduler")),
 			},
 		},
 		Spec: batchv1.CronJobSpec{
-			Schedule: schedule.CronExpression,
-			JobTemplate: batchv1.JobTemplateSpec{
-				Spec: batchv1.JobSpec{
-					Template: corev1.PodTemplateSpec{
-						Spec: corev1.PodSpec{
-							RestartPolicy: corev1.RestartPolicyOnFailure,
-							Containers: []corev1.Container{
-								{
-									Name:  "job",
-									Image: schedule.Image,
-									Args:  schedule.Params,
-									Env:   schedule.Env,
-								},
-							},
-						},
-					},
+			Schedule:                schedule.CronExpression,
+			Suspend:                 ptr.To(IsSuspended(scheduler, schedule)),
+			StartingDeadlineSeconds: startingDeadlineSeconds(schedule),
+			FailedJobsHistoryLimit:  failedJobsHistoryLimit,
+			JobTemplate:             jobTemplate,
+		},
+	}, nil
+}
+
+// JobName returns the deterministic name of the Job fired for a schedule at the given
+// logical time, so that firing the same slot twice collides instead of duplicating.
+func JobName(scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule, scheduledTime time.Time) string {
+	return boundedName(scheduler.Name+"-"+schedule.Name, fmt.Sprintf("-%d", scheduledTime.Unix()), maxJobNameLength)
+}
+
+// BuildJob creates a Kubernetes Job for a single scheduled run of a schedule, rendered
+// from the same template BuildCronJob uses.
+func BuildJob(scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule, scheduledTime time.Time, outputs Outputs) (*batchv1.Job, error) {
+	name := JobName(scheduler, schedule, scheduledTime)
+	return buildRunJob(scheduler, schedule, name, name, RunInfo{
+		ScheduledTime: scheduledTime,
+		Trigger:       TriggerCron,
+		Attempt:       1,
+		Outputs:       outputs,
+	})
+}
+
+// ManualJobName returns the name of the Job started by a trigger, derived from its nonce
+// so that the same trigger collides instead of running twice.
+func ManualJobName(scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule, nonce string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(nonce))
+	return boundedName(scheduler.Name+"-"+schedule.Name, fmt.Sprintf("-manual-%08x", h.Sum32()), maxJobNameLength)
+}
+
+// BuildManualJob creates a Kubernetes Job for a run of a schedule started by hand at the
+// given time, labelled as manually triggered.
+func BuildManualJob(scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule, nonce, triggeredBy string, at time.Time, outputs Outputs) (*batchv1.Job, error) {
+	name := ManualJobName(scheduler, schedule, nonce)
+	job, err := buildRunJob(scheduler, schedule, name, name, RunInfo{
+		ScheduledTime: at,
+		Trigger:       schedulingapiv1.TriggerManual,
+		Attempt:       1,
+		Outputs:       outputs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	job.Annotations[schedulingapiv1.TriggerAnnotation] = schedule.Name + "@" + nonce
+	if triggeredBy != "" {
+		job.Annotations[schedulingapiv1.TriggeredByAnnotation] = triggeredBy
+	}
+	return job, nil
+}
+
+// BuildBackfillJob creates a Kubernetes Job for a slot of a backfill request. It has the
+// name of the scheduled run of the same slot, so a slot that already ran is not repeated.
+func BuildBackfillJob(scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule, scheduledTime time.Time, outputs Outputs) (*batchv1.Job, error) {
+	name := JobName(scheduler, schedule, scheduledTime)
+	return buildRunJob(scheduler, schedule, name, name, RunInfo{
+		ScheduledTime: scheduledTime,
+		Trigger:       schedulingapiv1.TriggerBackfill,
+		Attempt:       1,
+		Outputs:       outputs,
+	})
+}
+
+// BuildDependentJob creates a Kubernetes Job for the run of a schedule started once the
+// runs of the schedules it depends on succeeded for the given logical time.
+func BuildDependentJob(scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule, scheduledTime time.Time, outputs Outputs) (*batchv1.Job, error) {
+	name := JobName(scheduler, schedule, scheduledTime)
+	return buildRunJob(scheduler, schedule, name, name, RunInfo{
+		ScheduledTime: scheduledTime,
+		Trigger:       schedulingapiv1.TriggerDependency,
+		Attempt:       1,
+		Outputs:       outputs,
+	})
+}
+
+// buildRunJob creates the Job of a run created by the controller, with the run metadata
+// in its environment and templates rendered against it. RUN_ID is runID, which differs
+// from the name of the Job for retries. Runs not started by the schedule carry their
+// trigger in TriggerLabel.
+func buildRunJob(scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule, name, runID string, run RunInfo) (*batchv1.Job, error) {
+	template := buildJobTemplate(scheduler, schedule)
+	if err := renderPod(&template.Spec.Template.Spec, scheduler, schedule, &run, runID); err != nil {
+		return nil, err
+	}
+	if run.Trigger != TriggerCron {
+		template.Labels[schedulingapiv1.TriggerLabel] = run.Trigger
+		template.Spec.Template.Labels[schedulingapiv1.TriggerLabel] = run.Trigger
+	}
+
+	annotations := map[string]string{
+		schedulingapiv1.ScheduledTimeAnnotation: run.ScheduledTime.UTC().Format(time.RFC3339),
+	}
+	for key, value := range template.Annotations {
+		annotations[key] = value
+	}
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   scheduler.Namespace,
+			Labels:      template.Labels,
+			Annotations: annotations,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(scheduler, schedulingapiv1.GroupVersion.WithKind("Scheduler")),
+			},
+		},
+		Spec: template.Spec,
+	}, nil
+}
+
+// buildJobTemplate renders the Job template shared by CronJobs and directly created Jobs.
+// Schedules with steps run them first, as init containers or, in Jobs mode, by running
+// the first step in place of the schedule's container. The Job of a run in a lock group
+// is labelled with it, so that it holds the lock until it finishes.
+func buildJobTemplate(scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule) batchv1.JobTemplateSpec {
+	template := jobTemplate(scheduler, schedule, corev1.Container{
+		Name:  JobContainerName,
+		Image: schedule.Image,
+		Args:  schedule.Params,
+		Env:   schedule.Env,
+	})
+	if group := schedule.LockGroup; group != nil {
+		scope := group.Scope
+		if scope == "" {
+			scope = schedulingapiv1.LockScopeNamespace
+		}
+		template.Labels[schedulingapiv1.LockGroupLabel] = group.Name
+		template.Labels[schedulingapiv1.LockScopeLabel] = string(scope)
+	}
+	if len(schedule.Steps) == 0 {
+		return template
+	}
+
+	podSpec := &template.Spec.Template.Spec
+	if schedule.StepsMode == schedulingapiv1.StepsModeJobs {
+		// The controller starts the Jobs of the next steps once this one has finished
+		first := schedule.Steps[0]
+		podSpec.Containers[0] = stepContainer(schedule, first)
+		if first.Retries != nil {
+			template.Spec.BackoffLimit = first.Retries
+		}
+		if template.Annotations == nil {
+			template.Annotations = map[string]string{}
+		}
+		template.Annotations[schedulingapiv1.StepsAnnotation] = strings.Join(StepNames(schedule), ",")
+	} else {
+		var steps []corev1.Container
+		for _, step := range schedule.Steps {
+			container := stepContainer(schedule, step)
+			container.Name = StepContainerPrefix + step.Name
+			steps = append(steps, container)
+		}
+		podSpec.InitContainers = steps
+	}
+	mountWorkspace(podSpec, schedule)
+	return template
+}
+
+// jobTemplate returns the template of a Job running a single container for a schedule.
+// The priority of the schedule is recorded on the Job for SchedulerQuotas. The pods of a
+// schedule with a RetryPolicy are not retried, as the controller retries the run with a
+// new Job.
+func jobTemplate(scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule, container corev1.Container) batchv1.JobTemplateSpec {
+	var annotations map[string]string
+	if schedule.Priority != 0 {
+		annotations = map[string]string{schedulingapiv1.PriorityAnnotation: strconv.Itoa(int(schedule.Priority))}
+	}
+	template := batchv1.JobTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels:      Labels(scheduler, schedule),
+			Annotations: annotations,
+		},
+		Spec: batchv1.JobSpec{
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: Labels(scheduler, schedule),
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyOnFailure,
+					Containers:    []corev1.Container{container},
 				},
 			},
 		},
 	}
+	if schedule.RetryPolicy != nil {
+		template.Spec.BackoffLimit = ptr.To(int32(0))
+		template.Spec.Template.Spec.RestartPolicy = corev1.RestartPolicyNever
+	}
+	return template
+}
+
+// startingDeadlineSeconds maps the catch-up policy of a schedule to the CronJob deadline
+// after which a missed run is skipped. Without a deadline, the CronJob controller runs the
+// latest missed slot.
+func startingDeadlineSeconds(schedule schedulingapiv1.Schedule) *int64 {
+	if schedule.CatchUpPolicy == schedulingapiv1.CatchUpNone {
+		return ptr.To(int64(MissedRunGrace / time.Second))
+	}
+	return nil
+}
+
+// jitterSeconds returns the jitter of a schedule in whole seconds.
+func jitterSeconds(schedule schedulingapiv1.Schedule) int64 {
+	if schedule.Jitter == nil {
+		return 0
+	}
+	return int64(schedule.Jitter.Duration / time.Second)
 }
@@ -0,0 +1,115 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cronjobbuilder_test
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cronjobbuilder"
+)
+
+var _ = Describe("Names", func() {
+	slot := time.Date(2025, 3, 14, 2, 0, 0, 0, time.UTC)
+	named := func(scheduler, schedule string) (*schedulingapiv1.Scheduler, schedulingapiv1.Schedule) {
+		return &schedulingapiv1.Scheduler{ObjectMeta: metav1.ObjectMeta{Name: scheduler, Namespace: "default"}},
+			schedulingapiv1.Schedule{Name: schedule}
+	}
+
+	It("should keep short names as they are", func() {
+		scheduler, schedule := named("etl", "nightly")
+
+		Expect(cronjobbuilder.CronJobName(scheduler, schedule)).To(Equal("etl-nightly"))
+		Expect(cronjobbuilder.JobName(scheduler, schedule, slot)).To(Equal("etl-nightly-1741917600"))
+		Expect(cronjobbuilder.ManualJobName(scheduler, schedule, "n1")).To(HavePrefix("etl-nightly-manual-"))
+	})
+
+	It("should bound the names of long Schedulers and schedules to valid labels", func() {
+		scheduler, schedule := named(strings.Repeat("s", 63), strings.Repeat("n", 63))
+
+		cronJob := cronjobbuilder.CronJobName(scheduler, schedule)
+		Expect(len(cronJob)).To(BeNumerically("<=", 52))
+		Expect(validation.IsDNS1123Label(cronJob)).To(BeEmpty())
+
+		job := cronjobbuilder.JobName(scheduler, schedule, slot)
+		manual := cronjobbuilder.ManualJobName(scheduler, schedule, "n1")
+		for _, name := range []string{job, manual} {
+			Expect(validation.IsDNS1123Label(name)).To(BeEmpty(), name)
+		}
+		Expect(job).To(HaveSuffix("-1741917600"))
+		Expect(manual).To(ContainSubstring("-manual-"))
+	})
+
+	It("should keep long names deterministic and distinct", func() {
+		scheduler, schedule := named(strings.Repeat("s", 63), strings.Repeat("n", 63))
+		other, otherSchedule := named(strings.Repeat("s", 63), strings.Repeat("n", 62)+"m")
+
+		Expect(cronjobbuilder.JobName(scheduler, schedule, slot)).
+			To(Equal(cronjobbuilder.JobName(scheduler, schedule, slot)))
+		Expect(cronjobbuilder.JobName(scheduler, schedule, slot)).
+			NotTo(Equal(cronjobbuilder.JobName(other, otherSchedule, slot)))
+		Expect(cronjobbuilder.CronJobName(scheduler, schedule)).
+			NotTo(Equal(cronjobbuilder.CronJobName(other, otherSchedule)))
+		Expect(cronjobbuilder.ManualJobName(scheduler, schedule, "n1")).
+			NotTo(Equal(cronjobbuilder.ManualJobName(scheduler, schedule, "n2")))
+	})
+})
+
+var _ = Describe("Jitter", func() {
+	scheduler := &schedulingapiv1.Scheduler{
+		ObjectMeta: metav1.ObjectMeta{Name: "etl", Namespace: "default"},
+	}
+	schedule := schedulingapiv1.Schedule{
+		Name:           "nightly",
+		Image:          "loader",
+		CronExpression: "0 2 * * *",
+	}
+
+	It("should sleep up to the jitter in an init container before the job container", func() {
+		jittered := schedule
+		jittered.Jitter = &metav1.Duration{Duration: 90 * time.Second}
+		jittered.Steps = []schedulingapiv1.Step{{Name: "extract"}}
+
+		cronJob, err := cronjobbuilder.BuildCronJob(scheduler, jittered)
+		Expect(err).NotTo(HaveOccurred())
+		initContainers := cronJob.Spec.JobTemplate.Spec.Template.Spec.InitContainers
+		Expect(initContainers).To(HaveLen(2))
+		Expect(initContainers[0].Name).To(Equal("jitter"))
+		Expect(initContainers[0].Image).To(Equal(cronjobbuilder.JitterImage))
+		Expect(initContainers[0].Command).To(Equal([]string{"sh", "-c",
+			"sleep $(( $(od -An -N4 -tu4 /dev/urandom) % 90 ))"}))
+		Expect(initContainers[1].Name).To(Equal("step-extract"))
+	})
+
+	It("should add no init container without jitter", func() {
+		cronJob, err := cronjobbuilder.BuildCronJob(scheduler, schedule)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cronJob.Spec.JobTemplate.Spec.Template.Spec.InitContainers).To(BeEmpty())
+
+		subSecond := schedule
+		subSecond.Jitter = &metav1.Duration{Duration: 500 * time.Millisecond}
+		cronJob, err = cronjobbuilder.BuildCronJob(scheduler, subSecond)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cronJob.Spec.JobTemplate.Spec.Template.Spec.InitContainers).To(BeEmpty())
+	})
+})
@@ -0,0 +1,124 @@
+package cronjobbuilder
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+const (
+	// PreconditionsContainerName is the name of the init container checking the
+	// preconditions of CronJob runs.
+	PreconditionsContainerName = "preconditions"
+
+	// PreconditionsImage is the image of the init container checking preconditions.
+	PreconditionsImage = JitterImage
+
+	// preconditionsMountPath is where the keys of ConfigMap preconditions are mounted in
+	// the init container, one directory per precondition.
+	preconditionsMountPath = "/preconditions"
+
+	// PreconditionSkipExitCode is the exit code of the preconditions init container when
+	// a precondition is not met. It fails the Job at once, which is reported as Skipped.
+	PreconditionSkipExitCode = 99
+)
+
+// preconditionsScript reads the API server with the token of the pod's service account.
+// Each check is passed in environment variables, so that no value of the schedule is
+// interpreted by the shell.
+const preconditionsScript = `API=https://kubernetes.default.svc
+TOKEN=$(cat /var/run/secrets/kubernetes.io/serviceaccount/token)
+skip() { echo "$1" | tee /dev/termination-log; exit %d; }
+get() { wget -q -O - --header "Authorization: Bearer $TOKEN" "$API$1"; }
+`
+
+// PreconditionReason describes a precondition that is not met.
+func PreconditionReason(p schedulingapiv1.Precondition) string {
+	switch {
+	case p.ConfigMap != nil:
+		return fmt.Sprintf("ConfigMap %s does not have %s=%s", p.ConfigMap.Name, p.ConfigMap.Key, p.ConfigMap.Value)
+	case p.Deployment != nil:
+		return fmt.Sprintf("Deployment %s is not Available", p.Deployment.Name)
+	case p.HTTP != nil:
+		return fmt.Sprintf("%s did not return 200", p.HTTP.URL)
+	}
+	return "unknown precondition"
+}
+
+// addPreconditions makes the pods of a CronJob check the preconditions of the schedule in
+// an init container before anything else runs. Their pods are not restarted, so that a
+// precondition that is not met ends the Job through its pod failure policy instead of
+// being retried: a failing container of the schedule is then retried in a new pod,
+// within the backoff limit of the Job, rather than restarted in place.
+func addPreconditions(spec *batchv1.JobSpec, scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule) {
+	if len(schedule.Preconditions) == 0 {
+		return
+	}
+
+	var script strings.Builder
+	fmt.Fprintf(&script, preconditionsScript, PreconditionSkipExitCode)
+	var env []corev1.EnvVar
+	var volumes []corev1.Volume
+	var mounts []corev1.VolumeMount
+	for i, p := range schedule.Preconditions {
+		prefix := fmt.Sprintf("PRECONDITION_%d_", i)
+		env = append(env, corev1.EnvVar{Name: prefix + "REASON", Value: PreconditionReason(p)})
+		switch {
+		case p.ConfigMap != nil:
+			// Only the key is mounted, and left out when it or the ConfigMap is missing, so
+			// that it is compared as a whole with the value
+			volume := fmt.Sprintf("precondition-%d", i)
+			dir := preconditionsMountPath + "/" + strconv.Itoa(i)
+			volumes = append(volumes, corev1.Volume{Name: volume, VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: p.ConfigMap.Name},
+				Items:                []corev1.KeyToPath{{Key: p.ConfigMap.Key, Path: "value"}},
+				Optional:             ptr.To(true),
+			}}})
+			mounts = append(mounts, corev1.VolumeMount{Name: volume, MountPath: dir, ReadOnly: true})
+			env = append(env, corev1.EnvVar{Name: prefix + "VALUE", Value: p.ConfigMap.Value})
+			fmt.Fprintf(&script, "printf %%s \"$%sVALUE\" | cmp -s - %s/value || skip \"$%sREASON\"\n", prefix, dir, prefix)
+			continue
+		case p.Deployment != nil:
+			env = append(env,
+				corev1.EnvVar{Name: prefix + "PATH", Value: fmt.Sprintf("/apis/apps/v1/namespaces/%s/deployments/%s", scheduler.Namespace, p.Deployment.Name)},
+				corev1.EnvVar{Name: prefix + "MATCH", Value: `"type":"Available","status":"True"`},
+			)
+		case p.HTTP != nil:
+			env = append(env, corev1.EnvVar{Name: prefix + "URL", Value: p.HTTP.URL})
+			fmt.Fprintf(&script, "wget -q -O /dev/null -T 10 \"$%sURL\" || skip \"$%sREASON\"\n", prefix, prefix)
+			continue
+		}
+		fmt.Fprintf(&script, "get \"$%sPATH\" | grep -qF \"$%sMATCH\" || skip \"$%sREASON\"\n", prefix, prefix, prefix)
+	}
+
+	// The kubelet expands $(VAR) in values, unless the $ is doubled
+	for i := range env {
+		env[i].Value = strings.ReplaceAll(env[i].Value, "$", "$$")
+	}
+
+	podSpec := &spec.Template.Spec
+	podSpec.RestartPolicy = corev1.RestartPolicyNever
+	podSpec.Volumes = append(podSpec.Volumes, volumes...)
+	podSpec.InitContainers = append([]corev1.Container{{
+		Name:         PreconditionsContainerName,
+		Image:        PreconditionsImage,
+		Command:      []string{"sh", "-c", script.String()},
+		Env:          env,
+		VolumeMounts: mounts,
+	}}, podSpec.InitContainers...)
+	spec.PodFailurePolicy = &batchv1.PodFailurePolicy{
+		Rules: []batchv1.PodFailurePolicyRule{{
+			Action: batchv1.PodFailurePolicyActionFailJob,
+			OnExitCodes: &batchv1.PodFailurePolicyOnExitCodesRequirement{
+				ContainerName: ptr.To(PreconditionsContainerName),
+				Operator:      batchv1.PodFailurePolicyOnExitCodesOpIn,
+				Values:        []int32{PreconditionSkipExitCode},
+			},
+		}},
+	}
+}
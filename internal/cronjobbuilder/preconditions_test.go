@@ -0,0 +1,91 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cronjobbuilder_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cronjobbuilder"
+)
+
+var _ = Describe("Preconditions", func() {
+	scheduler := &schedulingapiv1.Scheduler{
+		ObjectMeta: metav1.ObjectMeta{Name: "etl", Namespace: "default"},
+	}
+	schedule := schedulingapiv1.Schedule{
+		Name:           "nightly",
+		Image:          "loader",
+		CronExpression: "0 2 * * *",
+		Jitter:         &metav1.Duration{Duration: time.Minute},
+		Preconditions: []schedulingapiv1.Precondition{
+			{ConfigMap: &schedulingapiv1.ConfigMapPrecondition{Name: "flags", Key: "etl-enabled", Value: "true"}},
+			{HTTP: &schedulingapiv1.HTTPPrecondition{URL: "https://example.com/healthz?a=1&b=$(id)"}},
+		},
+	}
+
+	It("checks them in an init container of CronJob runs", func() {
+		cronJob, err := cronjobbuilder.BuildCronJob(scheduler, schedule)
+		Expect(err).NotTo(HaveOccurred())
+
+		spec := cronJob.Spec.JobTemplate.Spec
+		podSpec := spec.Template.Spec
+		Expect(podSpec.RestartPolicy).To(Equal(corev1.RestartPolicyNever))
+		Expect(podSpec.InitContainers).To(HaveLen(2))
+		Expect(podSpec.InitContainers[0].Name).To(Equal("jitter"))
+
+		check := podSpec.InitContainers[1]
+		Expect(check.Name).To(Equal(cronjobbuilder.PreconditionsContainerName))
+		Expect(check.Command[2]).NotTo(ContainSubstring("example.com"))
+		Expect(check.Env).To(ContainElements(
+			corev1.EnvVar{Name: "PRECONDITION_0_VALUE", Value: "true"},
+			corev1.EnvVar{Name: "PRECONDITION_1_URL", Value: "https://example.com/healthz?a=1&b=$$(id)"},
+		))
+
+		// Only the value of the key is compared, rather than anything in the ConfigMap
+		Expect(check.Command[2]).To(ContainSubstring(`cmp -s - /preconditions/0/value`))
+		Expect(check.VolumeMounts).To(ConsistOf(corev1.VolumeMount{Name: "precondition-0", MountPath: "/preconditions/0", ReadOnly: true}))
+		Expect(podSpec.Volumes).To(HaveLen(1))
+		Expect(podSpec.Volumes[0].ConfigMap.Name).To(Equal("flags"))
+		Expect(podSpec.Volumes[0].ConfigMap.Items).To(Equal([]corev1.KeyToPath{{Key: "etl-enabled", Path: "value"}}))
+		Expect(podSpec.Volumes[0].ConfigMap.Optional).To(HaveValue(BeTrue()))
+
+		Expect(spec.PodFailurePolicy).NotTo(BeNil())
+		Expect(spec.PodFailurePolicy.Rules[0].OnExitCodes.Values).To(ConsistOf(int32(cronjobbuilder.PreconditionSkipExitCode)))
+	})
+
+	It("restarts the pods of CronJob runs only without preconditions", func() {
+		unchecked := schedule
+		unchecked.Preconditions = nil
+		cronJob, err := cronjobbuilder.BuildCronJob(scheduler, unchecked)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cronJob.Spec.JobTemplate.Spec.Template.Spec.RestartPolicy).To(Equal(corev1.RestartPolicyOnFailure))
+		Expect(cronJob.Spec.JobTemplate.Spec.PodFailurePolicy).To(BeNil())
+	})
+
+	It("leaves controller runs to the controller", func() {
+		job, err := cronjobbuilder.BuildJob(scheduler, schedule, time.Date(2025, 3, 14, 2, 0, 0, 0, time.UTC), nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.Spec.Template.Spec.InitContainers).To(BeEmpty())
+		Expect(job.Spec.PodFailurePolicy).To(BeNil())
+	})
+})
@@ -0,0 +1,45 @@
+package cronjobbuilder
+
+import (
+	"fmt"
+	"strconv"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	batchv1 "k8s.io/api/batch/v1"
+)
+
+// RunID returns the ID of the run a Job executes: the name of the Job of its first attempt.
+func RunID(job *batchv1.Job) string {
+	if runID, ok := job.Annotations[schedulingapiv1.RetryOfAnnotation]; ok {
+		return runID
+	}
+	return job.Name
+}
+
+// JobAttempt returns the attempt of its run a Job executes, starting at 1.
+func JobAttempt(job *batchv1.Job) int32 {
+	attempt, err := strconv.ParseInt(job.Annotations[schedulingapiv1.AttemptAnnotation], 10, 32)
+	if err != nil || attempt < 1 {
+		return 1
+	}
+	return int32(attempt)
+}
+
+// RetryJobName returns the name of the Job of an attempt of a run after the first one, so
+// that retrying the same attempt twice collides instead of duplicating.
+func RetryJobName(runID string, attempt int32) string {
+	return boundedName(runID, fmt.Sprintf("-retry-%d", attempt), maxJobNameLength)
+}
+
+// BuildRetryJob creates a Kubernetes Job for a further attempt of a failed run. It keeps
+// the run ID, logical time and trigger of the run, with the number of the attempt in
+// ATTEMPT.
+func BuildRetryJob(scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule, runID string, run RunInfo) (*batchv1.Job, error) {
+	job, err := buildRunJob(scheduler, schedule, RetryJobName(runID, run.Attempt), runID, run)
+	if err != nil {
+		return nil, err
+	}
+	job.Annotations[schedulingapiv1.RetryOfAnnotation] = runID
+	job.Annotations[schedulingapiv1.AttemptAnnotation] = strconv.Itoa(int(run.Attempt))
+	return job, nil
+}
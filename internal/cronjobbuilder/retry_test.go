@@ -0,0 +1,100 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cronjobbuilder_test
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/utils/ptr"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cronjobbuilder"
+)
+
+var _ = Describe("Retries", func() {
+	scheduler := &schedulingapiv1.Scheduler{
+		ObjectMeta: metav1.ObjectMeta{Name: "etl", Namespace: "default"},
+	}
+	slot := time.Date(2025, 3, 14, 2, 0, 0, 0, time.UTC)
+	schedule := schedulingapiv1.Schedule{
+		Name:        "nightly",
+		Image:       "loader",
+		RetryPolicy: &schedulingapiv1.RetryPolicy{MaxAttempts: 3},
+	}
+
+	env := func(vars []corev1.EnvVar, name string) string {
+		for _, v := range vars {
+			if v.Name == name {
+				return v.Value
+			}
+		}
+		return ""
+	}
+
+	It("keeps the run ID and counts the attempt", func() {
+		first, err := cronjobbuilder.BuildJob(scheduler, schedule, slot, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cronjobbuilder.RunID(first)).To(Equal(first.Name))
+		Expect(cronjobbuilder.JobAttempt(first)).To(Equal(int32(1)))
+
+		retry, err := cronjobbuilder.BuildRetryJob(scheduler, schedule, cronjobbuilder.RunID(first),
+			cronjobbuilder.RunInfo{ScheduledTime: slot, Trigger: cronjobbuilder.TriggerCron, Attempt: 2})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(retry.Name).To(Equal(first.Name + "-retry-2"))
+		Expect(cronjobbuilder.RunID(retry)).To(Equal(first.Name))
+		Expect(cronjobbuilder.JobAttempt(retry)).To(Equal(int32(2)))
+		Expect(retry.Annotations).To(HaveKeyWithValue(schedulingapiv1.ScheduledTimeAnnotation, "2025-03-14T02:00:00Z"))
+
+		container := retry.Spec.Template.Spec.Containers[0]
+		Expect(env(container.Env, cronjobbuilder.RunIDEnv)).To(Equal(first.Name))
+		Expect(env(container.Env, cronjobbuilder.AttemptEnv)).To(Equal("2"))
+	})
+	It("leaves retries to the controller rather than to the Job", func() {
+		job, err := cronjobbuilder.BuildJob(scheduler, schedule, slot, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.Spec.BackoffLimit).To(Equal(ptr.To(int32(0))))
+		Expect(job.Spec.Template.Spec.RestartPolicy).To(Equal(corev1.RestartPolicyNever))
+
+		cronJob, err := cronjobbuilder.BuildCronJob(scheduler, schedule)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cronJob.Spec.JobTemplate.Spec.BackoffLimit).To(Equal(ptr.To(int32(0))))
+		Expect(cronJob.Spec.JobTemplate.Spec.Template.Spec.RestartPolicy).To(Equal(corev1.RestartPolicyNever))
+		Expect(cronJob.Spec.FailedJobsHistoryLimit).To(HaveValue(BeNumerically(">", 1)))
+
+		withoutRetries := schedule
+		withoutRetries.RetryPolicy = nil
+		job, err = cronjobbuilder.BuildJob(scheduler, withoutRetries, slot, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.Spec.BackoffLimit).To(BeNil())
+		Expect(job.Spec.Template.Spec.RestartPolicy).To(Equal(corev1.RestartPolicyOnFailure))
+	})
+
+	It("bounds the names of retries of long run IDs", func() {
+		runID := strings.Repeat("r", 63)
+		name := cronjobbuilder.RetryJobName(runID, 10)
+		Expect(validation.IsDNS1123Label(name)).To(BeEmpty())
+		Expect(name).To(HaveSuffix("-retry-10"))
+		Expect(name).NotTo(Equal(cronjobbuilder.RetryJobName(runID, 11)))
+		Expect(cronjobbuilder.RetryJobName("etl-nightly-1741917600", 2)).To(Equal("etl-nightly-1741917600-retry-2"))
+	})
+})
@@ -0,0 +1,129 @@
+package cronjobbuilder
+
+import (
+	"fmt"
+	"time"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// StepContainerPrefix prefixes the name of the init container running a step in
+	// InitContainers mode.
+	StepContainerPrefix = "step-"
+
+	// WorkspaceMountPath is where the workspace shared by the steps is mounted.
+	WorkspaceMountPath = "/workspace"
+
+	workspaceVolumeName = "workspace"
+)
+
+// StepNames returns the steps of a schedule's pipeline in order, ending with MainStep.
+func StepNames(schedule schedulingapiv1.Schedule) []string {
+	names := make([]string, 0, len(schedule.Steps)+1)
+	for _, step := range schedule.Steps {
+		names = append(names, step.Name)
+	}
+	return append(names, schedulingapiv1.MainStep)
+}
+
+// StepJobName returns the name of the Job of a step of a run in Jobs mode.
+func StepJobName(runJobName, step string) string {
+	return boundedName(runJobName, "-"+step, maxJobNameLength)
+}
+
+// BuildStepJob creates the Job of a step of a run in Jobs mode, other than the first step
+// which runs in the Job of the run itself. The Job carries the trigger of the run and
+// shares its run metadata, so RUN_ID is the same in every step.
+func BuildStepJob(scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule, runJob *batchv1.Job, step string, run RunInfo) (*batchv1.Job, error) {
+	var container corev1.Container
+	var retries *int32
+	if step == schedulingapiv1.MainStep {
+		container = corev1.Container{Image: schedule.Image, Args: schedule.Params, Env: schedule.Env}
+	} else {
+		found := false
+		for _, s := range schedule.Steps {
+			if s.Name == step {
+				container, retries, found = stepContainer(schedule, s), s.Retries, true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("schedule %s has no step %s", schedule.Name, step)
+		}
+	}
+	container.Name = JobContainerName
+
+	template := jobTemplate(scheduler, schedule, container)
+	if retries != nil {
+		template.Spec.BackoffLimit = retries
+	}
+	podSpec := &template.Spec.Template.Spec
+	mountWorkspace(podSpec, schedule)
+	if err := renderPod(podSpec, scheduler, schedule, &run, RunID(runJob)); err != nil {
+		return nil, err
+	}
+
+	labels := Labels(scheduler, schedule)
+	if trigger, ok := runJob.Labels[schedulingapiv1.TriggerLabel]; ok {
+		labels[schedulingapiv1.TriggerLabel] = trigger
+	}
+	labels[schedulingapiv1.StepLabel] = step
+	labels[schedulingapiv1.RunLabel] = runJob.Name
+	template.Spec.Template.Labels = labels
+
+	annotations := map[string]string{
+		schedulingapiv1.ScheduledTimeAnnotation: run.ScheduledTime.UTC().Format(time.RFC3339),
+	}
+	for key, value := range template.Annotations {
+		annotations[key] = value
+	}
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        StepJobName(runJob.Name, step),
+			Namespace:   scheduler.Namespace,
+			Labels:      labels,
+			Annotations: annotations,
+		},
+		Spec: template.Spec,
+	}, nil
+}
+
+// stepContainer returns the container of a step, named JobContainerName.
+func stepContainer(schedule schedulingapiv1.Schedule, step schedulingapiv1.Step) corev1.Container {
+	image := step.Image
+	if image == "" {
+		image = schedule.Image
+	}
+	return corev1.Container{
+		Name:  JobContainerName,
+		Image: image,
+		Args:  step.Params,
+		Env:   step.Env,
+	}
+}
+
+// mountWorkspace mounts the workspace of a schedule with steps in every container of a pod.
+func mountWorkspace(podSpec *corev1.PodSpec, schedule schedulingapiv1.Schedule) {
+	volume := corev1.Volume{
+		Name:         workspaceVolumeName,
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	}
+	if schedule.WorkspaceClaimName != "" {
+		volume.VolumeSource = corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: schedule.WorkspaceClaimName},
+		}
+	}
+	podSpec.Volumes = append(podSpec.Volumes, volume)
+
+	mount := corev1.VolumeMount{Name: workspaceVolumeName, MountPath: WorkspaceMountPath}
+	for i := range podSpec.InitContainers {
+		podSpec.InitContainers[i].VolumeMounts = append(podSpec.InitContainers[i].VolumeMounts, mount)
+	}
+	for i := range podSpec.Containers {
+		podSpec.Containers[i].VolumeMounts = append(podSpec.Containers[i].VolumeMounts, mount)
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cronjobbuilder_test
+
+import (
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/utils/ptr"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cronjobbuilder"
+)
+
+var _ = Describe("Steps", func() {
+	scheduler := &schedulingapiv1.Scheduler{
+		ObjectMeta: metav1.ObjectMeta{Name: "etl", Namespace: "default"},
+	}
+	slot := time.Date(2025, 3, 14, 2, 0, 0, 0, time.UTC)
+	schedule := schedulingapiv1.Schedule{
+		Name:  "nightly",
+		Image: "loader",
+		Steps: []schedulingapiv1.Step{
+			{Name: "extract", Image: "extractor", Params: []string{"--out=/workspace/{{ .ScheduleName }}"}},
+			{Name: "transform"},
+		},
+	}
+
+	It("runs the steps as init containers sharing a workspace", func() {
+		job, err := cronjobbuilder.BuildJob(scheduler, schedule, slot, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		podSpec := job.Spec.Template.Spec
+		Expect(podSpec.InitContainers).To(HaveLen(2))
+		Expect(podSpec.InitContainers[0].Name).To(Equal("step-extract"))
+		Expect(podSpec.InitContainers[0].Args).To(Equal([]string{"--out=/workspace/nightly"}))
+		Expect(podSpec.InitContainers[1].Image).To(Equal("loader"))
+		Expect(podSpec.Volumes).To(HaveLen(1))
+		Expect(podSpec.Volumes[0].EmptyDir).NotTo(BeNil())
+		Expect(podSpec.Containers[0].VolumeMounts).To(ContainElement(HaveField("MountPath", cronjobbuilder.WorkspaceMountPath)))
+		Expect(job.Annotations).NotTo(HaveKey(schedulingapiv1.StepsAnnotation))
+	})
+
+	It("chains Jobs in Jobs mode", func() {
+		jobs := schedule
+		jobs.StepsMode = schedulingapiv1.StepsModeJobs
+		jobs.WorkspaceClaimName = "etl-workspace"
+		jobs.Steps = []schedulingapiv1.Step{
+			{Name: "extract", Image: "extractor", Retries: ptr.To(int32(2))},
+			{Name: "transform", ContinueOnError: true},
+		}
+
+		runJob, err := cronjobbuilder.BuildJob(scheduler, jobs, slot, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(runJob.Annotations).To(HaveKeyWithValue(schedulingapiv1.StepsAnnotation, "extract,transform,main"))
+		Expect(runJob.Spec.Template.Spec.InitContainers).To(BeEmpty())
+		Expect(runJob.Spec.Template.Spec.Containers[0].Image).To(Equal("extractor"))
+		Expect(runJob.Spec.BackoffLimit).To(Equal(ptr.To(int32(2))))
+		Expect(runJob.Spec.Template.Spec.Volumes[0].PersistentVolumeClaim.ClaimName).To(Equal("etl-workspace"))
+
+		stepJob, err := cronjobbuilder.BuildStepJob(scheduler, jobs, runJob, schedulingapiv1.MainStep,
+			cronjobbuilder.RunInfo{ScheduledTime: slot, Trigger: cronjobbuilder.TriggerCron, Attempt: 1})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(stepJob.Name).To(Equal(runJob.Name + "-main"))
+		Expect(stepJob.Labels).To(HaveKeyWithValue(schedulingapiv1.RunLabel, runJob.Name))
+		Expect(stepJob.Spec.Template.Spec.Containers[0].Image).To(Equal("loader"))
+		Expect(stepJob.Spec.Template.Spec.Containers[0].Env).To(ContainElement(HaveField("Value", runJob.Name)))
+
+		_, err = cronjobbuilder.BuildStepJob(scheduler, jobs, runJob, "unknown", cronjobbuilder.RunInfo{ScheduledTime: slot})
+		Expect(err).To(HaveOccurred())
+	})
+	It("bounds the names of step Jobs of long run Job names", func() {
+		runJobName := strings.Repeat("r", 63)
+		name := cronjobbuilder.StepJobName(runJobName, strings.Repeat("s", 40))
+		Expect(validation.IsDNS1123Label(name)).To(BeEmpty())
+		Expect(name).To(HaveSuffix("-" + strings.Repeat("s", 40)))
+		Expect(name).NotTo(Equal(cronjobbuilder.StepJobName(strings.Repeat("r", 62)+"q", strings.Repeat("s", 40))))
+	})
+})
@@ -0,0 +1,155 @@
+package cronjobbuilder
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Environment variables describing the run, set in the job container of every Job.
+const (
+	SchedulerNameEnv = "SCHEDULER_NAME"
+	ScheduleNameEnv  = "SCHEDULE_NAME"
+	ScheduledTimeEnv = "SCHEDULED_TIME"
+	RunIDEnv         = "RUN_ID"
+	AttemptEnv       = "ATTEMPT"
+	TriggerEnv       = "TRIGGER"
+)
+
+// TriggerCron is the TRIGGER value of runs started by the schedule itself.
+const TriggerCron = "cron"
+
+// RunInfo describes the run a Job is created for.
+type RunInfo struct {
+	// ScheduledTime is the logical time of the run.
+	ScheduledTime time.Time
+	// Trigger is what started the run: TriggerCron, manual, backfill or dependency.
+	Trigger string
+	// Attempt is the attempt number of the run, starting at 1.
+	Attempt int32
+	// Outputs are the outputs of previous runs available to templates.
+	Outputs Outputs
+}
+
+// Outputs are the outputs of previous runs of the schedules of a Scheduler, by schedule
+// name, as templates see them in .Outputs.
+type Outputs map[string]map[string]string
+
+// templateFuncs are the functions available to templates in params and env values.
+var templateFuncs = template.FuncMap{
+	// date formats a time with a Go layout, as in {{ .ScheduledTime | date "2006-01-02" }}
+	"date": func(layout string, t time.Time) string {
+		return t.UTC().Format(layout)
+	},
+}
+
+// renderContainer sets the run environment of the job container and renders the
+// templates in its args and env values. The run is nil for CronJobs, whose Jobs are
+// created by the CronJob controller: their run ID comes from the Job name label and
+// the scheduled time is not known. The CronJob controller records it in an annotation
+// of the Job only, which the downward API of its pods cannot read, so CronJobs referring
+// to it are rejected.
+func renderContainer(container *corev1.Container, scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule, run *RunInfo, jobName string) error {
+	data := map[string]any{
+		"SchedulerName": scheduler.Name,
+		"ScheduleName":  schedule.Name,
+		"Namespace":     scheduler.Namespace,
+	}
+	env := []corev1.EnvVar{
+		{Name: SchedulerNameEnv, Value: scheduler.Name},
+		{Name: ScheduleNameEnv, Value: schedule.Name},
+	}
+	if run != nil {
+		scheduledTime := run.ScheduledTime.UTC()
+		data["ScheduledTime"] = scheduledTime
+		data["RunID"] = jobName
+		data["Attempt"] = run.Attempt
+		data["Trigger"] = run.Trigger
+		data["Outputs"] = run.Outputs
+		env = append(env,
+			corev1.EnvVar{Name: ScheduledTimeEnv, Value: scheduledTime.Format(time.RFC3339)},
+			corev1.EnvVar{Name: RunIDEnv, Value: jobName},
+			corev1.EnvVar{Name: AttemptEnv, Value: strconv.Itoa(int(run.Attempt))},
+			corev1.EnvVar{Name: TriggerEnv, Value: run.Trigger},
+		)
+	} else {
+		env = append(env,
+			corev1.EnvVar{Name: RunIDEnv, ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.labels['" + batchv1.JobNameLabel + "']"},
+			}},
+			corev1.EnvVar{Name: AttemptEnv, Value: "1"},
+			corev1.EnvVar{Name: TriggerEnv, Value: TriggerCron},
+		)
+	}
+
+	// The kubelet would leave a reference to SCHEDULED_TIME as it is in CronJob runs,
+	// which do not have it
+	scheduledTimeRef := "$(" + ScheduledTimeEnv + ")"
+
+	var args []string
+	for _, arg := range container.Args {
+		rendered, err := render(arg, data)
+		if err != nil {
+			return fmt.Errorf("schedule %s: %w", schedule.Name, err)
+		}
+		if run == nil && strings.Contains(rendered, scheduledTimeRef) {
+			return fmt.Errorf("schedule %s: params refer to %s", schedule.Name, scheduledTimeRef)
+		}
+		args = append(args, rendered)
+	}
+	container.Args = args
+
+	// The run environment comes first, so the schedule's own variables can refer to it
+	// with $(VAR) and override it
+	for _, v := range container.Env {
+		rendered, err := render(v.Value, data)
+		if err != nil {
+			return fmt.Errorf("schedule %s: env %s: %w", schedule.Name, v.Name, err)
+		}
+		if run == nil && strings.Contains(rendered, scheduledTimeRef) {
+			return fmt.Errorf("schedule %s: env %s refers to %s", schedule.Name, v.Name, scheduledTimeRef)
+		}
+		v.Value = rendered
+		env = append(env, v)
+	}
+	container.Env = env
+	return nil
+}
+
+// renderPod renders the containers of a pod running the schedule's steps and image.
+func renderPod(podSpec *corev1.PodSpec, scheduler *schedulingapiv1.Scheduler, schedule schedulingapiv1.Schedule, run *RunInfo, jobName string) error {
+	for i := range podSpec.InitContainers {
+		if err := renderContainer(&podSpec.InitContainers[i], scheduler, schedule, run, jobName); err != nil {
+			return err
+		}
+	}
+	for i := range podSpec.Containers {
+		if err := renderContainer(&podSpec.Containers[i], scheduler, schedule, run, jobName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// render executes value as a template when it contains one, and returns it unchanged
+// otherwise so that literal values are never reinterpreted.
+func render(value string, data map[string]any) (string, error) {
+	if !strings.Contains(value, "{{") {
+		return value, nil
+	}
+	tmpl, err := template.New("").Funcs(templateFuncs).Option("missingkey=error").Parse(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid template %q: %w", value, err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render template %q: %w", value, err)
+	}
+	return b.String(), nil
+}
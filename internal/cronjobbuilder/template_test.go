@@ -0,0 +1,127 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cronjobbuilder_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	schedulingapiv1 "github.com/lorenzorottigni/k8s-cj-scheduler/api/v1"
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/cronjobbuilder"
+)
+
+var _ = Describe("Templates", func() {
+	scheduler := &schedulingapiv1.Scheduler{
+		ObjectMeta: metav1.ObjectMeta{Name: "reports", Namespace: "default"},
+	}
+	slot := time.Date(2025, 3, 14, 2, 0, 0, 0, time.UTC)
+
+	envValue := func(env []corev1.EnvVar, name string) string {
+		for _, v := range env {
+			if v.Name == name {
+				return v.Value
+			}
+		}
+		return ""
+	}
+
+	It("renders run data into params and env of controller-created Jobs", func() {
+		schedule := schedulingapiv1.Schedule{
+			Name:   "daily",
+			Image:  "busybox",
+			Params: []string{"--date={{ .ScheduledTime | date \"2006-01-02\" }}", "--literal"},
+			Env:    []corev1.EnvVar{{Name: "OUT", Value: "{{ .SchedulerName }}/{{ .RunID }}"}},
+		}
+		job, err := cronjobbuilder.BuildJob(scheduler, schedule, slot, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		container := job.Spec.Template.Spec.Containers[0]
+		Expect(container.Args).To(Equal([]string{"--date=2025-03-14", "--literal"}))
+		Expect(envValue(container.Env, "OUT")).To(Equal("reports/" + job.Name))
+		Expect(envValue(container.Env, cronjobbuilder.ScheduledTimeEnv)).To(Equal("2025-03-14T02:00:00Z"))
+		Expect(envValue(container.Env, cronjobbuilder.RunIDEnv)).To(Equal(job.Name))
+		Expect(envValue(container.Env, cronjobbuilder.AttemptEnv)).To(Equal("1"))
+		Expect(envValue(container.Env, cronjobbuilder.TriggerEnv)).To(Equal(cronjobbuilder.TriggerCron))
+		Expect(schedule.Env[0].Value).To(Equal("{{ .SchedulerName }}/{{ .RunID }}"))
+	})
+
+	It("renders the outputs of previous runs", func() {
+		schedule := schedulingapiv1.Schedule{
+			Name:   "load",
+			Image:  "busybox",
+			Params: []string{"--rows={{ .Outputs.extract.rows }}", "--optional={{ index .Outputs \"report\" \"path\" }}"},
+		}
+		outputs := cronjobbuilder.Outputs{"extract": {"rows": "1200"}}
+		job, err := cronjobbuilder.BuildDependentJob(scheduler, schedule, slot, outputs)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(job.Spec.Template.Spec.Containers[0].Args).To(Equal([]string{"--rows=1200", "--optional="}))
+
+		_, err = cronjobbuilder.BuildJob(scheduler, schedule, slot, nil)
+		Expect(err).To(MatchError(ContainSubstring("extract")))
+	})
+
+	It("records the trigger of backfill runs", func() {
+		schedule := schedulingapiv1.Schedule{Name: "daily", Image: "busybox"}
+		job, err := cronjobbuilder.BuildBackfillJob(scheduler, schedule, slot, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(envValue(job.Spec.Template.Spec.Containers[0].Env, cronjobbuilder.TriggerEnv)).To(Equal(schedulingapiv1.TriggerBackfill))
+		Expect(job.Labels).To(HaveKeyWithValue(schedulingapiv1.TriggerLabel, schedulingapiv1.TriggerBackfill))
+	})
+
+	It("takes the run ID of CronJob runs from the Job name", func() {
+		schedule := schedulingapiv1.Schedule{
+			Name:   "daily",
+			Image:  "busybox",
+			Params: []string{"{{ .ScheduleName }}"},
+		}
+		cronJob, err := cronjobbuilder.BuildCronJob(scheduler, schedule)
+		Expect(err).NotTo(HaveOccurred())
+
+		container := cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0]
+		Expect(container.Args).To(Equal([]string{"daily"}))
+		Expect(container.Env).NotTo(ContainElement(HaveField("Name", cronjobbuilder.ScheduledTimeEnv)))
+		Expect(container.Env).To(ContainElement(HaveField("ValueFrom.FieldRef.FieldPath", "metadata.labels['batch.kubernetes.io/job-name']")))
+	})
+
+	It("rejects run data in CronJob templates", func() {
+		schedule := schedulingapiv1.Schedule{
+			Name:   "daily",
+			Image:  "busybox",
+			Params: []string{"{{ .ScheduledTime | date \"2006-01-02\" }}"},
+		}
+		_, err := cronjobbuilder.BuildCronJob(scheduler, schedule)
+		Expect(err).To(MatchError(ContainSubstring("mode: Native")))
+	})
+
+	It("rejects references to SCHEDULED_TIME in CronJob runs", func() {
+		schedule := schedulingapiv1.Schedule{
+			Name:  "daily",
+			Image: "busybox",
+			Env:   []corev1.EnvVar{{Name: "PARTITION", Value: "date=$(SCHEDULED_TIME)"}},
+		}
+		_, err := cronjobbuilder.BuildCronJob(scheduler, schedule)
+		Expect(err).To(MatchError(ContainSubstring("env PARTITION refers to $(SCHEDULED_TIME)")))
+
+		schedule.Mode = schedulingapiv1.ScheduleModeNative
+		_, err = cronjobbuilder.BuildJob(scheduler, schedule, time.Date(2025, 3, 14, 2, 0, 0, 0, time.UTC), nil)
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
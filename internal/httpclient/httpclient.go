@@ -0,0 +1,20 @@
+// Package httpclient builds the HTTP client used for URLs taken from Scheduler specs.
+package httpclient
+
+import (
+	"net/http"
+	"time"
+)
+
+// New returns a client for URLs set by the users of namespaced resources. They are called
+// with the network identity of the controller, so the client gives up after timeout and
+// does not follow redirects, which could lead anywhere: a redirect is returned as the
+// response.
+func New(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,85 @@
+package notify
+
+import (
+	"context"
+	"sync"
+)
+
+const (
+	// DefaultQueueSize is the number of notifications waiting for a worker before further
+	// ones are refused.
+	DefaultQueueSize = 100
+
+	// DefaultWorkers is the number of notifications delivered at once.
+	DefaultWorkers = 4
+)
+
+// delivery is a notification waiting to be sent.
+type delivery struct {
+	sender       Sender
+	notification Notification
+	done         func(err error)
+}
+
+// Dispatcher sends notifications in the background, from a bounded queue, so that
+// producers never wait for a sink. Every notification queued is sent once by Start,
+// which reports the outcome to the callback it was queued with.
+type Dispatcher struct {
+	queue   chan delivery
+	workers int
+}
+
+// NewDispatcher returns a dispatcher buffering up to queueSize notifications and sending
+// up to workers at once.
+func NewDispatcher(queueSize, workers int) *Dispatcher {
+	if queueSize <= 0 {
+		queueSize = DefaultQueueSize
+	}
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	return &Dispatcher{queue: make(chan delivery, queueSize), workers: workers}
+}
+
+// Dispatch queues a notification without blocking. done is called with the outcome of
+// its delivery, from another goroutine. It reports false when the queue is full and the
+// notification was not queued.
+func (d *Dispatcher) Dispatch(sender Sender, n Notification, done func(err error)) bool {
+	select {
+	case d.queue <- delivery{sender: sender, notification: n, done: done}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Start sends the queued notifications until ctx is done. It implements
+// manager.Runnable.
+func (d *Dispatcher) Start(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for range d.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case delivery := <-d.queue:
+					sendCtx, cancel := context.WithTimeout(ctx, Timeout)
+					err := delivery.sender.Send(sendCtx, delivery.notification)
+					cancel()
+					delivery.done(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable: notifications are
+// queued by the leader only, but standbys have none to send.
+func (d *Dispatcher) NeedLeaderElection() bool {
+	return false
+}
@@ -0,0 +1,234 @@
+// Package notify delivers notifications about the runs of schedules to webhooks, Slack
+// and email.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/lorenzorottigni/k8s-cj-scheduler/internal/httpclient"
+)
+
+// Timeout bounds the delivery of a notification.
+const Timeout = 10 * time.Second
+
+// client posts to webhooks, whose URLs come from namespaced Schedulers, without following
+// redirects.
+var client = httpclient.New(Timeout)
+
+// Notification describes an occurrence in the runs of a schedule.
+type Notification struct {
+	Trigger       string     `json:"trigger"`
+	Namespace     string     `json:"namespace"`
+	Scheduler     string     `json:"scheduler"`
+	Schedule      string     `json:"schedule"`
+	Run           string     `json:"run"`
+	ScheduledTime *time.Time `json:"scheduledTime,omitempty"`
+	Time          time.Time  `json:"time"`
+	Message       string     `json:"message"`
+}
+
+// Summary describes a notification in one line.
+func (n Notification) Summary() string {
+	return fmt.Sprintf("[%s] %s/%s/%s: %s", n.Trigger, n.Namespace, n.Scheduler, n.Schedule, n.Message)
+}
+
+// Sender delivers notifications to a sink.
+type Sender interface {
+	Send(ctx context.Context, n Notification) error
+}
+
+// Webhook posts notifications as JSON documents to a URL.
+type Webhook struct {
+	URL string
+	// Authorization is the value of the Authorization header, if any.
+	Authorization string
+	// Body is a template rendering the document, or empty for the notification itself.
+	Body string
+}
+
+// Send implements Sender.
+func (w Webhook) Send(ctx context.Context, n Notification) error {
+	body, err := RenderBody(w.Body, n)
+	if err != nil {
+		return err
+	}
+	return post(ctx, w.URL, w.Authorization, body)
+}
+
+// templateFuncs are the functions available to webhook body templates.
+var templateFuncs = template.FuncMap{
+	// json encodes a value, as in {"text": {{ .Message | json }}}
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// RenderBody renders the JSON document of a notification with a template, or encodes the
+// notification itself when the template is empty. A template rendering invalid JSON is
+// an error.
+func RenderBody(body string, n Notification) ([]byte, error) {
+	if body == "" {
+		return json.Marshal(n)
+	}
+	tmpl, err := template.New("").Funcs(templateFuncs).Option("missingkey=error").Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid body template: %w", err)
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, n); err != nil {
+		return nil, fmt.Errorf("failed to render body template: %w", err)
+	}
+	if !json.Valid(b.Bytes()) {
+		return nil, fmt.Errorf("body template rendered invalid JSON: %s", b.String())
+	}
+	return b.Bytes(), nil
+}
+
+// Slack posts notifications to a Slack-compatible incoming webhook.
+type Slack struct {
+	URL string
+}
+
+// Send implements Sender.
+func (s Slack) Send(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(map[string]string{"text": n.Summary()})
+	if err != nil {
+		return err
+	}
+	return post(ctx, s.URL, "", body)
+}
+
+// post sends a JSON document and expects a 2xx response.
+func post(ctx context.Context, target, authorization string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// The URL may hold credentials, so only the cause is reported
+		return fmt.Errorf("webhook request failed: %w", unwrapURLError(err))
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(excerpt)))
+	}
+	return nil
+}
+
+// unwrapURLError strips the URL from the errors of the HTTP client.
+func unwrapURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+// SMTP sends notifications by email.
+type SMTP struct {
+	Host     string
+	Port     int
+	From     string
+	To       []string
+	Username string
+	Password string
+}
+
+// Send implements Sender.
+func (s SMTP) Send(ctx context.Context, n Notification) error {
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	c, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("failed to greet SMTP server %s: %w", addr, err)
+	}
+	defer func() { _ = c.Close() }()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: s.Host}); err != nil {
+			return fmt.Errorf("failed to start TLS with %s: %w", addr, err)
+		}
+	}
+	if s.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			return fmt.Errorf("failed to authenticate to %s: %w", addr, err)
+		}
+	}
+	if err := c.Mail(s.From); err != nil {
+		return fmt.Errorf("SMTP server rejected sender: %w", err)
+	}
+	for _, to := range s.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("SMTP server rejected recipient %s: %w", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP server rejected message: %w", err)
+	}
+	if _, err := w.Write(s.Message(n)); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("SMTP server rejected message: %w", err)
+	}
+	return c.Quit()
+}
+
+// Message returns the email of a notification.
+func (s SMTP) Message(n Notification) []byte {
+	var b strings.Builder
+	header := func(name, value string) {
+		// Values come from the cluster, so line breaks must not inject headers
+		value = strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+		fmt.Fprintf(&b, "%s: %s\r\n", name, value)
+	}
+	header("From", s.From)
+	header("To", strings.Join(s.To, ", "))
+	header("Subject", fmt.Sprintf("[%s] %s/%s/%s", n.Trigger, n.Namespace, n.Scheduler, n.Schedule))
+	header("Date", n.Time.Format(time.RFC1123Z))
+	header("Content-Type", "text/plain; charset=utf-8")
+	b.WriteString("\r\n")
+
+	lines := []string{n.Message, "", "Run: " + n.Run}
+	if n.ScheduledTime != nil {
+		lines = append(lines, "Scheduled time: "+n.ScheduledTime.UTC().Format(time.RFC3339))
+	}
+	lines = append(lines, "Time: "+n.Time.UTC().Format(time.RFC3339))
+	for _, line := range lines {
+		b.WriteString(line + "\r\n")
+	}
+	return []byte(b.String())
+}
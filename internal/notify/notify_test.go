@@ -0,0 +1,185 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Notifications", func() {
+	scheduledTime := time.Date(2025, 1, 1, 2, 0, 0, 0, time.UTC)
+	notification := Notification{
+		Trigger:       "Failure",
+		Namespace:     "default",
+		Scheduler:     "reports",
+		Schedule:      "nightly",
+		Run:           "reports-nightly-29000000",
+		ScheduledTime: &scheduledTime,
+		Time:          scheduledTime.Add(5 * time.Minute),
+		Message:       "BackoffLimitExceeded: exit code 1",
+	}
+
+	Describe("RenderBody", func() {
+		It("encodes the notification when no template is set", func() {
+			body, err := RenderBody("", notification)
+			Expect(err).NotTo(HaveOccurred())
+			var decoded map[string]any
+			Expect(json.Unmarshal(body, &decoded)).To(Succeed())
+			Expect(decoded).To(HaveKeyWithValue("trigger", "Failure"))
+			Expect(decoded).To(HaveKeyWithValue("run", "reports-nightly-29000000"))
+			Expect(decoded).To(HaveKeyWithValue("scheduledTime", "2025-01-01T02:00:00Z"))
+		})
+
+		It("renders a template, quoting values with json", func() {
+			body, err := RenderBody(`{"text": {{ printf "%s: %s" .Schedule .Message | json }}}`, notification)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(body)).To(Equal(`{"text": "nightly: BackoffLimitExceeded: exit code 1"}`))
+		})
+
+		It("rejects templates that do not render JSON", func() {
+			_, err := RenderBody(`{"text": {{ .Message }}}`, notification)
+			Expect(err).To(MatchError(ContainSubstring("invalid JSON")))
+		})
+
+		It("rejects unknown fields", func() {
+			_, err := RenderBody(`{"text": {{ .Missing | json }}}`, notification)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("webhooks", func() {
+		var (
+			server   *httptest.Server
+			status   int
+			received *http.Request
+			body     []byte
+		)
+
+		BeforeEach(func() {
+			status = http.StatusOK
+			received = nil
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = r
+				body, _ = io.ReadAll(r.Body)
+				w.WriteHeader(status)
+				_, _ = w.Write([]byte("unavailable"))
+			}))
+			DeferCleanup(server.Close)
+		})
+
+		It("posts the rendered body with the Authorization header", func() {
+			webhook := Webhook{URL: server.URL, Authorization: "Bearer token", Body: `{"run": {{ .Run | json }}}`}
+			Expect(webhook.Send(context.Background(), notification)).To(Succeed())
+			Expect(received.Method).To(Equal(http.MethodPost))
+			Expect(received.Header.Get("Authorization")).To(Equal("Bearer token"))
+			Expect(received.Header.Get("Content-Type")).To(Equal("application/json"))
+			Expect(string(body)).To(Equal(`{"run": "reports-nightly-29000000"}`))
+		})
+
+		It("fails on responses other than 2xx", func() {
+			status = http.StatusServiceUnavailable
+			err := Webhook{URL: server.URL}.Send(context.Background(), notification)
+			Expect(err).To(MatchError(ContainSubstring("503 Service Unavailable: unavailable")))
+		})
+
+		It("does not follow redirects", func() {
+			redirect := httptest.NewServer(http.RedirectHandler(server.URL, http.StatusFound))
+			DeferCleanup(redirect.Close)
+			err := Webhook{URL: redirect.URL}.Send(context.Background(), notification)
+			Expect(err).To(MatchError(ContainSubstring("302 Found")))
+			Expect(received).To(BeNil())
+		})
+
+		It("does not report the URL when the request fails", func() {
+			err := Slack{URL: "http://127.0.0.1:1/services/secret"}.Send(context.Background(), notification)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).NotTo(ContainSubstring("secret"))
+		})
+
+		It("posts a one-line summary to Slack", func() {
+			Expect(Slack{URL: server.URL}.Send(context.Background(), notification)).To(Succeed())
+			Expect(string(body)).To(Equal(`{"text":"[Failure] default/reports/nightly: BackoffLimitExceeded: exit code 1"}`))
+		})
+	})
+
+	Describe("SMTP messages", func() {
+		It("describes the run", func() {
+			message := string(SMTP{From: "scheduler@example.com", To: []string{"ops@example.com", "dev@example.com"}}.Message(notification))
+			headers, text, found := strings.Cut(message, "\r\n\r\n")
+			Expect(found).To(BeTrue())
+			Expect(headers).To(ContainSubstring("To: ops@example.com, dev@example.com\r\n"))
+			Expect(headers).To(ContainSubstring("Subject: [Failure] default/reports/nightly\r\n"))
+			Expect(text).To(ContainSubstring("Run: reports-nightly-29000000\r\n"))
+			Expect(text).To(ContainSubstring("Scheduled time: 2025-01-01T02:00:00Z\r\n"))
+		})
+
+		It("does not let values inject headers", func() {
+			injected := notification
+			injected.Schedule = "nightly\r\nBcc: attacker@example.com"
+			headers, _, _ := strings.Cut(string(SMTP{From: "scheduler@example.com", To: []string{"ops@example.com"}}.Message(injected)), "\r\n\r\n")
+			Expect(headers).NotTo(ContainSubstring("\r\nBcc:"))
+		})
+	})
+
+	Describe("Dispatcher", func() {
+		It("sends queued notifications in the background and reports their outcome", func() {
+			failing := errors.New("unavailable")
+			var sent []string
+			var mu sync.Mutex
+			sender := senderFunc(func(_ context.Context, n Notification) error {
+				mu.Lock()
+				defer mu.Unlock()
+				sent = append(sent, n.Run)
+				return failing
+			})
+
+			dispatcher := NewDispatcher(1, 1)
+			outcomes := make(chan error, 2)
+			Expect(dispatcher.Dispatch(sender, notification, func(err error) { outcomes <- err })).To(BeTrue())
+			Expect(dispatcher.Dispatch(sender, notification, func(err error) { outcomes <- err })).To(BeFalse())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				_ = dispatcher.Start(ctx)
+			}()
+			Eventually(outcomes).Should(Receive(MatchError(failing)))
+			Expect(sent).To(Equal([]string{notification.Run}))
+			cancel()
+			Eventually(done).Should(BeClosed())
+		})
+	})
+})
+
+// senderFunc adapts a function to a Sender.
+type senderFunc func(ctx context.Context, n Notification) error
+
+func (f senderFunc) Send(ctx context.Context, n Notification) error {
+	return f(ctx, n)
+}
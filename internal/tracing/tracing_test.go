@@ -0,0 +1,53 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"regexp"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+var _ = Describe("Tracing", func() {
+	It("is enabled by an endpoint or the OTLP environment variables", func() {
+		GinkgoT().Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+		GinkgoT().Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+		Expect(Enabled("")).To(BeFalse())
+		Expect(Enabled("http://otel-collector:4318")).To(BeTrue())
+
+		GinkgoT().Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "http://otel-collector:4318/v1/traces")
+		Expect(Enabled("")).To(BeTrue())
+	})
+
+	It("returns no traceparent outside of a trace", func() {
+		Expect(TraceParent(context.Background())).To(BeEmpty())
+	})
+
+	It("returns the traceparent of the current span", func() {
+		provider := sdktrace.NewTracerProvider()
+		DeferCleanup(provider.Shutdown, context.Background())
+		ctx, span := provider.Tracer("test").Start(context.Background(), "Create Job")
+		defer span.End()
+
+		traceParent := TraceParent(ctx)
+		Expect(traceParent).To(MatchRegexp("^00-%s-%s-01$",
+			regexp.QuoteMeta(span.SpanContext().TraceID().String()), regexp.QuoteMeta(span.SpanContext().SpanID().String())))
+	})
+})